@@ -3,30 +3,15 @@ package main
 import (
 	"log/slog"
 	"os"
-	"time"
 
 	appcmd "github.com/rebellions-sw/rbln-metrics-exporter/internal/cmd"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
 )
 
-func initLogger() {
-	opts := &slog.HandlerOptions{
-		Level:     slog.LevelDebug,
-		AddSource: true,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.TimeKey {
-				a.Value = slog.StringValue(a.Value.Time().Format(time.RFC3339))
-			}
-			return a
-		},
-	}
-
-	handler := slog.NewJSONHandler(os.Stdout, opts)
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
-}
-
 func main() {
-	initLogger()
+	// The logger is set up again from --log-level and --log-format once the
+	// configuration is loaded.
+	logging.Setup(os.Stdout, slog.LevelInfo, logging.FormatJSON)
 	app := appcmd.NewApp()
 	if err := app.Execute(); err != nil {
 		slog.Error("command execution failed", "err", err)
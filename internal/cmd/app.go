@@ -2,16 +2,33 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
 	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
 	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/grpcapi"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/health"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/otlp"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/probe"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/pushgateway"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/remotewrite"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/restapi"
 	"github.com/rebellions-sw/rbln-metrics-exporter/internal/scheduler"
 	"github.com/rebellions-sw/rbln-metrics-exporter/internal/server"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/sink"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/version"
 	"github.com/spf13/cobra"
 )
 
@@ -21,33 +38,77 @@ func NewApp() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:           "rbln-metrics-exporter",
 		Short:         "Expose RBLN device metrics via Prometheus",
+		Version:       version.Get().Version,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := builder.finalize(); err != nil {
+			loader := newConfigLoader(os.Getenv, cmd.Flags())
+			b, _, err := loader.load()
+			if err != nil {
 				return err
 			}
-			return Start(cmd.Context(), builder.cfg)
+			logging.Setup(os.Stdout, mustParseLevel(b.cfg.LogLevel), b.cfg.LogFormat)
+			return Start(cmd.Context(), b.cfg, loader)
 		},
 	}
 
-	builder.bindFlags(cmd.Flags())
+	builder.bindFlags(cmd.PersistentFlags())
+	cmd.AddCommand(newConfigCommand())
+	cmd.AddCommand(newDevicesCommand())
+	cmd.AddCommand(newTopCommand())
+	cmd.AddCommand(newDoctorCommand())
+	cmd.AddCommand(newDashboardsCommand())
+	cmd.AddCommand(newRulesCommand())
+	cmd.AddCommand(newManifestsCommand())
+	cmd.AddCommand(newSupportBundleCommand())
+	cmd.AddCommand(newVersionCommand())
 
 	return cmd
 }
 
-func Start(ctx context.Context, config Config) error {
-	slog.Info("Starting rbln-metrics-exporter", "config", config)
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the exporter configuration",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "print",
+		Short: "Print the merged and validated configuration in the config file format",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, fs, err := newConfigLoader(os.Getenv, cmd.Flags()).load()
+			if err != nil {
+				return err
+			}
+			return writeConfig(cmd.OutOrStdout(), fs)
+		},
+	})
+	return cmd
+}
+
+// Start runs the exporter. When the config was read from a file, loader is
+// used to reload it on SIGHUP or when the file changes.
+func Start(ctx context.Context, config Config, loader *configLoader) error {
+	slog.Info("Starting rbln-metrics-exporter", "version", version.Get().Version, "config", config.redacted())
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	defer stop()
 
+	// Loops that still talk to remote systems on shutdown are waited for
+	// after ctx is canceled, so the process does not exit in the middle.
+	var shutdown sync.WaitGroup
+	defer shutdown.Wait()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	dClient, err := daemon.NewClient(ctx, config.RBLNDaemonURL)
+	sources, err := newSources(ctx, config)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		for _, source := range sources {
+			_ = source.Client.Close()
+		}
+	}()
 
 	metricRegistry := prometheus.NewRegistry()
 	isKubernetes := resolveKubernetesMode(config.KubernetesMode)
@@ -60,13 +121,136 @@ func Start(ctx context.Context, config Config) error {
 	} else {
 		podResourceMapper = collector.NewNoopPodResourceMapper()
 	}
-	collectorFactory := collector.NewCollectorFactory(podResourceMapper, metricRegistry, dClient, config.NodeName, isKubernetes)
-	collectors := collectorFactory.NewCollectors()
+	snapshots := collector.NewSnapshotStore()
+	collectorFactory := collector.NewCollectorFactory(podResourceMapper, snapshots, metricRegistry, sources, config.NodeName, isKubernetes)
+	collectors := collectorFactory.NewCollectors(config.Collectors)
+	metricRegistry.MustRegister(collector.NewBuildInfoCollector(sources, snapshots, config.NodeName))
+	if isKubernetes {
+		metricRegistry.MustRegister(collector.NewAllocationCollector(podResourceMapper, config.NodeName))
+	}
 
 	sched := scheduler.NewScheduler(podResourceMapper, collectors, config.Interval)
+	if config.Oneshot {
+		return runOnce(ctx, config, sched, metricRegistry)
+	}
 	go sched.Run(ctx)
 
-	server := server.NewMetricServer(metricRegistry, config.Port)
+	eventBroker := events.NewBroker()
+	go events.NewWatcher(sources, snapshots, eventBroker).Run(ctx)
+	eventMetrics := events.NewMetrics(sources, config.NodeName)
+	eventMetrics.Register(metricRegistry)
+	go eventMetrics.Run(ctx, eventBroker, snapshots)
+	recorder := events.NewRecorder(recordedEvents)
+	go recorder.Run(ctx, eventBroker)
+	go writeSupportBundleOnSignal(ctx, bundleState{
+		loader:       loader,
+		sources:      sources,
+		podResources: podResourceMapper,
+		gatherer:     metricRegistry,
+		recorder:     recorder,
+	}, config.SupportBundleDir, config.NodeName)
+
+	var interval, readinessMaxAge, logLevel atomic.Int64
+	interval.Store(int64(config.Interval))
+	readinessMaxAge.Store(int64(time.Duration(config.ReadinessMaxStale) * config.Interval))
+	logLevel.Store(int64(mustParseLevel(config.LogLevel)))
+	go logging.ToggleDebugOnSignal(ctx, func() slog.Level {
+		return slog.Level(logLevel.Load())
+	})
+	// The push loops follow a reloaded interval like the scheduler does.
+	var setIntervals []func(Config)
+	if config.RemoteWrite.Enabled() {
+		writer := remotewrite.NewWriter(config.RemoteWrite, metricRegistry, config.Interval)
+		setIntervals = append(setIntervals, func(next Config) { writer.SetInterval(next.Interval) })
+		go writer.Run(ctx)
+	}
+
+	if config.OTLP.Enabled() {
+		exporter, err := otlp.NewExporter(config.OTLP, metricRegistry, config.NodeName, isKubernetes)
+		if err != nil {
+			return err
+		}
+		setIntervals = append(setIntervals, func(next Config) { exporter.SetInterval(next.OTLP.Interval) })
+		go exporter.Run(ctx)
+	}
+
+	sinks, err := newSinks(config)
+	if err != nil {
+		return err
+	}
+	for _, s := range sinks {
+		go sink.Run(ctx, s, snapshots, func() time.Duration {
+			return time.Duration(interval.Load())
+		})
+	}
+
+	if config.Pushgateway.Enabled() {
+		pusher := pushgateway.NewPusher(config.Pushgateway, metricRegistry, config.NodeName)
+		setIntervals = append(setIntervals, func(next Config) { pusher.SetInterval(next.Interval) })
+		shutdown.Add(1)
+		go func() {
+			defer shutdown.Done()
+			pusher.Run(ctx, config.Interval)
+		}()
+	}
+
+	if config.ConfigFile != "" {
+		go watchConfig(ctx, loader, config, func(next Config) {
+			sched.SetInterval(next.Interval)
+			interval.Store(int64(next.Interval))
+			for _, setInterval := range setIntervals {
+				setInterval(next)
+			}
+			for _, c := range collectors {
+				c.SetGroups(next.Collectors)
+			}
+			readinessMaxAge.Store(int64(time.Duration(next.ReadinessMaxStale) * next.Interval))
+			if lvl := mustParseLevel(next.LogLevel); slog.Level(logLevel.Swap(int64(lvl))) != lvl {
+				logging.SetLevel(lvl)
+			}
+		})
+	}
+
+	if config.GRPC.Enabled() {
+		grpcServer, err := grpcapi.NewServer(config.GRPC, snapshots)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := grpcServer.Run(ctx); err != nil {
+				slog.Error("grpc server stopped", "err", err)
+			}
+		}()
+	}
+
+	if config.DisableMetricsServer {
+		<-ctx.Done()
+		return nil
+	}
+
+	readiness := health.NewChecker()
+	readiness.Add("daemon", func(context.Context) error {
+		return checkSources(sources)
+	})
+	readiness.Add("collection", func(context.Context) error {
+		return snapshots.CheckFreshness(time.Duration(readinessMaxAge.Load()))
+	})
+	if isKubernetes {
+		readiness.Add("pod-resources", func(context.Context) error {
+			return podResourceMapper.SyncError()
+		})
+	}
+
+	server := server.NewMetricServer(collector.NewMetricsHandler(metricRegistry, snapshots), config.Server)
+	server.HandleProbe("/healthz", health.LivenessHandler())
+	server.HandleProbe("/readyz", readiness)
+	server.Handle("/api/v1/", restapi.NewHandler(snapshots, eventBroker))
+	server.Handle("/-/log-level", logging.Handler(config.WebEnableLogLevel))
+	if config.Probe.Enabled() {
+		prober := probe.NewHandler(config.Probe)
+		go prober.Run(ctx)
+		server.Handle("/probe", prober)
+	}
 	if err := server.Start(ctx); err != nil {
 		slog.Error("http metrics server stopped", "err", err)
 		return err
@@ -75,6 +259,108 @@ func Start(ctx context.Context, config Config) error {
 	return nil
 }
 
+// newSources connects to the rbln-daemon. With named endpoints every daemon
+// gets its own lazily connected client, so that one unreachable endpoint does
+// not prevent the others from being collected.
+func newSources(ctx context.Context, config Config) ([]collector.Source, error) {
+	if len(config.DaemonEndpoints) == 0 {
+		client, err := daemon.NewClient(ctx, config.RBLNDaemonURL)
+		if err != nil {
+			return nil, err
+		}
+		return []collector.Source{{Client: client}}, nil
+	}
+
+	sources := make([]collector.Source, 0, len(config.DaemonEndpoints))
+	for _, endpoint := range config.DaemonEndpoints {
+		client, err := daemon.NewLazyClient(endpoint.Address)
+		if err != nil {
+			for _, source := range sources {
+				_ = source.Client.Close()
+			}
+			return nil, err
+		}
+		sources = append(sources, collector.Source{
+			Name:     endpoint.Name,
+			Hostname: endpoint.Hostname,
+			Client:   client,
+		})
+	}
+	return sources, nil
+}
+
+// checkSources fails only when no daemon is reachable, so that a single
+// unreachable endpoint does not take the whole exporter out of service.
+func checkSources(sources []collector.Source) error {
+	errs := make([]error, 0, len(sources))
+	for _, source := range sources {
+		if err := source.Client.CheckConnection(); err != nil {
+			if source.Name != "" {
+				err = fmt.Errorf("%s: %w", source.Name, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) < len(sources) {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// runOnce collects a single time and pushes the result to the Pushgateway, or
+// writes it to stdout in the text exposition format when no Pushgateway is set.
+func runOnce(ctx context.Context, config Config, sched *scheduler.Scheduler, gatherer prometheus.Gatherer) error {
+	if err := sched.RunOnce(ctx); err != nil {
+		return err
+	}
+
+	if config.Pushgateway.Enabled() {
+		pushCtx, cancel := context.WithTimeout(ctx, config.Pushgateway.Timeout)
+		defer cancel()
+		return pushgateway.NewPusher(config.Pushgateway, gatherer, config.NodeName).Push(pushCtx)
+	}
+
+	families, err := gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	enc := expfmt.NewEncoder(os.Stdout, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newSinks(config Config) ([]sink.Sink, error) {
+	var sinks []sink.Sink
+	if config.InfluxDB.Enabled() {
+		s, err := sink.NewInfluxDBSink(config.InfluxDB)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if config.StatsD.Enabled() {
+		s, err := sink.NewStatsDSink(config.StatsD)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// mustParseLevel parses a log level that was validated by finalize.
+func mustParseLevel(s string) slog.Level {
+	lvl, err := logging.ParseLevel(s)
+	if err != nil {
+		panic(err)
+	}
+	return lvl
+}
+
 func resolveKubernetesMode(mode string) bool {
 	switch mode {
 	case KubernetesModeOn:
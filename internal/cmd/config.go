@@ -1,12 +1,25 @@
 package cmd
 
 import (
+	"cmp"
 	"fmt"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/prometheus/common/model"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/grpcapi"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/otlp"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/probe"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/pushgateway"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/remotewrite"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/server"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/sink"
 	"github.com/spf13/pflag"
 )
 
@@ -16,42 +29,197 @@ const (
 )
 
 type Config struct {
-	RBLNDaemonURL  string
-	Port           int
-	Interval       time.Duration
-	Oneshot        bool
-	NodeName       string
-	KubernetesMode string
+	ConfigFile           string
+	RBLNDaemonURL        string
+	DaemonEndpoints      []DaemonEndpoint
+	Port                 int
+	Server               server.Config
+	Interval             time.Duration
+	Oneshot              bool
+	NodeName             string
+	KubernetesMode       string
+	DisableMetricsServer bool
+	ReadinessMaxStale    int
+	Collectors           []string
+	LogLevel             string
+	LogFormat            string
+	WebEnableLogLevel    bool
+	SupportBundleDir     string
+	RemoteWrite          remotewrite.Config
+	OTLP                 otlp.Config
+	InfluxDB             sink.InfluxDBConfig
+	StatsD               sink.StatsDConfig
+	Pushgateway          pushgateway.Config
+	GRPC                 grpcapi.Config
+	Probe                probe.Config
+}
+
+// DaemonEndpoint is one of several rbln-daemons polled by the exporter. Its
+// devices are labeled with source=Name and hostname=Hostname.
+type DaemonEndpoint struct {
+	Name     string
+	Address  string
+	Hostname string
 }
 
 type configBuilder struct {
-	cfg         Config
-	intervalSec int
+	cfg             Config
+	intervalSec     int
+	daemonEndpoints []string
 }
 
 func newConfigBuilder(getenv func(string) string) *configBuilder {
 	cfg := Config{
-		RBLNDaemonURL:  getenvDefault(getenv, "RBLN_METRICS_EXPORTER_RBLN_DAEMON_URL", "127.0.0.1:50051"),
-		Port:           getenvIntDefault(getenv, "RBLN_METRICS_EXPORTER_PORT", 9090),
-		Interval:       time.Duration(getenvIntDefault(getenv, "RBLN_METRICS_EXPORTER_INTERVAL", 5)) * time.Second,
-		Oneshot:        getenvBoolDefault(getenv, "RBLN_METRICS_EXPORTER_ONESHOT", false),
-		NodeName:       detectNodeName(getenv, "NODE_NAME", "unknown"),
-		KubernetesMode: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_KUBERNETES_MODE", KubernetesModeAuto),
+		ConfigFile:           getenvDefault(getenv, "RBLN_METRICS_EXPORTER_CONFIG_FILE", ""),
+		RBLNDaemonURL:        getenvDefault(getenv, "RBLN_METRICS_EXPORTER_RBLN_DAEMON_URL", "127.0.0.1:50051"),
+		Port:                 getenvIntDefault(getenv, "RBLN_METRICS_EXPORTER_PORT", 9090),
+		Interval:             time.Duration(getenvIntDefault(getenv, "RBLN_METRICS_EXPORTER_INTERVAL", 5)) * time.Second,
+		Oneshot:              getenvBoolDefault(getenv, "RBLN_METRICS_EXPORTER_ONESHOT", false),
+		NodeName:             detectNodeName(getenv, "NODE_NAME", "unknown"),
+		KubernetesMode:       getenvDefault(getenv, "RBLN_METRICS_EXPORTER_KUBERNETES_MODE", KubernetesModeAuto),
+		DisableMetricsServer: getenvBoolDefault(getenv, "RBLN_METRICS_EXPORTER_DISABLE_METRICS_SERVER", false),
+		ReadinessMaxStale:    getenvIntDefault(getenv, "RBLN_METRICS_EXPORTER_READINESS_MAX_STALE_INTERVALS", 3),
+		Collectors:           getenvListDefault(getenv, "RBLN_METRICS_EXPORTER_COLLECTORS", collector.MetricGroups()),
+		LogLevel:             getenvDefault(getenv, "RBLN_METRICS_EXPORTER_LOG_LEVEL", "info"),
+		LogFormat:            getenvDefault(getenv, "RBLN_METRICS_EXPORTER_LOG_FORMAT", logging.FormatJSON),
+		WebEnableLogLevel:    getenvBoolDefault(getenv, "RBLN_METRICS_EXPORTER_WEB_ENABLE_LOG_LEVEL", false),
+		SupportBundleDir:     getenvDefault(getenv, "RBLN_METRICS_EXPORTER_SUPPORT_BUNDLE_DIR", os.TempDir()),
+		Server: server.Config{
+			ListenAddresses: getenvListDefault(getenv, "RBLN_METRICS_EXPORTER_LISTEN_ADDRESS", nil),
+			WebConfigFile:   getenvDefault(getenv, "RBLN_METRICS_EXPORTER_WEB_CONFIG_FILE", ""),
+			BearerTokenFile: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_WEB_BEARER_TOKEN_FILE", ""),
+			KubeAuth: server.KubeAuthConfig{
+				Enabled:    getenvBoolDefault(getenv, "RBLN_METRICS_EXPORTER_KUBE_AUTH", false),
+				Kubeconfig: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_KUBECONFIG", ""),
+				Verb:       getenvDefault(getenv, "RBLN_METRICS_EXPORTER_KUBE_AUTH_VERB", "get"),
+				Resource:   getenvDefault(getenv, "RBLN_METRICS_EXPORTER_KUBE_AUTH_RESOURCE", ""),
+				APIGroup:   getenvDefault(getenv, "RBLN_METRICS_EXPORTER_KUBE_AUTH_API_GROUP", ""),
+				Namespace:  getenvDefault(getenv, "RBLN_METRICS_EXPORTER_KUBE_AUTH_NAMESPACE", ""),
+				Name:       getenvDefault(getenv, "RBLN_METRICS_EXPORTER_KUBE_AUTH_NAME", ""),
+				Audiences:  getenvListDefault(getenv, "RBLN_METRICS_EXPORTER_KUBE_AUTH_AUDIENCES", nil),
+				CacheTTL:   getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_KUBE_AUTH_CACHE_TTL", time.Minute),
+			},
+		},
+		RemoteWrite: remotewrite.Config{
+			URL:                   getenvDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_URL", ""),
+			Timeout:               getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_TIMEOUT", 10*time.Second),
+			BatchSize:             getenvIntDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_BATCH_SIZE", 500),
+			QueueCapacity:         getenvIntDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_QUEUE_CAPACITY", 10000),
+			MaxRetries:            getenvIntDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_MAX_RETRIES", 5),
+			MinBackoff:            getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_MIN_BACKOFF", 100*time.Millisecond),
+			MaxBackoff:            getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_MAX_BACKOFF", 10*time.Second),
+			BearerTokenFile:       getenvDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_BEARER_TOKEN_FILE", ""),
+			BasicAuthUsername:     getenvDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_BASIC_AUTH_USERNAME", ""),
+			BasicAuthPasswordFile: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_BASIC_AUTH_PASSWORD_FILE", ""),
+			ExternalLabels:        getenvMapDefault(getenv, "RBLN_METRICS_EXPORTER_REMOTE_WRITE_EXTERNAL_LABELS", map[string]string{}),
+		},
+		OTLP: otlp.Config{
+			Endpoint: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_OTLP_ENDPOINT", ""),
+			Protocol: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_OTLP_PROTOCOL", otlp.ProtocolGRPC),
+			Interval: getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_OTLP_INTERVAL", 0),
+			Timeout:  getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_OTLP_TIMEOUT", 10*time.Second),
+			Headers:  getenvMapDefault(getenv, "RBLN_METRICS_EXPORTER_OTLP_HEADERS", map[string]string{}),
+			Insecure: getenvBoolDefault(getenv, "RBLN_METRICS_EXPORTER_OTLP_INSECURE", false),
+		},
+		InfluxDB: sink.InfluxDBConfig{
+			URL:         getenvDefault(getenv, "RBLN_METRICS_EXPORTER_INFLUXDB_URL", ""),
+			TokenFile:   getenvDefault(getenv, "RBLN_METRICS_EXPORTER_INFLUXDB_TOKEN_FILE", ""),
+			Measurement: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_INFLUXDB_MEASUREMENT", "rbln_device"),
+		},
+		StatsD: sink.StatsDConfig{
+			Address: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_STATSD_ADDRESS", ""),
+			Prefix:  getenvDefault(getenv, "RBLN_METRICS_EXPORTER_STATSD_PREFIX", "rbln.device"),
+		},
+		Pushgateway: pushgateway.Config{
+			URL:          getenvDefault(getenv, "RBLN_METRICS_EXPORTER_PUSHGATEWAY_URL", ""),
+			Job:          getenvDefault(getenv, "RBLN_METRICS_EXPORTER_PUSHGATEWAY_JOB", "rbln-metrics-exporter"),
+			Method:       getenvDefault(getenv, "RBLN_METRICS_EXPORTER_PUSHGATEWAY_METHOD", pushgateway.MethodPut),
+			Grouping:     getenvMapDefault(getenv, "RBLN_METRICS_EXPORTER_PUSHGATEWAY_GROUPING", map[string]string{}),
+			Timeout:      getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_PUSHGATEWAY_TIMEOUT", 10*time.Second),
+			DeleteOnExit: getenvBoolDefault(getenv, "RBLN_METRICS_EXPORTER_PUSHGATEWAY_DELETE_ON_EXIT", false),
+		},
+		GRPC: grpcapi.Config{
+			ListenAddress: getenvDefault(getenv, "RBLN_METRICS_EXPORTER_GRPC_LISTEN_ADDRESS", ""),
+		},
+		Probe: probe.Config{
+			AllowedTargets: getenvListDefault(getenv, "RBLN_METRICS_EXPORTER_PROBE_ALLOWED_TARGETS", nil),
+			Timeout:        getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_PROBE_TIMEOUT", 10*time.Second),
+			IdleTimeout:    getenvDurationDefault(getenv, "RBLN_METRICS_EXPORTER_PROBE_IDLE_TIMEOUT", 5*time.Minute),
+		},
 	}
 
 	return &configBuilder{
-		cfg:         cfg,
-		intervalSec: int(cfg.Interval / time.Second),
+		cfg:             cfg,
+		intervalSec:     int(cfg.Interval / time.Second),
+		daemonEndpoints: getenvListDefault(getenv, "RBLN_METRICS_EXPORTER_RBLN_DAEMON_ENDPOINTS", nil),
 	}
 }
 
 func (b *configBuilder) bindFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&b.cfg.ConfigFile, configFileFlag, b.cfg.ConfigFile, "YAML file with settings keyed by flag name; flags and environment variables take precedence")
 	fs.StringVar(&b.cfg.RBLNDaemonURL, "rbln-daemon-url", b.cfg.RBLNDaemonURL, "Endpoint to RBLN daemon grpc server")
+	fs.StringArrayVar(&b.daemonEndpoints, "rbln-daemon-endpoint", b.daemonEndpoints, "Named rbln-daemon endpoint as NAME=ADDRESS[@HOSTNAME]; repeat to poll several daemons (overrides --rbln-daemon-url)")
 	fs.IntVar(&b.cfg.Port, "port", b.cfg.Port, "Port to listen for requests")
+	fs.StringSliceVar(&b.cfg.Server.ListenAddresses, "listen-address", b.cfg.Server.ListenAddresses, "Addresses to listen on, e.g. 127.0.0.1:9090, [::]:9090 or unix:///run/rbln/metrics.sock (overrides --port)")
+	fs.StringVar(&b.cfg.Server.WebConfigFile, "web-config-file", b.cfg.Server.WebConfigFile, "exporter-toolkit web config file enabling TLS and basic auth")
+	fs.StringVar(&b.cfg.Server.BearerTokenFile, "web-bearer-token-file", b.cfg.Server.BearerTokenFile, "File containing a bearer token required to access the HTTP endpoints")
+	fs.BoolVar(&b.cfg.WebEnableLogLevel, "web-enable-log-level", b.cfg.WebEnableLogLevel, "Allow changing the log level with PUT or POST /-/log-level")
+	fs.BoolVar(&b.cfg.Server.KubeAuth.Enabled, "kube-auth", b.cfg.Server.KubeAuth.Enabled, "Authenticate and authorize HTTP requests with Kubernetes TokenReview and SubjectAccessReview")
+	fs.StringVar(&b.cfg.Server.KubeAuth.Kubeconfig, "kubeconfig", b.cfg.Server.KubeAuth.Kubeconfig, "Kubeconfig used for Kubernetes API calls (defaults to the in-cluster service account)")
+	fs.StringVar(&b.cfg.Server.KubeAuth.Verb, "kube-auth-verb", b.cfg.Server.KubeAuth.Verb, "Verb checked by the SubjectAccessReview")
+	fs.StringVar(&b.cfg.Server.KubeAuth.Resource, "kube-auth-resource", b.cfg.Server.KubeAuth.Resource, "Resource[/subresource] checked by the SubjectAccessReview, e.g. nodes/metrics (defaults to a non-resource check of the request path)")
+	fs.StringVar(&b.cfg.Server.KubeAuth.APIGroup, "kube-auth-api-group", b.cfg.Server.KubeAuth.APIGroup, "API group of --kube-auth-resource")
+	fs.StringVar(&b.cfg.Server.KubeAuth.Namespace, "kube-auth-namespace", b.cfg.Server.KubeAuth.Namespace, "Namespace of --kube-auth-resource")
+	fs.StringVar(&b.cfg.Server.KubeAuth.Name, "kube-auth-name", b.cfg.Server.KubeAuth.Name, "Object name of --kube-auth-resource")
+	fs.StringSliceVar(&b.cfg.Server.KubeAuth.Audiences, "kube-auth-audiences", b.cfg.Server.KubeAuth.Audiences, "Audiences the bearer token must be valid for")
+	fs.DurationVar(&b.cfg.Server.KubeAuth.CacheTTL, "kube-auth-cache-ttl", b.cfg.Server.KubeAuth.CacheTTL, "How long authentication and authorization decisions are cached")
 	fs.IntVar(&b.intervalSec, "interval", b.intervalSec, fmt.Sprintf("Interval of collecting metrics (%d-%d seconds)", MinIntervalSeconds, MaxIntervalSeconds))
 	fs.BoolVar(&b.cfg.Oneshot, "oneshot", b.cfg.Oneshot, "Collect once and exit")
 	fs.StringVar(&b.cfg.NodeName, "node-name", b.cfg.NodeName, "Name of the node")
 	fs.StringVar(&b.cfg.KubernetesMode, "kubernetes-mode", b.cfg.KubernetesMode, "Kubernetes mode: auto, on, off")
+	fs.IntVar(&b.cfg.ReadinessMaxStale, "readiness-max-stale-intervals", b.cfg.ReadinessMaxStale, "Number of collection intervals without a successful collection before /readyz fails")
+	fs.StringSliceVar(&b.cfg.Collectors, "collectors", b.cfg.Collectors, fmt.Sprintf("Device metric groups to collect (%s)", strings.Join(collector.MetricGroups(), ", ")))
+	fs.StringVar(&b.cfg.LogLevel, "log-level", b.cfg.LogLevel, "Log level: debug, info, warn, error")
+	fs.StringVar(&b.cfg.LogFormat, "log-format", b.cfg.LogFormat, "Log format: json, text")
+	fs.StringVar(&b.cfg.SupportBundleDir, "support-bundle-dir", b.cfg.SupportBundleDir, "Directory the exporter writes a support bundle to on SIGUSR1")
+	fs.BoolVar(&b.cfg.DisableMetricsServer, "disable-metrics-server", b.cfg.DisableMetricsServer, "Do not serve the /metrics endpoint (e.g. when only pushing metrics)")
+
+	fs.StringVar(&b.cfg.RemoteWrite.URL, "remote-write-url", b.cfg.RemoteWrite.URL, "Prometheus remote_write endpoint to push metrics to (disabled when empty)")
+	fs.DurationVar(&b.cfg.RemoteWrite.Timeout, "remote-write-timeout", b.cfg.RemoteWrite.Timeout, "Timeout of a single remote_write request")
+	fs.IntVar(&b.cfg.RemoteWrite.BatchSize, "remote-write-batch-size", b.cfg.RemoteWrite.BatchSize, "Maximum number of samples per remote_write request")
+	fs.IntVar(&b.cfg.RemoteWrite.QueueCapacity, "remote-write-queue-capacity", b.cfg.RemoteWrite.QueueCapacity, "Maximum number of samples buffered in memory; oldest samples are dropped when full")
+	fs.IntVar(&b.cfg.RemoteWrite.MaxRetries, "remote-write-max-retries", b.cfg.RemoteWrite.MaxRetries, "Maximum number of retries for a failed remote_write request")
+	fs.DurationVar(&b.cfg.RemoteWrite.MinBackoff, "remote-write-min-backoff", b.cfg.RemoteWrite.MinBackoff, "Initial backoff between remote_write retries")
+	fs.DurationVar(&b.cfg.RemoteWrite.MaxBackoff, "remote-write-max-backoff", b.cfg.RemoteWrite.MaxBackoff, "Maximum backoff between remote_write retries")
+	fs.StringVar(&b.cfg.RemoteWrite.BearerTokenFile, "remote-write-bearer-token-file", b.cfg.RemoteWrite.BearerTokenFile, "File containing the bearer token for remote_write")
+	fs.StringVar(&b.cfg.RemoteWrite.BasicAuthUsername, "remote-write-basic-auth-username", b.cfg.RemoteWrite.BasicAuthUsername, "Basic auth username for remote_write")
+	fs.StringVar(&b.cfg.RemoteWrite.BasicAuthPasswordFile, "remote-write-basic-auth-password-file", b.cfg.RemoteWrite.BasicAuthPasswordFile, "File containing the basic auth password for remote_write")
+	fs.StringToStringVar(&b.cfg.RemoteWrite.ExternalLabels, "remote-write-external-labels", b.cfg.RemoteWrite.ExternalLabels, "Labels added to every pushed series (e.g. cluster=a,region=b)")
+
+	fs.StringVar(&b.cfg.OTLP.Endpoint, "otlp-endpoint", b.cfg.OTLP.Endpoint, "OTLP receiver to push metrics to: host:port for grpc, URL for http (disabled when empty)")
+	fs.StringVar(&b.cfg.OTLP.Protocol, "otlp-protocol", b.cfg.OTLP.Protocol, "OTLP protocol: grpc, http")
+	fs.DurationVar(&b.cfg.OTLP.Interval, "otlp-interval", b.cfg.OTLP.Interval, "Interval of OTLP pushes (defaults to --interval)")
+	fs.DurationVar(&b.cfg.OTLP.Timeout, "otlp-timeout", b.cfg.OTLP.Timeout, "Timeout of a single OTLP export")
+	fs.StringToStringVar(&b.cfg.OTLP.Headers, "otlp-headers", b.cfg.OTLP.Headers, "Headers sent with every OTLP export (e.g. authorization=Bearer x)")
+	fs.BoolVar(&b.cfg.OTLP.Insecure, "otlp-insecure", b.cfg.OTLP.Insecure, "Disable TLS for the OTLP grpc connection")
+
+	fs.StringVar(&b.cfg.InfluxDB.URL, "influxdb-url", b.cfg.InfluxDB.URL, "InfluxDB write URL (http(s)://.../write?db=x, http(s)://.../api/v2/write?org=x&bucket=y or udp://host:port; disabled when empty)")
+	fs.StringVar(&b.cfg.InfluxDB.TokenFile, "influxdb-token-file", b.cfg.InfluxDB.TokenFile, "File containing the InfluxDB API token")
+	fs.StringVar(&b.cfg.InfluxDB.Measurement, "influxdb-measurement", b.cfg.InfluxDB.Measurement, "InfluxDB measurement name")
+	fs.StringVar(&b.cfg.StatsD.Address, "statsd-address", b.cfg.StatsD.Address, "DogStatsD UDP address (host:port) to send gauges to (disabled when empty)")
+	fs.StringVar(&b.cfg.StatsD.Prefix, "statsd-prefix", b.cfg.StatsD.Prefix, "Prefix of StatsD metric names")
+
+	fs.StringVar(&b.cfg.Pushgateway.URL, "pushgateway-url", b.cfg.Pushgateway.URL, "Prometheus Pushgateway to push metrics to (disabled when empty)")
+	fs.StringVar(&b.cfg.Pushgateway.Job, "pushgateway-job", b.cfg.Pushgateway.Job, "Job label of pushed metrics")
+	fs.StringVar(&b.cfg.Pushgateway.Method, "pushgateway-method", b.cfg.Pushgateway.Method, "Push method: put (replace the whole group), post (replace metrics with the same name)")
+	fs.StringToStringVar(&b.cfg.Pushgateway.Grouping, "pushgateway-grouping", b.cfg.Pushgateway.Grouping, "Grouping labels in addition to instance=<node name>")
+	fs.DurationVar(&b.cfg.Pushgateway.Timeout, "pushgateway-timeout", b.cfg.Pushgateway.Timeout, "Timeout of a single push")
+	fs.BoolVar(&b.cfg.Pushgateway.DeleteOnExit, "pushgateway-delete-on-exit", b.cfg.Pushgateway.DeleteOnExit, "Delete the pushed group from the Pushgateway on shutdown")
+
+	fs.StringSliceVar(&b.cfg.Probe.AllowedTargets, "probe-allowed-targets", b.cfg.Probe.AllowedTargets, "rbln-daemon targets /probe may connect to, as host:port patterns such as tray-*:50051 (/probe is disabled when empty)")
+	fs.DurationVar(&b.cfg.Probe.Timeout, "probe-timeout", b.cfg.Probe.Timeout, "Maximum duration of a /probe request; a shorter Prometheus scrape timeout takes precedence")
+	fs.DurationVar(&b.cfg.Probe.IdleTimeout, "probe-idle-timeout", b.cfg.Probe.IdleTimeout, "Close pooled /probe connections that were not used for this long")
+	fs.StringVar(&b.cfg.GRPC.ListenAddress, "grpc-listen-address", b.cfg.GRPC.ListenAddress, "Address of the exporter gRPC API, e.g. unix:///run/rbln/exporter.sock or 127.0.0.1:50061 (disabled when empty); other addresses require TLS in --web-config-file and --web-bearer-token-file")
 }
 
 func (b *configBuilder) finalize() error {
@@ -59,6 +227,39 @@ func (b *configBuilder) finalize() error {
 		return fmt.Errorf("interval must be %d-%d seconds", MinIntervalSeconds, MaxIntervalSeconds)
 	}
 	b.cfg.Interval = time.Duration(b.intervalSec) * time.Second
+	if b.cfg.ReadinessMaxStale < 1 {
+		return fmt.Errorf("readiness-max-stale-intervals must be at least 1")
+	}
+	for _, group := range b.cfg.Collectors {
+		if !slices.Contains(collector.MetricGroups(), group) {
+			return fmt.Errorf("unknown collector %q, must be one of %s", group, strings.Join(collector.MetricGroups(), ", "))
+		}
+	}
+	lvl, err := logging.ParseLevel(b.cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	b.cfg.LogLevel = logging.LevelName(lvl)
+	b.cfg.LogFormat = strings.ToLower(b.cfg.LogFormat)
+	switch b.cfg.LogFormat {
+	case logging.FormatJSON, logging.FormatText:
+	default:
+		return fmt.Errorf("log-format must be one of %q, %q", logging.FormatJSON, logging.FormatText)
+	}
+	if len(b.cfg.Server.ListenAddresses) == 0 {
+		b.cfg.Server.ListenAddresses = []string{fmt.Sprintf(":%d", b.cfg.Port)}
+	}
+	// The gRPC API shares the TLS certificate and bearer token of the HTTP endpoints.
+	b.cfg.GRPC.WebConfigFile = b.cfg.Server.WebConfigFile
+	b.cfg.GRPC.BearerTokenFile = b.cfg.Server.BearerTokenFile
+	if b.cfg.Server.KubeAuth.Enabled {
+		if b.cfg.Server.BearerTokenFile != "" {
+			return fmt.Errorf("kube-auth cannot be combined with web-bearer-token-file")
+		}
+		if b.cfg.Server.KubeAuth.Verb == "" || b.cfg.Server.KubeAuth.CacheTTL < 0 {
+			return fmt.Errorf("kube-auth-verb must not be empty and kube-auth-cache-ttl must not be negative")
+		}
+	}
 	b.cfg.KubernetesMode = strings.ToLower(b.cfg.KubernetesMode)
 	switch b.cfg.KubernetesMode {
 	case KubernetesModeAuto, KubernetesModeOn, KubernetesModeOff:
@@ -67,6 +268,97 @@ func (b *configBuilder) finalize() error {
 	}
 	// Deprecated compatibility shim: remove when inputs no longer include http(s) schemes.
 	b.cfg.RBLNDaemonURL = stripSchemePrefix(b.cfg.RBLNDaemonURL)
+	if err := b.finalizeDaemonEndpoints(); err != nil {
+		return err
+	}
+	if err := validateRemoteWrite(b.cfg.RemoteWrite); err != nil {
+		return err
+	}
+	if err := b.finalizeOTLP(); err != nil {
+		return err
+	}
+	if b.cfg.InfluxDB.Enabled() && b.cfg.InfluxDB.Measurement == "" {
+		return fmt.Errorf("influxdb-measurement must not be empty")
+	}
+	if b.cfg.Probe.Enabled() && (b.cfg.Probe.Timeout <= 0 || b.cfg.Probe.IdleTimeout <= 0) {
+		return fmt.Errorf("probe-timeout and probe-idle-timeout must be positive")
+	}
+	if err := b.finalizePushgateway(); err != nil {
+		return err
+	}
+	if b.cfg.DisableMetricsServer && !b.cfg.hasOtherOutput() {
+		return fmt.Errorf("disable-metrics-server requires another output such as remote-write-url, otlp-endpoint, influxdb-url, statsd-address, pushgateway-url or grpc-listen-address")
+	}
+	return nil
+}
+
+func (c Config) hasOtherOutput() bool {
+	return c.RemoteWrite.Enabled() || c.OTLP.Enabled() || c.InfluxDB.Enabled() || c.StatsD.Enabled() || c.Pushgateway.Enabled() || c.GRPC.Enabled()
+}
+
+func (b *configBuilder) finalizeOTLP() error {
+	b.cfg.OTLP.Protocol = strings.ToLower(b.cfg.OTLP.Protocol)
+	switch b.cfg.OTLP.Protocol {
+	case otlp.ProtocolGRPC, otlp.ProtocolHTTP:
+	default:
+		return fmt.Errorf("otlp-protocol must be one of %q, %q", otlp.ProtocolGRPC, otlp.ProtocolHTTP)
+	}
+	if b.cfg.OTLP.Interval <= 0 {
+		b.cfg.OTLP.Interval = b.cfg.Interval
+	}
+	if b.cfg.OTLP.Timeout <= 0 {
+		return fmt.Errorf("otlp-timeout must be positive")
+	}
+	return nil
+}
+
+func (b *configBuilder) finalizePushgateway() error {
+	pg := &b.cfg.Pushgateway
+	if !pg.Enabled() {
+		return nil
+	}
+	pg.Method = strings.ToLower(pg.Method)
+	switch pg.Method {
+	case pushgateway.MethodPut, pushgateway.MethodPost:
+	default:
+		return fmt.Errorf("pushgateway-method must be one of %q, %q", pushgateway.MethodPut, pushgateway.MethodPost)
+	}
+	if pg.Job == "" {
+		return fmt.Errorf("pushgateway-job must not be empty")
+	}
+	for k := range pg.Grouping {
+		if !model.LabelName(k).IsValid() || k == "job" || k == "instance" {
+			return fmt.Errorf("invalid pushgateway grouping label %q", k)
+		}
+	}
+	if pg.DeleteOnExit && b.cfg.Oneshot {
+		return fmt.Errorf("pushgateway-delete-on-exit cannot be combined with oneshot")
+	}
+	return nil
+}
+
+func validateRemoteWrite(rw remotewrite.Config) error {
+	if !rw.Enabled() {
+		return nil
+	}
+	u, err := url.Parse(rw.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("remote-write-url must be an absolute http(s) URL, got %q", logging.RedactURL(rw.URL))
+	}
+	if rw.BatchSize <= 0 || rw.QueueCapacity <= 0 {
+		return fmt.Errorf("remote-write-batch-size and remote-write-queue-capacity must be positive")
+	}
+	if rw.MaxRetries < 0 || rw.MinBackoff <= 0 || rw.MaxBackoff < rw.MinBackoff {
+		return fmt.Errorf("remote-write retries must be >= 0 and 0 < min-backoff <= max-backoff")
+	}
+	if rw.BearerTokenFile != "" && rw.BasicAuthUsername != "" {
+		return fmt.Errorf("remote-write bearer token and basic auth are mutually exclusive")
+	}
+	for k := range rw.ExternalLabels {
+		if !model.LabelName(k).IsValid() {
+			return fmt.Errorf("invalid remote-write external label name %q", k)
+		}
+	}
 	return nil
 }
 
@@ -87,6 +379,48 @@ func getenvIntDefault(getenv func(string) string, key string, def int) int {
 	return def
 }
 
+func getenvDurationDefault(getenv func(string) string, key string, def time.Duration) time.Duration {
+	if v := getenv(key); v != "" {
+		d, err := time.ParseDuration(v)
+		if err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// getenvListDefault parses a comma separated list.
+func getenvListDefault(getenv func(string) string, key string, def []string) []string {
+	v := getenv(key)
+	if v == "" {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getenvMapDefault parses a comma separated list of key=value pairs.
+func getenvMapDefault(getenv func(string) string, key string, def map[string]string) map[string]string {
+	v := getenv(key)
+	if v == "" {
+		return def
+	}
+	m := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || k == "" {
+			continue
+		}
+		m[k] = val
+	}
+	return m
+}
+
 func getenvBoolDefault(getenv func(string) string, key string, def bool) bool {
 	if v := getenv(key); v != "" {
 		switch strings.ToLower(v) {
@@ -99,6 +433,31 @@ func getenvBoolDefault(getenv func(string) string, key string, def bool) bool {
 	return def
 }
 
+// finalizeDaemonEndpoints parses NAME=ADDRESS[@HOSTNAME] entries. The
+// hostname label defaults to the endpoint name.
+func (b *configBuilder) finalizeDaemonEndpoints() error {
+	b.cfg.DaemonEndpoints = nil
+	names := make(map[string]struct{}, len(b.daemonEndpoints))
+	for _, entry := range b.daemonEndpoints {
+		name, address, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || address == "" {
+			return fmt.Errorf("rbln-daemon-endpoint must be NAME=ADDRESS[@HOSTNAME], got %q", entry)
+		}
+		if _, dup := names[name]; dup {
+			return fmt.Errorf("rbln-daemon-endpoint name %q is used more than once", name)
+		}
+		names[name] = struct{}{}
+
+		address, hostname, _ := strings.Cut(address, "@")
+		b.cfg.DaemonEndpoints = append(b.cfg.DaemonEndpoints, DaemonEndpoint{
+			Name:     name,
+			Address:  stripSchemePrefix(address),
+			Hostname: cmp.Or(hostname, name),
+		})
+	}
+	return nil
+}
+
 // stripSchemePrefix keeps backward compatibility with URLs that include http(s)://
 func stripSchemePrefix(addr string) string {
 	if strings.HasPrefix(addr, "http://") {
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v2"
+)
+
+const configFileFlag = "config-file"
+
+// envKeyOverrides lists the flags whose environment variable does not follow
+// the RBLN_METRICS_EXPORTER_<FLAG_NAME> convention.
+var envKeyOverrides = map[string]string{
+	"node-name":            "NODE_NAME",
+	"rbln-daemon-endpoint": "RBLN_METRICS_EXPORTER_RBLN_DAEMON_ENDPOINTS",
+}
+
+func envKey(flag string) string {
+	if key, ok := envKeyOverrides[flag]; ok {
+		return key
+	}
+	return "RBLN_METRICS_EXPORTER_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// configLoader merges defaults, the config file, the environment and the
+// command line, in increasing order of precedence. It can be run again to
+// reload the config file.
+type configLoader struct {
+	getenv func(string) string
+	// cmdline holds the flags given on the command line.
+	cmdline map[string][]string
+}
+
+func newConfigLoader(getenv func(string) string, fs *pflag.FlagSet) *configLoader {
+	// Subcommands have flags of their own, which are not settings.
+	settings := pflag.NewFlagSet("settings", pflag.ContinueOnError)
+	newConfigBuilder(getenv).bindFlags(settings)
+
+	cmdline := make(map[string][]string)
+	fs.Visit(func(f *pflag.Flag) {
+		if settings.Lookup(f.Name) != nil {
+			cmdline[f.Name] = flagValues(f)
+		}
+	})
+	return &configLoader{
+		getenv:  getenv,
+		cmdline: cmdline,
+	}
+}
+
+// load returns the finalized builder and the flag set bound to it.
+func (l *configLoader) load() (*configBuilder, *pflag.FlagSet, error) {
+	b := newConfigBuilder(l.getenv)
+	fs := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	b.bindFlags(fs)
+
+	for name, values := range l.cmdline {
+		if err := setFlag(fs, name, values); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if b.cfg.ConfigFile != "" {
+		settings, err := readConfigFile(b.cfg.ConfigFile, fs)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, values := range settings {
+			if _, ok := l.cmdline[name]; ok || l.getenv(envKey(name)) != "" {
+				continue
+			}
+			if err := setFlag(fs, name, values); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s in config file %s: %w", name, b.cfg.ConfigFile, err)
+			}
+		}
+	}
+
+	if err := b.finalize(); err != nil {
+		return nil, nil, err
+	}
+	return b, fs, nil
+}
+
+// readConfigFile reads a YAML file whose keys are flag names. Sections are
+// joined with "-", so "remote-write: {url: ...}" sets --remote-write-url.
+func readConfigFile(path string, fs *pflag.FlagSet) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var root yaml.MapSlice
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	settings := make(map[string][]string)
+	if err := flattenConfig(fs, "", root, settings); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+func flattenConfig(fs *pflag.FlagSet, prefix string, section yaml.MapSlice, settings map[string][]string) error {
+	for _, item := range section {
+		name := prefix + fmt.Sprint(item.Key)
+		if name == configFileFlag {
+			return fmt.Errorf("%s cannot be set in the config file", configFileFlag)
+		}
+
+		f := fs.Lookup(name)
+		if f == nil {
+			sub, ok := item.Value.(yaml.MapSlice)
+			if !ok {
+				return fmt.Errorf("unknown setting %q", name)
+			}
+			if err := flattenConfig(fs, name+"-", sub, settings); err != nil {
+				return err
+			}
+			continue
+		}
+
+		values, err := configValues(f, item.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		settings[name] = values
+	}
+	return nil
+}
+
+// configValues converts a YAML value to the arguments of the flag.
+func configValues(f *pflag.Flag, value any) ([]string, error) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		if f.Value.Type() != "stringToString" {
+			return nil, fmt.Errorf("got a map, want %s", f.Value.Type())
+		}
+		pairs := make([]string, 0, len(v))
+		for _, item := range v {
+			pairs = append(pairs, fmt.Sprintf("%v=%v", item.Key, item.Value))
+		}
+		return []string{strings.Join(pairs, ",")}, nil
+	case []any:
+		if _, ok := f.Value.(pflag.SliceValue); !ok {
+			return nil, fmt.Errorf("got a list, want %s", f.Value.Type())
+		}
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values, nil
+	case nil:
+		return []string{}, nil
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
+
+func flagValues(f *pflag.Flag) []string {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return slices.Clone(sv.GetSlice())
+	}
+	if f.Value.Type() == "stringToString" {
+		return []string{strings.TrimSuffix(strings.TrimPrefix(f.Value.String(), "["), "]")}
+	}
+	return []string{f.Value.String()}
+}
+
+func setFlag(fs *pflag.FlagSet, name string, values []string) error {
+	f := fs.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("unknown flag %q", name)
+	}
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		f.Changed = true
+		return sv.Replace(values)
+	}
+	for _, value := range values {
+		if err := fs.Set(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactedFlags hold credentials that are not printed by config print.
+var redactedFlags = map[string]bool{
+	"otlp-headers": true,
+}
+
+// urlFlags are URLs that may embed credentials as userinfo or, for
+// InfluxDB 1.x, as u and p query parameters. They are printed without them.
+var urlFlags = map[string]bool{
+	"pushgateway-url":  true,
+	"remote-write-url": true,
+	"influxdb-url":     true,
+}
+
+// redacted returns a copy of c that is safe to log, as the logs end up in
+// support bundles.
+func (c Config) redacted() Config {
+	if len(c.OTLP.Headers) > 0 {
+		headers := make(map[string]string, len(c.OTLP.Headers))
+		for name := range c.OTLP.Headers {
+			headers[name] = "<redacted>"
+		}
+		c.OTLP.Headers = headers
+	}
+	c.Pushgateway.URL = logging.RedactURL(c.Pushgateway.URL)
+	c.RemoteWrite.URL = logging.RedactURL(c.RemoteWrite.URL)
+	c.InfluxDB.URL = logging.RedactURL(c.InfluxDB.URL)
+	return c
+}
+
+// writeConfig prints every setting in the config file format.
+func writeConfig(w io.Writer, fs *pflag.FlagSet) error {
+	var out yaml.MapSlice
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Name == configFileFlag {
+			return
+		}
+		value := typedValue(f)
+		if redactedFlags[f.Name] && f.Value.String() != "[]" {
+			value = "<redacted>"
+		}
+		if urlFlags[f.Name] {
+			value = logging.RedactURL(f.Value.String())
+		}
+		out = append(out, yaml.MapItem{Key: f.Name, Value: value})
+	})
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func typedValue(f *pflag.Flag) any {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.GetSlice()
+	}
+	s := f.Value.String()
+	switch f.Value.Type() {
+	case "bool":
+		v, _ := strconv.ParseBool(s)
+		return v
+	case "int":
+		v, _ := strconv.Atoi(s)
+		return v
+	case "stringToString":
+		m := make(map[string]string)
+		for pair := range strings.SplitSeq(strings.Trim(s, "[]"), ",") {
+			if k, v, ok := strings.Cut(pair, "="); ok {
+				m[k] = v
+			}
+		}
+		out := make(yaml.MapSlice, 0, len(m))
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			out = append(out, yaml.MapItem{Key: k, Value: m[k]})
+		}
+		return out
+	default:
+		return s
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/dashboards"
+	"github.com/spf13/cobra"
+)
+
+func newDashboardsCommand() *cobra.Command {
+	var outputDir string
+	cmd := &cobra.Command{
+		Use:   "dashboards",
+		Short: "Write Grafana dashboards for the metrics of the configured collectors",
+		Long: `Write Grafana dashboards for the metrics of the configured collectors.
+
+The node, device, workload and fleet dashboards are generated from the metrics
+the exporter registers with the same flags, environment variables and config
+file, so they use exactly the metric names and labels it exposes. The workload
+dashboard needs pod labels and is skipped with --kubernetes-mode off;
+--rbln-daemon-endpoint adds a source variable.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, _, err := newConfigLoader(os.Getenv, cmd.Flags()).load()
+			if err != nil {
+				return err
+			}
+			// Dashboards are usually generated away from the nodes, so pod
+			// labels are assumed unless they are turned off explicitly.
+			includePodLabels := b.cfg.KubernetesMode != KubernetesModeOff
+			catalog, err := collector.Catalog(b.cfg.Collectors, includePodLabels, len(b.cfg.DaemonEndpoints) > 0)
+			if err != nil {
+				return err
+			}
+			boards, err := dashboards.Generate(catalog)
+			if err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(outputDir, 0o755); err != nil {
+				return err
+			}
+			for _, board := range boards {
+				data, err := json.MarshalIndent(board, "", "  ")
+				if err != nil {
+					return err
+				}
+				path := filepath.Join(outputDir, board.UID+".json")
+				if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+					return fmt.Errorf("failed to write dashboard: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), path)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "d", ".", "Directory the dashboard JSON files are written to")
+	return cmd
+}
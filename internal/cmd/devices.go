@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/restapi"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/scheduler"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v2"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputCSV   = "csv"
+)
+
+// deviceOutput extends the REST API representation with the PCI address,
+// which is only known on the node itself.
+type deviceOutput struct {
+	restapi.Device
+	PCIAddress string `json:"pci_address,omitempty"`
+}
+
+type deviceListOutput struct {
+	Hostname  string         `json:"hostname"`
+	Timestamp time.Time      `json:"timestamp"`
+	Devices   []deviceOutput `json:"devices"`
+}
+
+func newDevicesCommand() *cobra.Command {
+	var (
+		output string
+		watch  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "devices",
+		Short: "Print the devices reported by the rbln-daemon",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch output {
+			case outputTable, outputJSON, outputYAML, outputCSV:
+			default:
+				return fmt.Errorf("output must be one of %q, %q, %q, %q", outputTable, outputJSON, outputYAML, outputCSV)
+			}
+			b, _, err := newConfigLoader(os.Getenv, cmd.Flags()).load()
+			if err != nil {
+				return err
+			}
+			// Keep stdout for the device list.
+			logging.Setup(os.Stderr, mustParseLevel(b.cfg.LogLevel), b.cfg.LogFormat)
+			return printDevices(cmd.Context(), cmd.OutOrStdout(), b.cfg, output, watch)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format: table, json, yaml, csv")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Refresh the output every --interval until interrupted")
+	return cmd
+}
+
+func printDevices(ctx context.Context, w io.Writer, config Config, output string, watch bool) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	sources, err := newSources(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, source := range sources {
+			_ = source.Client.Close()
+		}
+	}()
+
+	podResourceMapper := collector.NewNoopPodResourceMapper()
+	isKubernetes := resolveKubernetesMode(config.KubernetesMode)
+	if isKubernetes {
+		if mapper, err := collector.NewPodResourceMapper(ctx); err != nil {
+			slog.Warn("pod owners are not shown", "err", err)
+		} else {
+			podResourceMapper = mapper
+		}
+	}
+	pciAddresses, err := collector.DevicePCIAddresses()
+	if err != nil {
+		slog.Debug("failed to read PCI addresses", "err", err)
+	}
+
+	snapshots := collector.NewSnapshotStore()
+	// Only the snapshot is used, so no metric groups are updated.
+	collectors := collector.NewCollectorFactory(podResourceMapper, snapshots, prometheus.NewRegistry(), sources, config.NodeName, isKubernetes).NewCollectors(nil)
+	sched := scheduler.NewScheduler(podResourceMapper, collectors, config.Interval)
+
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		if err := sched.RunOnce(ctx); err != nil {
+			if !watch {
+				return err
+			}
+			slog.Warn("failed to read devices", "err", err)
+		} else {
+			snapshot, _ := snapshots.Latest()
+			if err := writeDevices(w, newDeviceListOutput(snapshot, pciAddresses), output, watch, frame); err != nil {
+				return err
+			}
+		}
+		if !watch {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func newDeviceListOutput(snapshot collector.Snapshot, pciAddresses map[collector.DeviceName]string) deviceListOutput {
+	list := restapi.NewDeviceList(snapshot)
+	out := deviceListOutput{
+		Hostname:  list.Hostname,
+		Timestamp: list.Timestamp,
+		Devices:   make([]deviceOutput, 0, len(list.Devices)),
+	}
+	for _, device := range list.Devices {
+		d := deviceOutput{Device: device}
+		// PCI addresses describe local devices only; see collector.Source.
+		if device.Source == "" {
+			d.PCIAddress = pciAddresses[collector.DeviceName(device.Name)]
+		}
+		out.Devices = append(out.Devices, d)
+	}
+	return out
+}
+
+func writeDevices(w io.Writer, list deviceListOutput, output string, watch bool, frame int) error {
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	case outputYAML:
+		// Go through JSON so that the YAML keys match the JSON field names.
+		data, err := json.Marshal(list)
+		if err != nil {
+			return err
+		}
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if data, err = yaml.Marshal(doc); err != nil {
+			return err
+		}
+		if watch {
+			data = append([]byte("---\n"), data...)
+		}
+		_, err = w.Write(data)
+		return err
+	case outputCSV:
+		return writeDevicesCSV(w, list, frame == 0)
+	default:
+		if watch {
+			// Clear the terminal like watch(1).
+			fmt.Fprint(w, "\033[H\033[2J")
+			fmt.Fprintf(w, "%s  %s\n\n", list.Hostname, list.Timestamp.Format(time.RFC3339))
+		}
+		return writeDevicesTable(w, list)
+	}
+}
+
+func writeDevicesTable(w io.Writer, list deviceListOutput) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCARD\tUUID\tPCI\tTEMP\tPOWER\tMEMORY\tUTIL\tHEALTH\tPOD")
+	for _, d := range list.Devices {
+		name := d.Name
+		if d.Source != "" {
+			name = d.Source + "/" + d.Name
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.0fC\t%.1fW\t%.1f/%.1f GiB\t%.1f%%\t%s\t%s\n",
+			name, d.Card, d.UUID, orDash(d.PCIAddress), d.TemperatureCelsius, d.PowerWatts,
+			float64(d.MemoryUsedBytes)/collector.GiBToBytes, float64(d.MemoryTotalBytes)/collector.GiBToBytes,
+			d.UtilizationPercent, healthName(d.Device), podNames(d.Pods))
+	}
+	return tw.Flush()
+}
+
+func writeDevicesCSV(w io.Writer, list deviceListOutput, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		_ = cw.Write([]string{
+			"timestamp", "hostname", "source", "name", "card", "uuid", "pci_address", "temperature_celsius", "power_watts",
+			"memory_used_bytes", "memory_total_bytes", "utilization_percent", "health_status", "pod_namespace", "pod_name", "container",
+		})
+	}
+	// Devices shared by several containers get a row per container, like
+	// their Prometheus series.
+	for _, d := range list.Devices {
+		pods := d.Pods
+		if len(pods) == 0 {
+			pods = []restapi.Pod{{}}
+		}
+		for _, pod := range pods {
+			_ = cw.Write([]string{
+				d.Timestamp.Format(time.RFC3339), d.Hostname, d.Source, d.Name, d.Card, d.UUID, d.PCIAddress,
+				formatFloat(d.TemperatureCelsius), formatFloat(d.PowerWatts),
+				strconv.FormatUint(d.MemoryUsedBytes, 10), strconv.FormatUint(d.MemoryTotalBytes, 10),
+				formatFloat(d.UtilizationPercent), strconv.Itoa(d.HealthStatus), pod.Namespace, pod.Name, pod.Container,
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func healthName(d restapi.Device) string {
+	if d.Healthy {
+		return "OK"
+	}
+	return fmt.Sprintf("FAILED(%d)", d.HealthStatus)
+}
+
+// podNames lists the pods owning a device. Containers of the same pod are
+// listed once.
+func podNames(pods []restapi.Pod) string {
+	if len(pods) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	return strings.Join(slices.Compact(names), ",")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
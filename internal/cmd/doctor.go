@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/doctor"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the daemon, pod-resources, sysfs and listen addresses the exporter depends on",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != outputTable && output != outputJSON {
+				return fmt.Errorf("output must be one of %q, %q", outputTable, outputJSON)
+			}
+			b, _, err := newConfigLoader(os.Getenv, cmd.Flags()).load()
+			if err != nil {
+				return err
+			}
+			logging.Setup(os.Stderr, mustParseLevel(b.cfg.LogLevel), b.cfg.LogFormat)
+
+			results := doctor.Run(cmd.Context(), newDoctorConfig(b.cfg))
+			if output == outputJSON {
+				err = doctor.WriteJSON(cmd.OutOrStdout(), results)
+			} else {
+				err = doctor.WriteText(cmd.OutOrStdout(), results)
+			}
+			if err != nil {
+				return err
+			}
+			if n := doctor.Failed(results); n > 0 {
+				return fmt.Errorf("%d of %d checks failed", n, len(results))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format: table, json")
+	return cmd
+}
+
+func newDoctorConfig(config Config) doctor.Config {
+	cfg := doctor.Config{
+		ListenAddresses:      config.Server.ListenAddresses,
+		PodResources:         resolveKubernetesMode(config.KubernetesMode),
+		PodResourcesRequired: config.KubernetesMode == KubernetesModeOn,
+	}
+	if config.GRPC.Enabled() {
+		cfg.ListenAddresses = append(cfg.ListenAddresses, config.GRPC.ListenAddress)
+	}
+	if len(config.DaemonEndpoints) == 0 {
+		cfg.Daemons = []doctor.Daemon{{Address: config.RBLNDaemonURL}}
+	}
+	for _, endpoint := range config.DaemonEndpoints {
+		cfg.Daemons = append(cfg.Daemons, doctor.Daemon{Name: endpoint.Name, Address: endpoint.Address})
+	}
+	return cfg
+}
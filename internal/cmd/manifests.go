@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/manifests"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/version"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v2"
+)
+
+const defaultImage = "docker.io/rebellions/rbln-metrics-exporter"
+
+// manifestFlags are the settings the manifests set themselves; they are not
+// copied into the config file.
+var manifestFlags = map[string]bool{
+	configFileFlag:    true,
+	"rbln-daemon-url": true,
+	"kubernetes-mode": true,
+	"node-name":       true,
+	"port":            true,
+	"listen-address":  true,
+}
+
+var releaseVersion = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+
+func newManifestsCommand() *cobra.Command {
+	o := manifests.Options{
+		Name:                 "rbln-metrics-exporter",
+		Namespace:            "rbln-system",
+		ServiceName:          "rbln-metrics-exporter-svc",
+		Transport:            manifests.TransportNodeIP,
+		NodeSelector:         manifests.NodeSelectorDeploy,
+		ServiceMonitor:       true,
+		ServiceMonitorLabels: map[string]string{},
+		NetworkPolicy:        true,
+		PrometheusNamespace:  "monitoring",
+		ScrapeInterval:       "30s",
+	}
+	var (
+		tolerations []string
+		tolerateAll bool
+	)
+	cmd := &cobra.Command{
+		Use:   "manifests",
+		Short: "Write Kubernetes manifests that deploy the exporter",
+		Long: `Write Kubernetes manifests that deploy the exporter.
+
+The DaemonSet, Service, ServiceMonitor, ServiceAccount, NetworkPolicy and, with
+--kube-auth, the RBAC rules are rendered from the exporter's own flags,
+environment variables and config file, which are validated as by the exporter.
+Settings other than the daemon URL, Kubernetes mode and port are passed in a
+config file mounted from a ConfigMap.
+
+The container drops all capabilities and uses a read-only root filesystem. It
+runs as root only when it has to open the kubelet pod-resources socket or the
+daemon's unix socket, and as nobody otherwise.`,
+		Example: `  rbln-metrics-exporter manifests | kubectl apply -f -
+  rbln-metrics-exporter manifests --node-selector present --tolerate-all --kubernetes-mode off
+  rbln-metrics-exporter manifests --daemon-transport unix --rbln-daemon-url unix:///run/rbln/daemon.sock`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, fs, err := newConfigLoader(os.Getenv, cmd.Flags()).load()
+			if err != nil {
+				return err
+			}
+			if len(b.cfg.Server.ListenAddresses) != 1 || b.cfg.Server.ListenAddresses[0] != fmt.Sprintf(":%d", b.cfg.Port) {
+				return fmt.Errorf("manifests serve the metrics on --port, --listen-address is not supported")
+			}
+			config, err := manifestConfig(fs)
+			if err != nil {
+				return err
+			}
+
+			if o.Image == "" {
+				o.Image = defaultImage + ":" + imageTag()
+			}
+			if _, tag, ok := strings.Cut(o.Image[strings.LastIndex(o.Image, "/")+1:], ":"); ok {
+				o.Version = tag
+			}
+			if len(b.cfg.DaemonEndpoints) == 0 {
+				o.DaemonURL = b.cfg.RBLNDaemonURL
+			}
+			o.KubernetesMode = b.cfg.KubernetesMode
+			o.PodResources = b.cfg.KubernetesMode != KubernetesModeOff
+			o.Port = b.cfg.Port
+			o.Config = config
+			o.KubeAuth = b.cfg.Server.KubeAuth.Enabled
+			if tolerateAll {
+				o.Tolerations = append(o.Tolerations, manifests.Toleration{Operator: "Exists"})
+			}
+			for _, value := range tolerations {
+				t, err := parseToleration(value)
+				if err != nil {
+					return err
+				}
+				o.Tolerations = append(o.Tolerations, t)
+			}
+			if o.NetworkPolicySkipped() {
+				fmt.Fprintf(cmd.ErrOrStderr(), "skipped NetworkPolicy: pods in the host network are not selected by network policies\n")
+			}
+			return manifests.Write(cmd.OutOrStdout(), o)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&o.Namespace, "namespace", o.Namespace, "Namespace of the exporter")
+	flags.StringVar(&o.ServiceName, "service-name", o.ServiceName, "Name of the Service")
+	flags.StringVar(&o.Image, "image", "", "Exporter image (default "+defaultImage+":<version>)")
+	flags.StringVar(&o.Transport, "daemon-transport", o.Transport, "How the exporter reaches the daemon: "+strings.Join(manifests.Transports(), ", "))
+	flags.StringVar(&o.NodeSelector, "node-selector", o.NodeSelector, "Nodes to run on: deploy (rebellions.ai/npu.deploy.metrics-exporter=true), present (rebellions.ai/npu.present=true) or none")
+	flags.StringArrayVar(&tolerations, "toleration", nil, "Tolerate a taint given as KEY[=VALUE][:EFFECT] (repeatable)")
+	flags.BoolVar(&tolerateAll, "tolerate-all", false, "Tolerate every taint")
+	flags.BoolVar(&o.ServiceMonitor, "service-monitor", o.ServiceMonitor, "Include a Prometheus Operator ServiceMonitor")
+	flags.StringToStringVar(&o.ServiceMonitorLabels, "service-monitor-labels", o.ServiceMonitorLabels, "Labels of the ServiceMonitor, e.g. to match the serviceMonitorSelector of Prometheus (e.g. release=prometheus)")
+	flags.StringVar(&o.ScrapeInterval, "scrape-interval", o.ScrapeInterval, "Scrape interval of the ServiceMonitor; the scrape timeout is 10s or the interval if shorter")
+	flags.BoolVar(&o.NetworkPolicy, "network-policy", o.NetworkPolicy, "Include a NetworkPolicy that only admits scrapes from --prometheus-namespace (not with --daemon-transport host-network)")
+	flags.StringVar(&o.PrometheusNamespace, "prometheus-namespace", o.PrometheusNamespace, "Namespace of Prometheus")
+	return cmd
+}
+
+// manifestConfig returns the settings given on the command line, in the
+// environment or in the config file, in the config file format.
+func manifestConfig(fs *pflag.FlagSet) ([]byte, error) {
+	var (
+		out yaml.MapSlice
+		err error
+	)
+	fs.VisitAll(func(f *pflag.Flag) {
+		if manifestFlags[f.Name] || (!f.Changed && os.Getenv(envKey(f.Name)) == "") {
+			return
+		}
+		if redactedFlags[f.Name] || (urlFlags[f.Name] && logging.RedactURL(f.Value.String()) != f.Value.String()) {
+			err = fmt.Errorf("%s holds credentials, set %s from a Secret instead", f.Name, envKey(f.Name))
+			return
+		}
+		out = append(out, yaml.MapItem{Key: f.Name, Value: typedValue(f)})
+	})
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+	return yaml.Marshal(out)
+}
+
+// imageTag is the image tag of release builds and latest otherwise.
+func imageTag() string {
+	if v := version.Get().Version; releaseVersion.MatchString(v) {
+		return v
+	}
+	return "latest"
+}
+
+func parseToleration(value string) (manifests.Toleration, error) {
+	t := manifests.Toleration{Operator: "Exists"}
+	rest, effect, ok := strings.Cut(value, ":")
+	if ok {
+		switch effect {
+		case "NoSchedule", "PreferNoSchedule", "NoExecute":
+			t.Effect = effect
+		default:
+			return t, fmt.Errorf("invalid toleration %q: effect must be NoSchedule, PreferNoSchedule or NoExecute", value)
+		}
+	}
+	t.Key, t.Value, ok = strings.Cut(rest, "=")
+	if ok {
+		t.Operator = "Equal"
+	}
+	if t.Key == "" {
+		return t, fmt.Errorf("invalid toleration %q: must be KEY[=VALUE][:EFFECT]", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// clearEnv unsets the exporter's environment variables, as make manifests
+// runs the command with env -i.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, "RBLN_METRICS_EXPORTER_") || key == "NODE_NAME" {
+			t.Setenv(key, "")
+		}
+	}
+}
+
+func runManifests(t *testing.T, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	app := NewApp()
+	app.SetArgs(append([]string{"manifests"}, args...))
+	app.SetOut(&out)
+	app.SetErr(&errOut)
+	err = app.Execute()
+	return out.String(), errOut.String(), err
+}
+
+// TestManifestsReference keeps the reference manifests in sync with the
+// manifests command; run make manifests after changing either.
+func TestManifestsReference(t *testing.T) {
+	clearEnv(t)
+	common := []string{"--image", defaultImage + ":latest", "--service-monitor=false", "--network-policy=false"}
+	tests := []struct {
+		file string
+		args []string
+	}{
+		{file: "daemonset.yaml", args: common},
+		{file: "daemonset-kubernetes-off.yaml", args: append(common[:len(common):len(common)], "--kubernetes-mode", "off", "--service-name", "rbln-metrics-exporter-service")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			want, err := os.ReadFile("../../deployments/kubernetes/" + tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _, err := runManifests(t, tt.args...)
+			if err != nil {
+				t.Fatalf("manifests %v: %v", tt.args, err)
+			}
+			if got != string(want) {
+				t.Errorf("deployments/kubernetes/%s is out of date, run make manifests; manifests %v wrote:\n%s", tt.file, tt.args, got)
+			}
+		})
+	}
+}
+
+func TestManifests(t *testing.T) {
+	clearEnv(t)
+	tests := []struct {
+		name       string
+		args       []string
+		wantErr    string
+		want       []string
+		wantAbsent []string
+		wantStderr string
+	}{
+		{
+			name: "defaults",
+			args: []string{"--image", "example.com/exporter:v1.2.3"},
+			want: []string{"kind: ServiceMonitor", "interval: 30s", "scrapeTimeout: 10s", "kind: NetworkPolicy", "name: rbln-metrics-exporter-svc", "app.kubernetes.io/version: v1.2.3"},
+		},
+		{
+			name: "short scrape interval",
+			args: []string{"--image", "exporter:latest", "--scrape-interval", "5s"},
+			want: []string{"interval: 5s", "scrapeTimeout: 5s"},
+		},
+		{
+			name:    "invalid scrape interval",
+			args:    []string{"--image", "exporter:latest", "--scrape-interval", "30"},
+			wantErr: `invalid scrape interval "30"`,
+		},
+		{
+			name:       "host network skips the network policy",
+			args:       []string{"--image", "exporter:latest", "--daemon-transport", "host-network"},
+			want:       []string{"hostNetwork: true", "kind: ServiceMonitor"},
+			wantAbsent: []string{"kind: NetworkPolicy"},
+			wantStderr: "skipped NetworkPolicy",
+		},
+		{
+			name: "settings go into the config file",
+			args: []string{"--image", "exporter:latest", "--interval", "10", "--collectors", "memory"},
+			want: []string{"kind: ConfigMap", "interval: 10", "- memory"},
+		},
+		{
+			name:    "credentials are rejected",
+			args:    []string{"--image", "exporter:latest", "--otlp-endpoint", "collector:4317", "--otlp-headers", "authorization=Bearer x"},
+			wantErr: "otlp-headers holds credentials",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stderr, err := runManifests(t, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("manifests do not contain %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.wantAbsent {
+				if strings.Contains(got, s) {
+					t.Errorf("manifests contain %q:\n%s", s, got)
+				}
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
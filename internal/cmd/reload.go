@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+const configPollInterval = 10 * time.Second
+
+// restartFields clears the settings that can be changed without a restart,
+// leaving the ones that only take effect at startup.
+func restartFields(c Config) Config {
+	c.Interval = 0
+	c.OTLP.Interval = 0
+	c.ReadinessMaxStale = 0
+	c.Collectors = nil
+	c.LogLevel = ""
+	return c
+}
+
+// watchConfig reloads the config file on SIGHUP and when its modification
+// time changes. Only interval, otlp-interval, readiness-max-stale-intervals,
+// collectors and log-level are applied; other changes are logged and need a
+// restart. A new interval reaches the collection and every push loop.
+func watchConfig(ctx context.Context, loader *configLoader, startup Config, apply func(Config)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	modTime := fileModTime(startup.ConfigFile)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			modTime = fileModTime(startup.ConfigFile)
+		case <-ticker.C:
+			t := fileModTime(startup.ConfigFile)
+			if t.Equal(modTime) {
+				continue
+			}
+			modTime = t
+		}
+
+		b, _, err := loader.load()
+		if err != nil {
+			slog.Error("failed to reload config, keeping the current settings", "file", startup.ConfigFile, "err", err)
+			continue
+		}
+		if !reflect.DeepEqual(restartFields(startup), restartFields(b.cfg)) {
+			slog.Warn("config file changes other than interval, otlp-interval, readiness-max-stale-intervals, collectors and log-level require a restart", "file", startup.ConfigFile)
+		}
+		apply(b.cfg)
+		slog.Info("reloaded config", "file", startup.ConfigFile, "interval", b.cfg.Interval, "otlp_interval", b.cfg.OTLP.Interval, "readiness_max_stale_intervals", b.cfg.ReadinessMaxStale, "collectors", b.cfg.Collectors, "log_level", b.cfg.LogLevel)
+	}
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func finalizedConfig(t *testing.T, args ...string) Config {
+	t.Helper()
+	b := newConfigBuilder(func(string) string { return "" })
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	b.bindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.finalize(); err != nil {
+		t.Fatal(err)
+	}
+	return b.cfg
+}
+
+func TestRestartFields(t *testing.T) {
+	startup := []string{"--otlp-endpoint", "collector:4317", "--interval", "5"}
+	tests := []struct {
+		name        string
+		args        []string
+		wantRestart bool
+	}{
+		{name: "interval followed by otlp", args: []string{"--otlp-endpoint", "collector:4317", "--interval", "10"}},
+		{name: "otlp interval", args: []string{"--otlp-endpoint", "collector:4317", "--interval", "5", "--otlp-interval", "30s"}},
+		{name: "log level and collectors", args: []string{"--otlp-endpoint", "collector:4317", "--interval", "5", "--log-level", "debug", "--collectors", "memory"}},
+		{name: "otlp endpoint", args: []string{"--otlp-endpoint", "other:4317", "--interval", "5"}, wantRestart: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := finalizedConfig(t, startup...), finalizedConfig(t, tt.args...)
+			if got := !reflect.DeepEqual(restartFields(before), restartFields(after)); got != tt.wantRestart {
+				t.Errorf("restart required = %v, want %v", got, tt.wantRestart)
+			}
+		})
+	}
+}
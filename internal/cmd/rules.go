@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/rules"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v2"
+)
+
+const (
+	outputRules          = "rules"
+	outputPrometheusRule = "prometheusrule"
+)
+
+// prometheusRule is the PrometheusRule custom resource of the Prometheus
+// Operator.
+type prometheusRule struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   prometheusRuleMeta `yaml:"metadata"`
+	Spec       rules.File         `yaml:"spec"`
+}
+
+type prometheusRuleMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+func newRulesCommand() *cobra.Command {
+	var (
+		output     string
+		name       string
+		namespace  string
+		labels     map[string]string
+		cardLimits []string
+	)
+	t := rules.DefaultThresholds()
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "Write Prometheus alerting and recording rules for the exporter's metrics",
+		Long: `Write Prometheus alerting and recording rules for the exporter's metrics.
+
+The rules are generated from the metrics the exporter registers with the same
+flags, environment variables and config file, and are validated against them.
+Rules whose metrics or labels are not exposed, e.g. because a collector is
+disabled or --kubernetes-mode is off, are left out and listed on stderr.
+
+Temperature and power alerts compare each device against the limits of its
+card model given with --card-limit, with "default" for the other cards. The
+rbln-daemon does not report the limits, so the alerts are left out without
+--card-limit.`,
+		Example: `  rbln-metrics-exporter rules > rbln-rules.yaml
+  rbln-metrics-exporter rules -o prometheusrule --namespace monitoring --labels release=prometheus | kubectl apply -f -
+  rbln-metrics-exporter rules --temperature-threshold 80 --card-limit RBLN-CA25=85:300 --card-limit default=85:150`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != outputRules && output != outputPrometheusRule {
+				return fmt.Errorf("invalid output %q: must be %s or %s", output, outputRules, outputPrometheusRule)
+			}
+			for _, value := range cardLimits {
+				card, limits, err := parseCardLimit(value)
+				if err != nil {
+					return err
+				}
+				t.CardLimits[card] = limits
+			}
+
+			b, _, err := newConfigLoader(os.Getenv, cmd.Flags()).load()
+			if err != nil {
+				return err
+			}
+			// Like the dashboards, rules are usually generated away from the
+			// nodes, so pod labels are assumed unless they are turned off.
+			includePodLabels := b.cfg.KubernetesMode != KubernetesModeOff
+			includeSourceLabel := len(b.cfg.DaemonEndpoints) > 0
+			catalog, err := rulesCatalog(b.cfg.Collectors, includePodLabels, includeSourceLabel)
+			if err != nil {
+				return err
+			}
+			file, skipped, err := rules.Generate(catalog, t)
+			if err != nil {
+				return err
+			}
+			for _, s := range skipped {
+				fmt.Fprintf(cmd.ErrOrStderr(), "skipped %s: %s\n", s.Rule, s.Reason)
+			}
+
+			var doc any = file
+			if output == outputPrometheusRule {
+				doc = prometheusRule{
+					APIVersion: "monitoring.coreos.com/v1",
+					Kind:       "PrometheusRule",
+					Metadata:   prometheusRuleMeta{Name: name, Namespace: namespace, Labels: labels},
+					Spec:       file,
+				}
+			}
+			data, err := yaml.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+	fs := cmd.Flags()
+	fs.StringVarP(&output, "output", "o", outputRules, "Output format: rules (a Prometheus rule file) or prometheusrule (a Prometheus Operator resource)")
+	fs.StringVar(&name, "name", "rbln-metrics-exporter", "Name of the PrometheusRule")
+	fs.StringVar(&namespace, "namespace", "rbln-system", "Namespace of the PrometheusRule")
+	fs.StringToStringVar(&labels, "labels", nil, "Labels of the PrometheusRule, e.g. to match the ruleSelector of Prometheus (e.g. release=prometheus)")
+	fs.Float64Var(&t.TemperaturePercent, "temperature-threshold", t.TemperaturePercent, "Temperature alert threshold in percent of the card's temperature limit")
+	fs.Float64Var(&t.PowerPercent, "power-threshold", t.PowerPercent, "Power alert threshold in percent of the card's power limit")
+	fs.Float64Var(&t.MemoryPercent, "memory-threshold", t.MemoryPercent, "Alert threshold of the used DRAM in percent")
+	fs.Float64Var(&t.IdleUtilizationPercent, "idle-threshold", t.IdleUtilizationPercent, "Utilization in percent below which an allocated device is idle")
+	fs.DurationVar(&t.IdleFor, "idle-for", t.IdleFor, "How long an allocated device must be idle before it is alerted on")
+	fs.DurationVar(&t.StaleAfter, "stale-after", t.StaleAfter, "Age of the last collection after which the metrics are stale")
+	fs.DurationVar(&t.EventWindow, "event-window", t.EventWindow, "How long a reset or TDR event keeps its alert firing")
+	fs.DurationVar(&t.For, "for", t.For, "How long a condition must hold before its alert fires")
+	fs.StringArrayVar(&cardLimits, "card-limit", nil, "Limits of a card model as CARD=TEMPERATURE:WATTS, from its datasheet; use default for cards without limits (repeatable)")
+	return cmd
+}
+
+// rulesCatalog describes the device, exporter and event metrics the rules
+// can use.
+func rulesCatalog(groups []string, includePodLabels, includeSourceLabel bool) ([]collector.MetricDescription, error) {
+	devices, err := collector.Catalog(groups, includePodLabels, includeSourceLabel)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := collector.ExporterCatalog(includeSourceLabel)
+	if err != nil {
+		return nil, err
+	}
+	deviceEvents, err := events.Catalog(includeSourceLabel)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(devices, exporter...), deviceEvents...), nil
+}
+
+func parseCardLimit(value string) (string, rules.CardLimits, error) {
+	card, limits, ok := strings.Cut(value, "=")
+	temperature, power, ok2 := strings.Cut(limits, ":")
+	if !ok || !ok2 || card == "" {
+		return "", rules.CardLimits{}, fmt.Errorf("invalid card limit %q: must be CARD=TEMPERATURE:WATTS", value)
+	}
+	maxTemperature, err := strconv.ParseFloat(temperature, 64)
+	if err != nil || maxTemperature <= 0 {
+		return "", rules.CardLimits{}, fmt.Errorf("invalid temperature limit in %q", value)
+	}
+	maxPower, err := strconv.ParseFloat(power, 64)
+	if err != nil || maxPower <= 0 {
+		return "", rules.CardLimits{}, fmt.Errorf("invalid power limit in %q", value)
+	}
+	return card, rules.CardLimits{MaxTemperature: maxTemperature, MaxPower: maxPower}, nil
+}
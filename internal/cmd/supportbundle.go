@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/scheduler"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/supportbundle"
+	"github.com/spf13/cobra"
+)
+
+// recordedEvents is the number of daemon events kept for support bundles.
+const recordedEvents = 500
+
+// bundleState is the part of a running exporter that goes into a support bundle.
+type bundleState struct {
+	loader       *configLoader
+	sources      []collector.Source
+	podResources *collector.PodResourceMapper
+	gatherer     prometheus.Gatherer
+	recorder     *events.Recorder
+}
+
+func (s bundleState) write(ctx context.Context, w io.Writer) error {
+	records, dropped := s.recorder.Events()
+	return supportbundle.Write(ctx, w, supportbundle.Input{
+		Config:        s.redactedConfig(),
+		Sources:       s.sources,
+		PodResources:  s.podResources.Snapshot(),
+		Gatherer:      s.gatherer,
+		Events:        records,
+		DroppedEvents: dropped,
+		Logs:          logging.RecentLogs(),
+	})
+}
+
+// redactedConfig reloads the configuration so that the bundle shows the
+// settings in the config file format, with secrets redacted.
+func (s bundleState) redactedConfig() []byte {
+	var buf bytes.Buffer
+	_, fs, err := s.loader.load()
+	if err == nil {
+		err = writeConfig(&buf, fs)
+	}
+	if err != nil {
+		return fmt.Appendf(nil, "# failed to load the configuration: %v\n", err)
+	}
+	return buf.Bytes()
+}
+
+// writeFile writes a support bundle to path. The file is only readable by
+// the owner as it contains logs and configuration.
+func (s bundleState) writeFile(ctx context.Context, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create support bundle: %w", err)
+	}
+	if err := s.write(ctx, f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// writeSupportBundleOnSignal writes a support bundle to dir whenever the
+// exporter receives SIGUSR1.
+func writeSupportBundleOnSignal(ctx context.Context, state bundleState, dir, nodeName string) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	defer signal.Stop(usr1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-usr1:
+		}
+		path := filepath.Join(dir, supportBundleName(nodeName, time.Now()))
+		if err := state.writeFile(ctx, path); err != nil {
+			slog.Error("failed to write support bundle", "err", err)
+			continue
+		}
+		slog.Info("support bundle written", "file", path)
+	}
+}
+
+func supportBundleName(nodeName string, now time.Time) string {
+	return fmt.Sprintf("rbln-support-bundle-%s-%s.tar.gz", nodeName, now.UTC().Format("20060102T150405Z"))
+}
+
+func newSupportBundleCommand() *cobra.Command {
+	var outputFile string
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Write a tar.gz archive with diagnostics for a support request",
+		Long: `Write a tar.gz archive with the effective configuration (secrets redacted),
+build information, the raw responses of all read-only rbln-daemon RPCs, daemon
+events, the pod-resources mapping, sysfs attributes of every rebellions PCI
+device, a /metrics snapshot and the exporter logs.
+
+A running exporter writes the same archive, including its recent logs and
+events, to --support-bundle-dir when it receives SIGUSR1.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loader := newConfigLoader(os.Getenv, cmd.Flags())
+			b, _, err := loader.load()
+			if err != nil {
+				return err
+			}
+			logging.Setup(os.Stderr, mustParseLevel(b.cfg.LogLevel), b.cfg.LogFormat)
+			return writeSupportBundle(cmd.Context(), cmd.OutOrStdout(), b.cfg, loader, outputFile)
+		},
+	}
+	cmd.Flags().StringVarP(&outputFile, "output-file", "f", "", `Archive to write, "-" for stdout (default "rbln-support-bundle-<node>-<time>.tar.gz")`)
+	return cmd
+}
+
+func writeSupportBundle(ctx context.Context, stdout io.Writer, config Config, loader *configLoader, outputFile string) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sources, err := newSources(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, source := range sources {
+			_ = source.Client.Close()
+		}
+	}()
+
+	podResourceMapper := collector.NewNoopPodResourceMapper()
+	isKubernetes := resolveKubernetesMode(config.KubernetesMode)
+	if isKubernetes {
+		if mapper, err := collector.NewPodResourceMapper(ctx); err != nil {
+			slog.Warn("pod resources are not included", "err", err)
+		} else {
+			podResourceMapper = mapper
+		}
+	}
+
+	registry := prometheus.NewRegistry()
+	snapshots := collector.NewSnapshotStore()
+	collectors := collector.NewCollectorFactory(podResourceMapper, snapshots, registry, sources, config.NodeName, isKubernetes).NewCollectors(config.Collectors)
+	registry.MustRegister(collector.NewBuildInfoCollector(sources, snapshots, config.NodeName))
+
+	// Events are only recorded while the bundle is written; a running
+	// exporter has a longer history.
+	broker := events.NewBroker()
+	recorder := events.NewRecorder(recordedEvents)
+	go recorder.Run(ctx, broker)
+	go events.NewWatcher(sources, snapshots, broker).Run(ctx)
+
+	if err := scheduler.NewScheduler(podResourceMapper, collectors, config.Interval).RunOnce(ctx); err != nil {
+		slog.Warn("failed to collect metrics", "err", err)
+	}
+
+	state := bundleState{
+		loader:       loader,
+		sources:      sources,
+		podResources: podResourceMapper,
+		gatherer:     registry,
+		recorder:     recorder,
+	}
+	if outputFile == "-" {
+		return state.write(ctx, stdout)
+	}
+	if outputFile == "" {
+		outputFile = supportBundleName(config.NodeName, time.Now())
+	}
+	if err := state.writeFile(ctx, outputFile); err != nil {
+		return err
+	}
+	slog.Info("support bundle written", "file", outputFile)
+	return nil
+}
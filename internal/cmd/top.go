@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/scheduler"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/top"
+	"github.com/spf13/cobra"
+)
+
+func newTopCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "top",
+		Short: "Show live device utilization, power, temperature and events in the terminal",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, _, err := newConfigLoader(os.Getenv, cmd.Flags()).load()
+			if err != nil {
+				return err
+			}
+			return runTop(cmd.Context(), b.cfg)
+		},
+	}
+}
+
+func runTop(ctx context.Context, config Config) error {
+	if err := top.CheckTerminal(os.Stdin, os.Stdout); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	sources, err := newSources(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, source := range sources {
+			_ = source.Client.Close()
+		}
+	}()
+	// Stop collecting before the clients are closed.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	podResourceMapper := collector.NewNoopPodResourceMapper()
+	isKubernetes := resolveKubernetesMode(config.KubernetesMode)
+	if isKubernetes {
+		if mapper, err := collector.NewPodResourceMapper(ctx); err != nil {
+			slog.Warn("pod owners are not shown", "err", err)
+		} else {
+			podResourceMapper = mapper
+		}
+	}
+
+	snapshots := collector.NewSnapshotStore()
+	// Only the snapshots are used, so no metric groups are updated.
+	collectors := collector.NewCollectorFactory(podResourceMapper, snapshots, prometheus.NewRegistry(), sources, config.NodeName, isKubernetes).NewCollectors(nil)
+	sched := scheduler.NewScheduler(podResourceMapper, collectors, config.Interval)
+
+	broker := events.NewBroker()
+	go events.NewWatcher(sources, snapshots, broker).Run(ctx)
+
+	errs := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for {
+			if err := sched.RunOnce(ctx); err != nil && ctx.Err() == nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	// Log lines would corrupt the screen; collection errors are shown in the
+	// view instead.
+	lvl := mustParseLevel(config.LogLevel)
+	logging.Setup(io.Discard, lvl, config.LogFormat)
+	defer logging.Setup(os.Stderr, lvl, config.LogFormat)
+
+	return top.Run(ctx, os.Stdin, os.Stdout, top.Options{
+		Snapshots: snapshots,
+		Broker:    broker,
+		Errors:    errs,
+	})
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/version"
+	"github.com/spf13/cobra"
+)
+
+func newVersionCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of the exporter",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeVersion(cmd.OutOrStdout(), version.Get(), output)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format: table, json")
+	return cmd
+}
+
+func writeVersion(w io.Writer, info version.Info, output string) error {
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Version:\t%s\n", info.Version)
+		fmt.Fprintf(tw, "Revision:\t%s\n", info.Revision)
+		fmt.Fprintf(tw, "Build date:\t%s\n", info.BuildDate)
+		fmt.Fprintf(tw, "Go version:\t%s\n", info.GoVersion)
+		fmt.Fprintf(tw, "Platform:\t%s\n", info.Platform)
+		fmt.Fprintf(tw, "API version:\t%s\n", info.APIVersion)
+		return tw.Flush()
+	default:
+		return fmt.Errorf("output must be one of %q, %q", outputTable, outputJSON)
+	}
+}
@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	AllocatableDevicesMetric = "rbln_kubelet_allocatable_devices"
+	AllocatedDevicesMetric   = "rbln_kubelet_allocated_devices"
+
+	resource = "resource"
+)
+
+// AllocationCollector exposes the RBLN devices the kubelet can allocate and
+// has allocated to pods per resource name, as of the last pod resource sync.
+// Allocatable devices are only reported by kubelets that serve the
+// pod-resources v1 API with GetAllocatableResources.
+type AllocationCollector struct {
+	allocatable *prometheus.Desc
+	allocated   *prometheus.Desc
+	mapper      *PodResourceMapper
+	nodeName    string
+}
+
+func NewAllocationCollector(mapper *PodResourceMapper, nodeName string) *AllocationCollector {
+	labels := []string{resource, hostname}
+	return &AllocationCollector{
+		allocatable: prometheus.NewDesc(
+			AllocatableDevicesMetric,
+			"RBLN devices the kubelet can allocate to pods",
+			labels, nil,
+		),
+		allocated: prometheus.NewDesc(
+			AllocatedDevicesMetric,
+			"RBLN devices the kubelet has allocated to pods",
+			labels, nil,
+		),
+		mapper:   mapper,
+		nodeName: nodeName,
+	}
+}
+
+func (a *AllocationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- a.allocatable
+	ch <- a.allocated
+}
+
+func (a *AllocationCollector) Collect(ch chan<- prometheus.Metric) {
+	for resourceName, counts := range a.mapper.DeviceCounts() {
+		if counts.Allocatable >= 0 {
+			ch <- prometheus.MustNewConstMetric(a.allocatable, prometheus.GaugeValue, float64(counts.Allocatable), resourceName, a.nodeName)
+		}
+		ch <- prometheus.MustNewConstMetric(a.allocated, prometheus.GaugeValue, float64(counts.Allocated), resourceName, a.nodeName)
+	}
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/version"
+)
+
+const BuildInfoMetric = "rbln_metrics_exporter_build_info"
+
+// BuildInfoCollector exposes the exporter version together with the version
+// of the daemon it is connected to, so that upgrades can be tracked across a
+// fleet. The daemon has no version RPC; its version is the driver version it
+// reports for its devices and is empty until the first successful collection.
+type BuildInfoCollector struct {
+	desc        *prometheus.Desc
+	info        version.Info
+	sources     []string
+	multiSource bool
+	snapshots   *SnapshotStore
+	nodeName    string
+}
+
+func NewBuildInfoCollector(sources []Source, snapshots *SnapshotStore, nodeName string) *BuildInfoCollector {
+	labels := []string{"version", "revision", "build_date", "goversion", "api_version", "daemon_version", hostname}
+	names := make([]string, 0, len(sources))
+	for _, s := range sources {
+		names = append(names, s.Name)
+	}
+	multiSource := len(sources) > 1 || (len(sources) == 1 && sources[0].Name != "")
+	if multiSource {
+		labels = append(labels, source)
+	}
+	return &BuildInfoCollector{
+		desc: prometheus.NewDesc(
+			BuildInfoMetric,
+			"Build information of the exporter and the version of the connected rbln-daemon, always 1",
+			labels, nil,
+		),
+		info:        version.Get(),
+		sources:     names,
+		multiSource: multiSource,
+		snapshots:   snapshots,
+		nodeName:    nodeName,
+	}
+}
+
+func (b *BuildInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.desc
+}
+
+func (b *BuildInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	daemonVersions := make(map[string]string, len(b.sources))
+	if snapshot, ok := b.snapshots.Latest(); ok {
+		for _, device := range snapshot.Devices {
+			if _, ok := daemonVersions[device.Source]; !ok && device.DriverVersion != "" {
+				daemonVersions[device.Source] = device.DriverVersion
+			}
+		}
+	}
+
+	for _, name := range b.sources {
+		values := []string{b.info.Version, b.info.Revision, b.info.BuildDate, b.info.GoVersion, b.info.APIVersion, daemonVersions[name], b.nodeName}
+		if b.multiSource {
+			values = append(values, name)
+		}
+		ch <- prometheus.MustNewConstMetric(b.desc, prometheus.GaugeValue, 1, values...)
+	}
+}
@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// Units of the device metrics in the catalog.
+const (
+	UnitCelsius = "celsius"
+	UnitWatts   = "watts"
+	UnitBytes   = "bytes"
+	UnitPercent = "percent"
+	UnitSeconds = "seconds"
+	UnitNone    = ""
+)
+
+// catalogDevice is the device of the sample snapshot the catalog is built from.
+const catalogDevice = "rbln0"
+
+// helpUnits maps the unit suffix of the collectors' help text, e.g.
+// "DRAM used (bytes)", to the unit of the metric.
+var helpUnits = map[string]string{
+	"C":     UnitCelsius,
+	"W":     UnitWatts,
+	"bytes": UnitBytes,
+	"%":     UnitPercent,
+	"s":     UnitSeconds,
+}
+
+// MetricDescription describes a device metric as the exporter registers it.
+type MetricDescription struct {
+	Name   string
+	Help   string
+	Group  string
+	Unit   string
+	Labels []string
+}
+
+// Catalog returns the device metrics of the given groups, in the order of
+// the groups, with the label set they carry under the given label options.
+// The metrics are read from a registry filled by the actual collectors, so
+// the catalog cannot drift from what /metrics exposes.
+func Catalog(groups []string, includePodLabels, includeSourceLabel bool) ([]MetricDescription, error) {
+	snapshot := Snapshot{
+		IncludePodLabels:   includePodLabels,
+		IncludeSourceLabel: includeSourceLabel,
+		Devices:            []daemon.DeviceInfo{{Name: catalogDevice}},
+	}
+	var catalog []MetricDescription
+	for _, group := range groups {
+		registry, err := NewSnapshotRegistry(context.Background(), snapshot, []string{group})
+		if err != nil {
+			return nil, err
+		}
+		metrics, err := DescribeMetrics(registry, group)
+		if err != nil {
+			return nil, err
+		}
+		catalog = append(catalog, metrics...)
+	}
+	return catalog, nil
+}
+
+// ExporterCatalog returns the metrics about the exporter itself: build info,
+// daemon status and the time of the last collection.
+func ExporterCatalog(includeSourceLabel bool) ([]MetricDescription, error) {
+	sources := []Source{{}}
+	if includeSourceLabel {
+		sources[0].Name = "catalog"
+	}
+	status := newStatusMetrics("", includeSourceLabel)
+	status.setDaemonUp(sources[0], true)
+	status.setCollected(time.Now())
+
+	registry := prometheus.NewRegistry()
+	status.Register(registry)
+	registry.MustRegister(NewBuildInfoCollector(sources, NewSnapshotStore(), ""))
+	return DescribeMetrics(registry, "exporter")
+}
+
+// DescribeMetrics describes the metrics gathered from g. Every metric needs at
+// least one series for its labels to be known.
+func DescribeMetrics(g prometheus.Gatherer, group string) ([]MetricDescription, error) {
+	families, err := g.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather %s metrics: %w", group, err)
+	}
+	metrics := make([]MetricDescription, 0, len(families))
+	for _, mf := range families {
+		var labels []string
+		if len(mf.GetMetric()) > 0 {
+			for _, pair := range mf.GetMetric()[0].GetLabel() {
+				labels = append(labels, pair.GetName())
+			}
+		}
+		slices.Sort(labels)
+		metrics = append(metrics, MetricDescription{
+			Name:   mf.GetName(),
+			Help:   mf.GetHelp(),
+			Group:  group,
+			Unit:   UnitFromHelp(mf.GetHelp()),
+			Labels: labels,
+		})
+	}
+	return metrics, nil
+}
+
+// UnitFromHelp returns the unit declared by the help text of a metric, so
+// the unit is defined once, next to the value the collector exports.
+func UnitFromHelp(help string) string {
+	open := strings.LastIndex(help, "(")
+	if open < 0 || !strings.HasSuffix(help, ")") {
+		return UnitNone
+	}
+	return helpUnits[help[open+1:len(help)-1]]
+}
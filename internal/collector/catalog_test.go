@@ -0,0 +1,27 @@
+package collector
+
+import "testing"
+
+func TestCatalogUnits(t *testing.T) {
+	catalog, err := Catalog([]string{"hardware", "memory", "utilization", "health"}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"RBLN_DEVICE_STATUS:TEMPERATURE": UnitCelsius,
+		"RBLN_DEVICE_STATUS:CARD_POWER":  UnitWatts,
+		"RBLN_DEVICE_STATUS:DRAM_USED":   UnitBytes,
+		"RBLN_DEVICE_STATUS:DRAM_TOTAL":  UnitBytes,
+		"RBLN_DEVICE_STATUS:UTILIZATION": UnitPercent,
+		"RBLN_DEVICE_STATUS:HEALTH":      UnitNone,
+	}
+	for _, m := range catalog {
+		if unit, ok := want[m.Name]; ok && m.Unit != unit {
+			t.Errorf("unit of %s = %q, want %q", m.Name, m.Unit, unit)
+		}
+		delete(want, m.Name)
+	}
+	for name := range want {
+		t.Errorf("catalog has no %s", name)
+	}
+}
@@ -2,31 +2,32 @@ package collector
 
 import (
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
 )
 
 type collectorFactory struct {
 	registry          prometheus.Registerer
-	dClient           *daemon.Client
+	sources           []Source
 	isKubernetes      bool
 	podResourceMapper *PodResourceMapper
+	snapshots         *SnapshotStore
 	nodeName          string
 }
 
-func NewCollectorFactory(podResourceMapper *PodResourceMapper, registry prometheus.Registerer, dClient *daemon.Client, nodeName string, isKubernetes bool) *collectorFactory {
+func NewCollectorFactory(podResourceMapper *PodResourceMapper, snapshots *SnapshotStore, registry prometheus.Registerer, sources []Source, nodeName string, isKubernetes bool) *collectorFactory {
 	return &collectorFactory{
 		registry:          registry,
-		dClient:           dClient,
+		sources:           sources,
 		isKubernetes:      isKubernetes,
 		podResourceMapper: podResourceMapper,
+		snapshots:         snapshots,
 		nodeName:          nodeName,
 	}
 }
 
-func (cf *collectorFactory) NewCollectors() []Collector {
-	collectors := []Collector{
-		NewNPUCollector(cf.dClient, cf.registry, cf.isKubernetes, cf.podResourceMapper, cf.nodeName),
-	}
+func (cf *collectorFactory) NewCollectors(groups []string) []Collector {
+	npuCollector := NewNPUCollector(cf.sources, cf.registry, cf.isKubernetes, cf.podResourceMapper, cf.snapshots, cf.nodeName)
+	npuCollector.SetGroups(groups)
+	collectors := []Collector{npuCollector}
 
 	for _, collector := range collectors {
 		collector.Register(cf.registry)
@@ -0,0 +1,277 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// metricConstructors are the device metric groups that can be selected with
+// the collect[] query parameter of /metrics.
+var metricConstructors = map[string]func(includePodLabels, includeSourceLabel bool) Metric{
+	"hardware":    func(pod, source bool) Metric { return NewHardwareInfoMetric(pod, source) },
+	"health":      func(pod, source bool) Metric { return NewDeviceHealthMetric(pod, source) },
+	"memory":      func(pod, source bool) Metric { return NewMemoryMetric(pod, source) },
+	"utilization": func(pod, source bool) Metric { return NewUtilizationMetric(pod, source) },
+}
+
+// MetricGroups returns the names accepted by collect[].
+func MetricGroups() []string {
+	names := make([]string, 0, len(metricConstructors))
+	for name := range metricConstructors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SnapshotFilter selects the devices of a snapshot. Empty fields match everything.
+type SnapshotFilter struct {
+	// Devices are device names or UUIDs.
+	Devices    []string
+	Namespaces []string
+	// Allocated keeps only devices that are (or are not) assigned to a pod.
+	Allocated *bool
+}
+
+func (f SnapshotFilter) empty() bool {
+	return len(f.Devices) == 0 && len(f.Namespaces) == 0 && f.Allocated == nil
+}
+
+// Apply returns a copy of the snapshot that only contains the matching devices.
+// With namespaces, the owners of shared devices are restricted to the pods in
+// them, so a device does not reveal the pods of other namespaces.
+func (f SnapshotFilter) Apply(snapshot Snapshot) Snapshot {
+	if f.empty() {
+		return snapshot
+	}
+	devices := make([]daemon.DeviceInfo, 0, len(snapshot.Devices))
+	for _, device := range snapshot.Devices {
+		if f.match(device, snapshot.Owners(device)) {
+			devices = append(devices, device)
+		}
+	}
+	snapshot.Devices = devices
+	if len(f.Namespaces) > 0 {
+		podResources := make(map[DeviceName][]PodResourceInfo, len(snapshot.PodResources))
+		for device, owners := range snapshot.PodResources {
+			owners = slices.DeleteFunc(slices.Clone(owners), func(owner PodResourceInfo) bool {
+				return !slices.Contains(f.Namespaces, owner.Namespace)
+			})
+			if len(owners) > 0 {
+				podResources[device] = owners
+			}
+		}
+		snapshot.PodResources = podResources
+	}
+	return snapshot
+}
+
+// match reports whether the device is selected. A shared device matches a
+// namespace when any of its owners runs in it.
+func (f SnapshotFilter) match(device daemon.DeviceInfo, owners []PodResourceInfo) bool {
+	if len(f.Devices) > 0 && !slices.Contains(f.Devices, device.Name) && !slices.Contains(f.Devices, device.UUID) {
+		return false
+	}
+	if len(f.Namespaces) > 0 && !slices.ContainsFunc(owners, func(owner PodResourceInfo) bool {
+		return slices.Contains(f.Namespaces, owner.Namespace)
+	}) {
+		return false
+	}
+	if f.Allocated != nil && *f.Allocated != (len(owners) > 0) {
+		return false
+	}
+	return true
+}
+
+// NewSnapshotRegistry returns a registry holding the metric groups for the
+// snapshot's devices. All groups are included when groups is empty.
+func NewSnapshotRegistry(ctx context.Context, snapshot Snapshot, groups []string) (*prometheus.Registry, error) {
+	if len(groups) == 0 {
+		groups = MetricGroups()
+	}
+	registry := prometheus.NewRegistry()
+	for _, group := range groups {
+		newMetric, ok := metricConstructors[group]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q, must be one of %s", group, strings.Join(MetricGroups(), ", "))
+		}
+		metric := newMetric(snapshot.IncludePodLabels, snapshot.IncludeSourceLabel)
+		metric.Register(registry)
+		metric.UpdateMetrics(ctx, snapshot)
+	}
+	return registry, nil
+}
+
+// NewMetricsHandler serves the registry on /metrics. Requests with filter
+// parameters get a subset of the same gathered series, so filtering never
+// triggers additional daemon calls:
+//
+//	collect[]=<group>      only the given device metric groups (hardware, health, memory, utilization)
+//	device=<name|uuid>     only the given devices
+//	namespace=<namespace>  only devices allocated to pods in the namespace, and only the series of those pods
+//	allocated=true|false   only devices that are (not) allocated to a pod
+//
+// Every parameter can be repeated or hold a comma-separated list. Devices are
+// selected from the latest snapshot; series without a device, such as the
+// exporter status and build info, are always kept.
+func NewMetricsHandler(gatherer prometheus.Gatherer, snapshots *SnapshotStore) http.Handler {
+	unfiltered := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if !slices.ContainsFunc(filterParams, query.Has) {
+			unfiltered.ServeHTTP(w, r)
+			return
+		}
+
+		groups := queryValues(query, "collect[]")
+		for _, group := range groups {
+			if _, ok := metricConstructors[group]; !ok {
+				http.Error(w, fmt.Sprintf("unknown collector %q, must be one of %s", group, strings.Join(MetricGroups(), ", ")), http.StatusBadRequest)
+				return
+			}
+		}
+		filter, err := parseSnapshotFilter(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var devices map[string][]selectedDevice
+		if !filter.empty() {
+			snapshot, _ := snapshots.Latest()
+			devices = selectDevices(filter.Apply(snapshot))
+		}
+		filtered := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			families, err := gatherer.Gather()
+			return filterFamilies(families, groups, devices), err
+		})
+		promhttp.HandlerFor(filtered, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// selectedDevice is a device kept by a SnapshotFilter with the owners its
+// series may carry.
+type selectedDevice struct {
+	source string
+	owners []PodResourceInfo
+}
+
+// selectDevices indexes the devices of a filtered snapshot by name.
+func selectDevices(snapshot Snapshot) map[string][]selectedDevice {
+	devices := make(map[string][]selectedDevice, len(snapshot.Devices))
+	for _, device := range snapshot.Devices {
+		devices[device.Name] = append(devices[device.Name], selectedDevice{
+			source: device.Source,
+			owners: snapshot.Owners(device),
+		})
+	}
+	return devices
+}
+
+// groupFamilies maps the metric family names of the device metric groups to
+// their group.
+var groupFamilies = sync.OnceValue(func() map[string]string {
+	families := make(map[string]string)
+	catalog, err := Catalog(MetricGroups(), false, false)
+	if err != nil {
+		panic(fmt.Sprintf("failed to build the metric catalog: %v", err))
+	}
+	for _, metric := range catalog {
+		families[metric.Name] = metric.Group
+	}
+	return families
+})
+
+// filterFamilies drops the families of device metric groups not in groups,
+// unless groups is empty, and the series of devices not in devices, unless
+// devices is nil.
+func filterFamilies(families []*dto.MetricFamily, groups []string, devices map[string][]selectedDevice) []*dto.MetricFamily {
+	out := families[:0]
+	for _, mf := range families {
+		if group, ok := groupFamilies()[mf.GetName()]; ok && len(groups) > 0 && !slices.Contains(groups, group) {
+			continue
+		}
+		if devices != nil {
+			mf.Metric = slices.DeleteFunc(mf.Metric, func(m *dto.Metric) bool {
+				return !keepSeries(m, devices)
+			})
+			if len(mf.Metric) == 0 {
+				continue
+			}
+		}
+		out = append(out, mf)
+	}
+	return out
+}
+
+// keepSeries reports whether a series belongs to a selected device and, when
+// it carries pod labels, to one of the device's selected owners. Series
+// without a device name label are not device series and are kept.
+func keepSeries(m *dto.Metric, devices map[string][]selectedDevice) bool {
+	labels := make(map[string]string, len(m.GetLabel()))
+	for _, pair := range m.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	deviceName, ok := labels[name]
+	if !ok {
+		return true
+	}
+	return slices.ContainsFunc(devices[deviceName], func(device selectedDevice) bool {
+		if src, ok := labels[source]; ok && src != device.source {
+			return false
+		}
+		podName, ok := labels[pod]
+		if !ok {
+			return true
+		}
+		if len(device.owners) == 0 {
+			return podName == ""
+		}
+		return slices.ContainsFunc(device.owners, func(owner PodResourceInfo) bool {
+			return owner.Namespace == labels[namespace] && owner.Name == podName && owner.ContainerName == labels[container]
+		})
+	})
+}
+
+var filterParams = []string{"collect[]", "device", "namespace", "allocated"}
+
+func parseSnapshotFilter(query url.Values) (SnapshotFilter, error) {
+	filter := SnapshotFilter{
+		Devices:    queryValues(query, "device"),
+		Namespaces: queryValues(query, "namespace"),
+	}
+	if values := queryValues(query, "allocated"); len(values) > 0 {
+		if len(values) > 1 {
+			return SnapshotFilter{}, fmt.Errorf("allocated must be given once")
+		}
+		allocated, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return SnapshotFilter{}, fmt.Errorf("allocated must be true or false, got %q", values[0])
+		}
+		filter.Allocated = &allocated
+	}
+	return filter, nil
+}
+
+func queryValues(query url.Values, key string) []string {
+	var out []string
+	for _, value := range query[key] {
+		for v := range strings.SplitSeq(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// newTestMetricsHandler serves the device metrics of rbln0, shared by pods in
+// namespaces team-a and team-b, and of the idle rbln1, next to an exporter
+// status metric and an events counter.
+func newTestMetricsHandler(t *testing.T) http.Handler {
+	t.Helper()
+	snapshot := Snapshot{
+		Timestamp:        time.Now(),
+		NodeName:         "node-1",
+		IncludePodLabels: true,
+		Devices: []daemon.DeviceInfo{
+			{Name: "rbln0", UUID: "uuid-0", DRAMTotalGiB: 16},
+			{Name: "rbln1", UUID: "uuid-1", DRAMTotalGiB: 16},
+		},
+		PodResources: map[DeviceName][]PodResourceInfo{
+			"rbln0": {
+				{Namespace: "team-a", Name: "train", ContainerName: "main"},
+				{Namespace: "team-b", Name: "serve", ContainerName: "main"},
+			},
+		},
+	}
+	registry, err := NewSnapshotRegistry(context.Background(), snapshot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := newStatusMetrics("node-1", false)
+	status.setCollected(snapshot.Timestamp)
+	status.Register(registry)
+	events := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "rbln_device_events_total", Help: "e"}, []string{"name", "cause"})
+	events.WithLabelValues("rbln0", "tdr").Add(1)
+	events.WithLabelValues("rbln1", "tdr").Add(2)
+	registry.MustRegister(events)
+
+	snapshots := NewSnapshotStore()
+	snapshots.Publish(snapshot)
+	return NewMetricsHandler(registry, snapshots)
+}
+
+// scrape returns the series of a /metrics response as name{labels} strings.
+func scrape(t *testing.T, h http.Handler, query string) []string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics?"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics?%s = %d: %s", query, rec.Code, rec.Body)
+	}
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var series []string
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			var labels []string
+			for _, pair := range m.GetLabel() {
+				if slices.Contains([]string{"name", "namespace", "pod", "cause", "hostname"}, pair.GetName()) {
+					labels = append(labels, pair.GetName()+"="+pair.GetValue())
+				}
+			}
+			series = append(series, mf.GetName()+"{"+strings.Join(labels, ",")+"}")
+		}
+	}
+	slices.Sort(series)
+	return series
+}
+
+func TestMetricsHandlerFilters(t *testing.T) {
+	h := newTestMetricsHandler(t)
+	all := scrape(t, h, "")
+
+	tests := []struct {
+		query string
+		// contains and excludes are matched against the series strings.
+		contains []string
+		excludes []string
+	}{
+		{
+			query:    "collect[]=memory",
+			contains: []string{"RBLN_DEVICE_STATUS:DRAM_TOTAL{", "rbln_metrics_exporter_last_collection_timestamp_seconds{", "rbln_device_events_total{"},
+			excludes: []string{"RBLN_DEVICE_STATUS:TEMPERATURE", "RBLN_DEVICE_STATUS:UTILIZATION"},
+		},
+		{
+			query:    "device=uuid-1",
+			contains: []string{"name=rbln1", "rbln_device_events_total{cause=tdr,name=rbln1}", "rbln_metrics_exporter_last_collection_timestamp_seconds{"},
+			excludes: []string{"name=rbln0"},
+		},
+		{
+			query:    "allocated=false",
+			contains: []string{"name=rbln1"},
+			excludes: []string{"name=rbln0"},
+		},
+		{
+			query:    "namespace=team-a",
+			contains: []string{"pod=train", "rbln_device_events_total{cause=tdr,name=rbln0}"},
+			excludes: []string{"pod=serve", "namespace=team-b", "name=rbln1"},
+		},
+		{
+			query:    "allocated=true&collect[]=hardware,memory",
+			contains: []string{"name=rbln0", "pod=train", "pod=serve"},
+			excludes: []string{"name=rbln1", "RBLN_DEVICE_STATUS:UTILIZATION"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := scrape(t, h, tt.query)
+			for _, series := range got {
+				if !slices.Contains(all, series) {
+					t.Errorf("filtered series %s is not in the unfiltered response", series)
+				}
+			}
+			joined := strings.Join(got, "\n")
+			for _, want := range tt.contains {
+				if !strings.Contains(joined, want) {
+					t.Errorf("response has no series matching %q:\n%s", want, joined)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(joined, unwanted) {
+					t.Errorf("response has series matching %q:\n%s", unwanted, joined)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsHandlerRejectsInvalidFilters(t *testing.T) {
+	h := newTestMetricsHandler(t)
+	for _, query := range []string{"collect[]=bogus", "allocated=maybe", "allocated=true&allocated=false"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /metrics?%s = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -4,18 +4,14 @@ import (
 	"context"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
 )
 
 type DeviceHealthMetric struct {
-	healthStatus      *prometheus.GaugeVec
-	podResourceMapper *PodResourceMapper
-	NodeName          string
-	includePodLabels  bool
+	healthStatus *prometheus.GaugeVec
 }
 
-func NewDeviceHealthMetric(podResourceMapper *PodResourceMapper, nodeName string, includePodLabels bool) *DeviceHealthMetric {
-	labels := labelNames(includePodLabels)
+func NewDeviceHealthMetric(includePodLabels, includeSourceLabel bool) *DeviceHealthMetric {
+	labels := labelNames(includePodLabels, includeSourceLabel)
 	return &DeviceHealthMetric{
 		healthStatus: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -23,9 +19,6 @@ func NewDeviceHealthMetric(podResourceMapper *PodResourceMapper, nodeName string
 				Help: "NPU health status",
 			}, labels,
 		),
-		podResourceMapper: podResourceMapper,
-		NodeName:          nodeName,
-		includePodLabels:  includePodLabels,
 	}
 }
 
@@ -37,11 +30,10 @@ func (d *DeviceHealthMetric) Reset() {
 	d.healthStatus.Reset()
 }
 
-func (d *DeviceHealthMetric) UpdateMetrics(ctx context.Context, devices []daemon.DeviceInfo) {
-	podResourceInfo := d.podResourceMapper.Snapshot()
-
-	for _, device := range devices {
-		labels := buildLabels(device, d.NodeName, podResourceInfo, d.includePodLabels)
-		d.healthStatus.With(labels).Set(float64(device.DeviceStatus))
+func (d *DeviceHealthMetric) UpdateMetrics(ctx context.Context, snapshot Snapshot) {
+	for _, device := range snapshot.Devices {
+		for _, labels := range snapshot.Labels(device) {
+			d.healthStatus.With(labels).Set(float64(device.DeviceStatus))
+		}
 	}
 }
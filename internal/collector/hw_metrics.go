@@ -4,19 +4,15 @@ import (
 	"context"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
 )
 
 type HardwareInfoMetric struct {
-	temperature       *prometheus.GaugeVec
-	power             *prometheus.GaugeVec
-	podResourceMapper *PodResourceMapper
-	NodeName          string
-	includePodLabels  bool
+	temperature *prometheus.GaugeVec
+	power       *prometheus.GaugeVec
 }
 
-func NewHardwareInfoMetric(podResourceMapper *PodResourceMapper, nodeName string, includePodLabels bool) *HardwareInfoMetric {
-	labels := labelNames(includePodLabels)
+func NewHardwareInfoMetric(includePodLabels, includeSourceLabel bool) *HardwareInfoMetric {
+	labels := labelNames(includePodLabels, includeSourceLabel)
 	return &HardwareInfoMetric{
 		temperature: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -30,9 +26,6 @@ func NewHardwareInfoMetric(podResourceMapper *PodResourceMapper, nodeName string
 				Help: "Card power usage (W)",
 			}, labels,
 		),
-		podResourceMapper: podResourceMapper,
-		NodeName:          nodeName,
-		includePodLabels:  includePodLabels,
 	}
 }
 
@@ -46,12 +39,11 @@ func (h *HardwareInfoMetric) Reset() {
 	h.power.Reset()
 }
 
-func (h *HardwareInfoMetric) UpdateMetrics(ctx context.Context, devices []daemon.DeviceInfo) {
-	podResourceInfo := h.podResourceMapper.Snapshot()
-
-	for _, device := range devices {
-		labels := buildLabels(device, h.NodeName, podResourceInfo, h.includePodLabels)
-		h.temperature.With(labels).Set(device.Temperature)
-		h.power.With(labels).Set(device.Power)
+func (h *HardwareInfoMetric) UpdateMetrics(ctx context.Context, snapshot Snapshot) {
+	for _, device := range snapshot.Devices {
+		for _, labels := range snapshot.Labels(device) {
+			h.temperature.With(labels).Set(device.Temperature)
+			h.power.With(labels).Set(device.Power)
+		}
 	}
 }
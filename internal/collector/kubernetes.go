@@ -1,24 +1,30 @@
 package collector
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
-	podResourcesAPI "k8s.io/kubelet/pkg/apis/podresources/v1alpha1"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	PodResourceSocket  = "/var/lib/kubelet/pod-resources/kubelet.sock"
 	RBLNResourcePrefix = "rebellions.ai"
-	SysfsDriverPools   = "/sys/bus/pci/drivers/rebellions/%s/pools"
+	SysfsDriverDir     = "/sys/bus/pci/drivers/rebellions"
+	SysfsDriverPools   = SysfsDriverDir + "/%s/pools"
 )
 
 type DeviceName string
@@ -29,12 +35,35 @@ type PodResourceInfo struct {
 	ContainerName string
 }
 
+func comparePodResourceInfo(a, b PodResourceInfo) int {
+	return cmp.Or(
+		cmp.Compare(a.Namespace, b.Namespace),
+		cmp.Compare(a.Name, b.Name),
+		cmp.Compare(a.ContainerName, b.ContainerName),
+	)
+}
+
+// DeviceCounts are the numbers of RBLN devices of a resource name the kubelet
+// can allocate and has allocated to pods. Allocatable is -1 when the kubelet
+// only serves the v1alpha1 API.
+type DeviceCounts struct {
+	Allocatable int
+	Allocated   int
+}
+
 type PodResourceMapper struct {
 	sync.RWMutex
-	podResourcesByDevice map[DeviceName]PodResourceInfo
+	// podResourcesByDevice holds every container a device is allocated to,
+	// sorted by namespace, pod and container. Devices shared by several
+	// containers have more than one owner.
+	podResourcesByDevice map[DeviceName][]PodResourceInfo
+	deviceCounts         map[string]DeviceCounts
 	syncRequests         chan struct{}
+	syncErr              error
 
-	client podResourcesAPI.PodResourcesListerClient
+	client podResourcesClient
+	// fallback is used when the kubelet does not serve client's API.
+	fallback podResourcesClient
 }
 
 func NewPodResourceMapper(ctx context.Context) (*PodResourceMapper, error) {
@@ -43,13 +72,16 @@ func NewPodResourceMapper(ctx context.Context) (*PodResourceMapper, error) {
 		return nil, err
 	}
 
+	client, fallback := newPodResourcesClients(conn)
 	m := &PodResourceMapper{
-		podResourcesByDevice: make(map[DeviceName]PodResourceInfo),
+		podResourcesByDevice: make(map[DeviceName][]PodResourceInfo),
+		deviceCounts:         make(map[string]DeviceCounts),
 		syncRequests:         make(chan struct{}, 1),
-		client:               podResourcesAPI.NewPodResourcesListerClient(conn),
+		client:               client,
+		fallback:             fallback,
 	}
 
-	if err := m.syncPodResources(); err != nil {
+	if err := m.sync(); err != nil {
 		slog.Warn("initial pod resource sync failed", "err", err)
 	}
 
@@ -72,20 +104,42 @@ func (p *PodResourceMapper) TriggerSync() {
 	}
 }
 
-func (p *PodResourceMapper) Snapshot() map[DeviceName]PodResourceInfo {
+// Snapshot returns the owners of every allocated device as of the last sync.
+func (p *PodResourceMapper) Snapshot() map[DeviceName][]PodResourceInfo {
 	p.RLock()
 	defer p.RUnlock()
 
-	snapshot := make(map[DeviceName]PodResourceInfo, len(p.podResourcesByDevice))
-	maps.Copy(snapshot, p.podResourcesByDevice)
+	snapshot := make(map[DeviceName][]PodResourceInfo, len(p.podResourcesByDevice))
+	for deviceName, owners := range p.podResourcesByDevice {
+		snapshot[deviceName] = slices.Clone(owners)
+	}
 	return snapshot
 }
 
+// DeviceCounts returns the allocatable and allocated device counts by RBLN
+// resource name as of the last sync.
+func (p *PodResourceMapper) DeviceCounts() map[string]DeviceCounts {
+	p.RLock()
+	defer p.RUnlock()
+	return maps.Clone(p.deviceCounts)
+}
+
+// APIVersion returns the version of the pod-resources API in use, or an
+// empty string without a kubelet connection.
+func (p *PodResourceMapper) APIVersion() string {
+	p.RLock()
+	defer p.RUnlock()
+	if p.client == nil {
+		return ""
+	}
+	return p.client.Version()
+}
+
 func (p *PodResourceMapper) runSyncLoop(ctx context.Context) {
 	for {
 		select {
 		case <-p.syncRequests:
-			if err := p.syncPodResources(); err != nil {
+			if err := p.sync(); err != nil {
 				slog.Warn("Failed to sync pod resources", "err", err)
 			}
 		case <-ctx.Done():
@@ -94,30 +148,74 @@ func (p *PodResourceMapper) runSyncLoop(ctx context.Context) {
 	}
 }
 
+// SyncError returns the error of the most recent pod resource sync, or nil if it succeeded.
+func (p *PodResourceMapper) SyncError() error {
+	p.RLock()
+	defer p.RUnlock()
+	return p.syncErr
+}
+
+func (p *PodResourceMapper) sync() error {
+	err := p.syncPodResources()
+
+	p.Lock()
+	defer p.Unlock()
+	p.syncErr = err
+	return err
+}
+
 func (p *PodResourceMapper) syncPodResources() error {
-	podResourcesInfo := make(map[DeviceName]PodResourceInfo)
+	podResourcesInfo := make(map[DeviceName][]PodResourceInfo)
+	deviceCounts := make(map[string]DeviceCounts)
+	// A device shared by several containers counts as allocated once.
+	allocated := make(map[string]map[string]struct{})
 
-	podResources, err := p.getPodResources()
+	allocations, err := p.getPodResources()
 	if err != nil {
 		return err
 	}
 
-	for _, pod := range podResources.GetPodResources() {
-		for _, container := range pod.GetContainers() {
-			for _, containerDevice := range container.GetDevices() {
-				if strings.HasPrefix(containerDevice.GetResourceName(), RBLNResourcePrefix) {
-					for _, deviceID := range containerDevice.GetDeviceIds() {
-						deviceName, err := getDeviceName(deviceID)
-						if err != nil {
-							return err
-						}
-						podResourcesInfo[DeviceName(deviceName)] = PodResourceInfo{
-							Name:          pod.Name,
-							Namespace:     pod.Namespace,
-							ContainerName: container.Name,
-						}
-					}
-				}
+	for _, devices := range allocations {
+		if !strings.HasPrefix(devices.ResourceName, RBLNResourcePrefix) {
+			continue
+		}
+		if allocated[devices.ResourceName] == nil {
+			allocated[devices.ResourceName] = make(map[string]struct{})
+		}
+		for _, deviceID := range devices.DeviceIDs {
+			allocated[devices.ResourceName][deviceID] = struct{}{}
+			deviceName, err := getDeviceName(deviceID)
+			if err != nil {
+				return err
+			}
+			owner := PodResourceInfo{
+				Name:          devices.Pod,
+				Namespace:     devices.Namespace,
+				ContainerName: devices.Container,
+			}
+			if !slices.Contains(podResourcesInfo[DeviceName(deviceName)], owner) {
+				podResourcesInfo[DeviceName(deviceName)] = append(podResourcesInfo[DeviceName(deviceName)], owner)
+			}
+		}
+	}
+	for _, owners := range podResourcesInfo {
+		slices.SortFunc(owners, comparePodResourceInfo)
+	}
+	for resourceName, deviceIDs := range allocated {
+		deviceCounts[resourceName] = DeviceCounts{Allocatable: -1, Allocated: len(deviceIDs)}
+	}
+
+	allocatable, err := p.getAllocatable()
+	switch {
+	case errors.Is(err, errAllocatableUnsupported):
+	case err != nil:
+		slog.Warn("failed to get allocatable pod resources", "err", err)
+	default:
+		for resourceName, deviceIDs := range allocatable {
+			if strings.HasPrefix(resourceName, RBLNResourcePrefix) {
+				counts := deviceCounts[resourceName]
+				counts.Allocatable = len(deviceIDs)
+				deviceCounts[resourceName] = counts
 			}
 		}
 	}
@@ -125,21 +223,42 @@ func (p *PodResourceMapper) syncPodResources() error {
 	p.Lock()
 	defer p.Unlock()
 	p.podResourcesByDevice = podResourcesInfo
+	p.deviceCounts = deviceCounts
 
 	return nil
 }
 
-func (p *PodResourceMapper) getPodResources() (*podResourcesAPI.ListPodResourcesResponse, error) {
+func (p *PodResourceMapper) getPodResources() ([]containerDevices, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	resp, err := p.client.List(ctx, &podResourcesAPI.ListPodResourcesRequest{})
+	resp, err := p.client.List(ctx)
+	if status.Code(err) == codes.Unimplemented && p.fallback != nil {
+		slog.Info("kubelet does not serve the pod-resources API, falling back", "version", p.client.Version(), "fallback", p.fallback.Version())
+		p.Lock()
+		p.client, p.fallback = p.fallback, nil
+		p.Unlock()
+		resp, err = p.client.List(ctx)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pod resources; err: %w", err)
 	}
 	return resp, nil
 }
 
+func (p *PodResourceMapper) getAllocatable() (map[string][]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	allocatable, err := p.client.Allocatable(ctx)
+	if status.Code(err) == codes.Unimplemented {
+		// Kubernetes 1.21 and 1.22 serve v1 without GetAllocatableResources
+		// unless the KubeletPodResourcesGetAllocatable feature is enabled.
+		return nil, errAllocatableUnsupported
+	}
+	return allocatable, err
+}
+
 func newKubeletClient() (*grpc.ClientConn, func(), error) {
 	if _, err := os.Stat(PodResourceSocket); err != nil {
 		slog.Error("kubelet pod-resources socket unavailable", "socket", PodResourceSocket, "err", err)
@@ -157,16 +276,46 @@ func newKubeletClient() (*grpc.ClientConn, func(), error) {
 
 func getDeviceName(pciAddress string) (string, error) {
 	poolsFilePath := fmt.Sprintf(SysfsDriverPools, pciAddress)
-	poolsFile, err := os.ReadFile(poolsFilePath)
+	deviceName, err := readPoolsDeviceName(poolsFilePath)
 	if err != nil {
 		slog.Error("Failed to read", "file", poolsFilePath, "err", err)
-		return "", fmt.Errorf("failed to read %s, %w", poolsFilePath, err)
+		return "", err
 	}
-
-	deviceName := strings.Split(strings.Split(string(poolsFile), "\n")[1], " ")[0]
 	return deviceName, nil
 }
 
+// DevicePCIAddresses maps the names of the devices bound to the rebellions
+// driver to their PCI addresses.
+func DevicePCIAddresses() (map[DeviceName]string, error) {
+	paths, err := filepath.Glob(fmt.Sprintf(SysfsDriverPools, "*"))
+	if err != nil {
+		return nil, err
+	}
+	addresses := make(map[DeviceName]string, len(paths))
+	for _, path := range paths {
+		deviceName, err := readPoolsDeviceName(path)
+		if err != nil {
+			return nil, err
+		}
+		addresses[DeviceName(deviceName)] = filepath.Base(filepath.Dir(path))
+	}
+	return addresses, nil
+}
+
+// readPoolsDeviceName reads the device name from the second line of a
+// driver pools file.
+func readPoolsDeviceName(path string) (string, error) {
+	poolsFile, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s, %w", path, err)
+	}
+	lines := strings.Split(string(poolsFile), "\n")
+	if len(lines) < 2 {
+		return "", fmt.Errorf("unexpected format of %s", path)
+	}
+	return strings.Split(lines[1], " ")[0], nil
+}
+
 func IsKubernetes() bool {
 	if s := os.Getenv("KUBERNETES_SERVICE_HOST"); s != "" {
 		return true
@@ -179,6 +328,7 @@ func IsKubernetes() bool {
 
 func NewNoopPodResourceMapper() *PodResourceMapper {
 	return &PodResourceMapper{
-		podResourcesByDevice: make(map[DeviceName]PodResourceInfo),
+		podResourcesByDevice: make(map[DeviceName][]PodResourceInfo),
+		deviceCounts:         make(map[string]DeviceCounts),
 	}
 }
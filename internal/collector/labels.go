@@ -1,6 +1,8 @@
 package collector
 
 import (
+	"cmp"
+	"maps"
 	"slices"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -18,6 +20,7 @@ const (
 	container       = "container"
 	driverVersion   = "driver_version"
 	firmwareVersion = "firmware_version"
+	source          = "source"
 )
 
 var baseLabels = []string{
@@ -32,30 +35,48 @@ var baseLabels = []string{
 
 var commonLabels = append(slices.Clone(baseLabels), namespace, pod, container)
 
-func labelNames(includePodLabels bool) []string {
+func labelNames(includePodLabels, includeSourceLabel bool) []string {
+	labels := baseLabels
 	if includePodLabels {
-		return commonLabels
+		labels = commonLabels
 	}
-	return baseLabels
+	if includeSourceLabel {
+		labels = append(slices.Clone(labels), source)
+	}
+	return labels
 }
 
-func buildLabels(device daemon.DeviceInfo, nodeName string, podResourceInfo map[DeviceName]PodResourceInfo, includePodLabels bool) prometheus.Labels {
+// buildLabels returns the label sets of the device's series. With pod labels
+// there is one label set per owner, and a single one with empty pod labels
+// when the device is not allocated.
+func buildLabels(device daemon.DeviceInfo, nodeName string, owners []PodResourceInfo, includePodLabels, includeSourceLabel bool) []prometheus.Labels {
 	labels := prometheus.Labels{
 		card:            device.Card,
 		uuid:            device.UUID,
 		name:            device.Name,
 		deviceID:        device.DeviceID,
-		hostname:        nodeName,
+		hostname:        cmp.Or(device.Hostname, nodeName),
 		driverVersion:   device.DriverVersion,
 		firmwareVersion: device.FirmwareVersion,
 	}
 
-	if includePodLabels {
-		info := podResourceInfo[DeviceName(device.Name)]
-		labels[namespace] = info.Namespace
-		labels[pod] = info.Name
-		labels[container] = info.ContainerName
+	if includeSourceLabel {
+		labels[source] = device.Source
 	}
 
-	return labels
+	if !includePodLabels {
+		return []prometheus.Labels{labels}
+	}
+	if len(owners) == 0 {
+		owners = []PodResourceInfo{{}}
+	}
+	sets := make([]prometheus.Labels, 0, len(owners))
+	for _, owner := range owners {
+		set := maps.Clone(labels)
+		set[namespace] = owner.Namespace
+		set[pod] = owner.Name
+		set[container] = owner.ContainerName
+		sets = append(sets, set)
+	}
+	return sets
 }
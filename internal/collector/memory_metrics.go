@@ -5,21 +5,24 @@ import (
 	"math"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
 )
 
-const gibToBytes = 1 << 30
+// GiBToBytes is the number of bytes in a GiB, the unit the daemon reports
+// DRAM sizes in.
+const GiBToBytes = 1 << 30
+
+// BytesFromGiB converts a DRAM size reported by the daemon to bytes.
+func BytesFromGiB(gib float64) uint64 {
+	return uint64(math.Round(gib * GiBToBytes))
+}
 
 type MemoryMetric struct {
-	dramUsed          *prometheus.GaugeVec
-	dramTotal         *prometheus.GaugeVec
-	podResourceMapper *PodResourceMapper
-	nodeName          string
-	includePodLabels  bool
+	dramUsed  *prometheus.GaugeVec
+	dramTotal *prometheus.GaugeVec
 }
 
-func NewMemoryMetric(podResourceMapper *PodResourceMapper, nodeName string, includePodLabels bool) *MemoryMetric {
-	labels := labelNames(includePodLabels)
+func NewMemoryMetric(includePodLabels, includeSourceLabel bool) *MemoryMetric {
+	labels := labelNames(includePodLabels, includeSourceLabel)
 	return &MemoryMetric{
 		dramUsed: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -33,9 +36,6 @@ func NewMemoryMetric(podResourceMapper *PodResourceMapper, nodeName string, incl
 				Help: "DRAM total (bytes)",
 			}, labels,
 		),
-		podResourceMapper: podResourceMapper,
-		nodeName:          nodeName,
-		includePodLabels:  includePodLabels,
 	}
 }
 
@@ -49,16 +49,14 @@ func (m *MemoryMetric) Reset() {
 	m.dramTotal.Reset()
 }
 
-func (m *MemoryMetric) UpdateMetrics(ctx context.Context, devices []daemon.DeviceInfo) {
-	podResourceInfo := m.podResourceMapper.Snapshot()
-
-	for _, device := range devices {
-		labels := buildLabels(device, m.nodeName, podResourceInfo, m.includePodLabels)
-
-		bytesUsed := uint64(math.Round(device.DRAMUsedGiB * float64(gibToBytes)))
-		bytesTotal := uint64(math.Round(device.DRAMTotalGiB * float64(gibToBytes)))
+func (m *MemoryMetric) UpdateMetrics(ctx context.Context, snapshot Snapshot) {
+	for _, device := range snapshot.Devices {
+		bytesUsed := BytesFromGiB(device.DRAMUsedGiB)
+		bytesTotal := BytesFromGiB(device.DRAMTotalGiB)
 
-		m.dramUsed.With(labels).Set(float64(bytesUsed))
-		m.dramTotal.With(labels).Set(float64(bytesTotal))
+		for _, labels := range snapshot.Labels(device) {
+			m.dramUsed.With(labels).Set(float64(bytesUsed))
+			m.dramTotal.With(labels).Set(float64(bytesTotal))
+		}
 	}
 }
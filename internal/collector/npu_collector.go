@@ -2,32 +2,76 @@ package collector
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
 )
 
+const (
+	minSourceBackoff = 5 * time.Second
+	maxSourceBackoff = 5 * time.Minute
+)
+
+// Source is a daemon endpoint polled by the NPUCollector. Name is empty when
+// the exporter talks to a single daemon; otherwise it becomes the source label
+// and Hostname overrides the hostname label of the source's devices.
+type Source struct {
+	Name     string
+	Hostname string
+	Client   *daemon.Client
+}
+
+// sourceState tracks the backoff of a failing source, so that an unreachable
+// endpoint is not retried on every cycle.
+type sourceState struct {
+	Source
+	failures int
+	retryAt  time.Time
+}
+
 type NPUCollector struct {
-	metrics           []Metric
-	dClient           *daemon.Client
+	metrics           map[string]Metric
+	status            *statusMetrics
+	mu                sync.RWMutex
+	groups            []string
+	sources           []*sourceState
+	multiSource       bool
 	isKubernetes      bool
 	podResourceMapper *PodResourceMapper
+	snapshots         *SnapshotStore
 	NodeName          string
 }
 
-func NewNPUCollector(dClient *daemon.Client, registry prometheus.Registerer, isKubernetes bool, podResourceMapper *PodResourceMapper, nodeName string) *NPUCollector {
-	metrics := []Metric{
-		NewHardwareInfoMetric(podResourceMapper, nodeName, isKubernetes),
-		NewDeviceHealthMetric(podResourceMapper, nodeName, isKubernetes),
-		NewMemoryMetric(podResourceMapper, nodeName, isKubernetes),
-		NewUtilizationMetric(podResourceMapper, nodeName, isKubernetes),
+func NewNPUCollector(sources []Source, registry prometheus.Registerer, isKubernetes bool, podResourceMapper *PodResourceMapper, snapshots *SnapshotStore, nodeName string) *NPUCollector {
+	multiSource := len(sources) > 1 || (len(sources) == 1 && sources[0].Name != "")
+	metrics := make(map[string]Metric, len(metricConstructors))
+	for name, newMetric := range metricConstructors {
+		metrics[name] = newMetric(isKubernetes, multiSource)
+	}
+
+	status := newStatusMetrics(nodeName, multiSource)
+	status.setCollected(time.Now())
+	states := make([]*sourceState, 0, len(sources))
+	for _, source := range sources {
+		states = append(states, &sourceState{Source: source})
+		status.setDaemonUp(source, false)
 	}
 
 	return &NPUCollector{
 		metrics:           metrics,
-		dClient:           dClient,
+		status:            status,
+		groups:            MetricGroups(),
+		sources:           states,
+		multiSource:       multiSource,
 		isKubernetes:      isKubernetes,
 		podResourceMapper: podResourceMapper,
+		snapshots:         snapshots,
 		NodeName:          nodeName,
 	}
 }
@@ -36,18 +80,101 @@ func (n *NPUCollector) Register(registerer prometheus.Registerer) {
 	for _, metric := range n.metrics {
 		metric.Register(registerer)
 	}
+	n.status.Register(registerer)
+}
+
+// SetGroups selects the metric groups updated from the next collection on.
+// The series of disabled groups are removed.
+func (n *NPUCollector) SetGroups(groups []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.groups = slices.Clone(groups)
 }
 
 func (n *NPUCollector) GetMetrics(ctx context.Context) error {
-	devices, err := n.dClient.GetDeviceInfo(ctx)
+	devices, err := n.collectDevices(ctx)
 	if err != nil {
 		return err
 	}
 
-	for _, metric := range n.metrics {
+	snapshot := Snapshot{
+		Timestamp:          time.Now(),
+		NodeName:           n.NodeName,
+		IncludePodLabels:   n.isKubernetes,
+		IncludeSourceLabel: n.multiSource,
+		Devices:            devices,
+		PodResources:       n.podResourceMapper.Snapshot(),
+	}
+
+	n.mu.RLock()
+	for name, metric := range n.metrics {
 		metric.Reset()
-		metric.UpdateMetrics(ctx, devices)
+		if slices.Contains(n.groups, name) {
+			metric.UpdateMetrics(ctx, snapshot)
+		}
 	}
+	n.mu.RUnlock()
 
+	n.status.setCollected(snapshot.Timestamp)
+	n.snapshots.Publish(snapshot)
 	return nil
 }
+
+// collectDevices polls all sources concurrently. It only fails when no source
+// could be read; devices of failing sources are left out of the snapshot.
+func (n *NPUCollector) collectDevices(ctx context.Context) ([]daemon.DeviceInfo, error) {
+	if !n.multiSource {
+		devices, err := n.sources[0].Client.GetDeviceInfo(ctx)
+		n.status.setDaemonUp(n.sources[0].Source, err == nil)
+		return devices, err
+	}
+
+	results := make([][]daemon.DeviceInfo, len(n.sources))
+	errs := make([]error, len(n.sources))
+	var wg sync.WaitGroup
+	now := time.Now()
+	for i, state := range n.sources {
+		if now.Before(state.retryAt) {
+			errs[i] = fmt.Errorf("source %s: backing off until %s", state.Name, state.retryAt.Format(time.TimeOnly))
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = n.collectSource(ctx, state)
+		}()
+	}
+	wg.Wait()
+
+	var devices []daemon.DeviceInfo
+	for _, result := range results {
+		devices = append(devices, result...)
+	}
+	if err := errors.Join(errs...); err != nil && len(devices) == 0 {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (n *NPUCollector) collectSource(ctx context.Context, state *sourceState) ([]daemon.DeviceInfo, error) {
+	devices, err := state.Client.GetDeviceInfo(ctx)
+	n.status.setDaemonUp(state.Source, err == nil)
+	if err != nil {
+		state.failures++
+		backoff := min(minSourceBackoff<<min(state.failures-1, 10), maxSourceBackoff)
+		state.retryAt = time.Now().Add(backoff)
+		slog.Warn("failed to collect from rbln-daemon source", "source", state.Name, "backoff", backoff, "err", err)
+		return nil, fmt.Errorf("source %s: %w", state.Name, err)
+	}
+	if state.failures > 0 {
+		slog.Info("rbln-daemon source recovered", "source", state.Name)
+	}
+	state.failures = 0
+	state.retryAt = time.Time{}
+
+	for i := range devices {
+		devices[i].Source = state.Name
+		devices[i].Hostname = state.Hostname
+	}
+	return devices, nil
+}
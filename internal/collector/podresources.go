@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	podResourcesV1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+	podResourcesV1alpha1 "k8s.io/kubelet/pkg/apis/podresources/v1alpha1"
+)
+
+const (
+	PodResourcesV1       = "v1"
+	PodResourcesV1alpha1 = "v1alpha1"
+)
+
+// errAllocatableUnsupported is returned by pod-resources APIs without
+// GetAllocatableResources.
+var errAllocatableUnsupported = errors.New("allocatable resources are not served by the pod-resources API")
+
+// containerDevices are the devices of one resource allocated to a container.
+type containerDevices struct {
+	Pod          string
+	Namespace    string
+	Container    string
+	ResourceName string
+	DeviceIDs    []string
+}
+
+// podResourcesClient is the part of the kubelet pod-resources API used by
+// the mapper, served by the v1 API or by v1alpha1 on kubelets older than
+// Kubernetes 1.20.
+type podResourcesClient interface {
+	Version() string
+	List(ctx context.Context) ([]containerDevices, error)
+	// Allocatable returns the IDs of the devices the kubelet can allocate by
+	// resource name.
+	Allocatable(ctx context.Context) (map[string][]string, error)
+}
+
+type podResourcesV1Client struct {
+	client podResourcesV1.PodResourcesListerClient
+}
+
+func (c podResourcesV1Client) Version() string {
+	return PodResourcesV1
+}
+
+func (c podResourcesV1Client) List(ctx context.Context) ([]containerDevices, error) {
+	resp, err := c.client.List(ctx, &podResourcesV1.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	var out []containerDevices
+	for _, pod := range resp.GetPodResources() {
+		for _, container := range pod.GetContainers() {
+			for _, devices := range container.GetDevices() {
+				out = append(out, containerDevices{
+					Pod:          pod.GetName(),
+					Namespace:    pod.GetNamespace(),
+					Container:    container.GetName(),
+					ResourceName: devices.GetResourceName(),
+					DeviceIDs:    devices.GetDeviceIds(),
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (c podResourcesV1Client) Allocatable(ctx context.Context) (map[string][]string, error) {
+	resp, err := c.client.GetAllocatableResources(ctx, &podResourcesV1.AllocatableResourcesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]string)
+	for _, devices := range resp.GetDevices() {
+		out[devices.GetResourceName()] = append(out[devices.GetResourceName()], devices.GetDeviceIds()...)
+	}
+	return out, nil
+}
+
+type podResourcesV1alpha1Client struct {
+	client podResourcesV1alpha1.PodResourcesListerClient
+}
+
+func (c podResourcesV1alpha1Client) Version() string {
+	return PodResourcesV1alpha1
+}
+
+func (c podResourcesV1alpha1Client) List(ctx context.Context) ([]containerDevices, error) {
+	resp, err := c.client.List(ctx, &podResourcesV1alpha1.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	var out []containerDevices
+	for _, pod := range resp.GetPodResources() {
+		for _, container := range pod.GetContainers() {
+			for _, devices := range container.GetDevices() {
+				out = append(out, containerDevices{
+					Pod:          pod.GetName(),
+					Namespace:    pod.GetNamespace(),
+					Container:    container.GetName(),
+					ResourceName: devices.GetResourceName(),
+					DeviceIDs:    devices.GetDeviceIds(),
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (c podResourcesV1alpha1Client) Allocatable(context.Context) (map[string][]string, error) {
+	return nil, errAllocatableUnsupported
+}
+
+func newPodResourcesClients(conn grpc.ClientConnInterface) (v1, v1alpha1 podResourcesClient) {
+	return podResourcesV1Client{client: podResourcesV1.NewPodResourcesListerClient(conn)},
+		podResourcesV1alpha1Client{client: podResourcesV1alpha1.NewPodResourcesListerClient(conn)}
+}
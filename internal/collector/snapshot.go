@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// Snapshot is the result of one collection cycle. The Prometheus metrics and
+// every other consumer (push sinks, APIs) are driven from the same snapshot.
+type Snapshot struct {
+	Timestamp        time.Time
+	NodeName         string
+	IncludePodLabels bool
+	// IncludeSourceLabel is set when devices are read from several daemon
+	// endpoints and their series carry a source label.
+	IncludeSourceLabel bool
+	Devices            []daemon.DeviceInfo
+	// PodResources are the containers each allocated device is assigned to.
+	PodResources map[DeviceName][]PodResourceInfo
+}
+
+// Owners returns the containers the device is allocated to. The kubelet only
+// knows the local devices, and device names of different daemon endpoints
+// collide, so devices of endpoints whose hostname is not the node never have
+// owners.
+func (s Snapshot) Owners(device daemon.DeviceInfo) []PodResourceInfo {
+	if device.Hostname != "" && device.Hostname != s.NodeName {
+		return nil
+	}
+	return s.PodResources[DeviceName(device.Name)]
+}
+
+// Labels returns the label sets used for the device's Prometheus series. A
+// device shared by several containers has one series per container.
+func (s Snapshot) Labels(device daemon.DeviceInfo) []prometheus.Labels {
+	return buildLabels(device, s.NodeName, s.Owners(device), s.IncludePodLabels, s.IncludeSourceLabel)
+}
+
+type SnapshotStore struct {
+	mu          sync.RWMutex
+	latest      *Snapshot
+	subscribers map[chan Snapshot]struct{}
+	created     time.Time
+}
+
+func NewSnapshotStore() *SnapshotStore {
+	return &SnapshotStore{
+		subscribers: make(map[chan Snapshot]struct{}),
+		created:     time.Now(),
+	}
+}
+
+// CheckFreshness reports an error when the latest snapshot, or the first one
+// after startup, is older than maxAge.
+func (s *SnapshotStore) CheckFreshness(maxAge time.Duration) error {
+	latest, ok := s.Latest()
+	if !ok {
+		if age := time.Since(s.created); age > maxAge {
+			return fmt.Errorf("no successful collection since startup %s ago", age.Round(time.Second))
+		}
+		return nil
+	}
+	if age := time.Since(latest.Timestamp); age > maxAge {
+		return fmt.Errorf("last successful collection was %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+func (s *SnapshotStore) Latest() (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.latest == nil {
+		return Snapshot{}, false
+	}
+	return *s.latest, true
+}
+
+// Publish stores the snapshot and hands it to every subscriber. A subscriber
+// that has not consumed the previous snapshot gets it replaced by the new one,
+// so slow consumers never block collection.
+func (s *SnapshotStore) Publish(snapshot Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latest = &snapshot
+	for ch := range s.subscribers {
+		select {
+		case ch <- snapshot:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- snapshot:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel that receives every published snapshot and a
+// function that cancels the subscription.
+func (s *SnapshotStore) Subscribe() (<-chan Snapshot, func()) {
+	ch := make(chan Snapshot, 1)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subscribers, ch)
+	}
+}
@@ -0,0 +1,33 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+func TestSnapshotOwners(t *testing.T) {
+	snapshot := Snapshot{
+		NodeName: "node-1",
+		PodResources: map[DeviceName][]PodResourceInfo{
+			"rbln0": {{Namespace: "team-a", Name: "train", ContainerName: "main"}},
+		},
+	}
+	tests := []struct {
+		name   string
+		device daemon.DeviceInfo
+		want   bool
+	}{
+		{name: "single daemon", device: daemon.DeviceInfo{Name: "rbln0"}, want: true},
+		{name: "endpoint on the node", device: daemon.DeviceInfo{Name: "rbln0", Source: "local", Hostname: "node-1"}, want: true},
+		{name: "endpoint on another host", device: daemon.DeviceInfo{Name: "rbln0", Source: "tray1", Hostname: "tray1"}},
+		{name: "unallocated device", device: daemon.DeviceInfo{Name: "rbln1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(snapshot.Owners(tt.device)) > 0; got != tt.want {
+				t.Errorf("has owners = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"cmp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DaemonUpMetric       = "rbln_metrics_exporter_daemon_up"
+	LastCollectionMetric = "rbln_metrics_exporter_last_collection_timestamp_seconds"
+)
+
+// statusMetrics report whether the daemons could be read, so that alerts can
+// tell a daemon that is down or stale data apart from missing devices. They
+// are not a metric group and are always collected.
+type statusMetrics struct {
+	daemonUp       *prometheus.GaugeVec
+	lastCollection *prometheus.GaugeVec
+	nodeName       string
+	multiSource    bool
+}
+
+func newStatusMetrics(nodeName string, multiSource bool) *statusMetrics {
+	labels := []string{hostname}
+	if multiSource {
+		labels = append(labels, source)
+	}
+	return &statusMetrics{
+		daemonUp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: DaemonUpMetric,
+				Help: "Whether the last collection from the rbln-daemon succeeded",
+			}, labels,
+		),
+		lastCollection: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: LastCollectionMetric,
+				Help: "Unix time of the last successful collection, or of the exporter start before the first one (s)",
+			}, []string{hostname},
+		),
+		nodeName:    nodeName,
+		multiSource: multiSource,
+	}
+}
+
+func (s *statusMetrics) Register(registerer prometheus.Registerer) {
+	registerer.MustRegister(s.daemonUp)
+	registerer.MustRegister(s.lastCollection)
+}
+
+func (s *statusMetrics) setDaemonUp(src Source, up bool) {
+	labels := prometheus.Labels{hostname: cmp.Or(src.Hostname, s.nodeName)}
+	if s.multiSource {
+		labels[source] = src.Name
+	}
+	value := 0.0
+	if up {
+		value = 1
+	}
+	s.daemonUp.With(labels).Set(value)
+}
+
+func (s *statusMetrics) setCollected(t time.Time) {
+	s.lastCollection.WithLabelValues(s.nodeName).Set(float64(t.Unix()))
+}
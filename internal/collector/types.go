@@ -4,16 +4,17 @@ import (
 	"context"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
 )
 
 type Collector interface {
 	Register(prometheus.Registerer)
 	GetMetrics(context.Context) error
+	// SetGroups selects the metric groups to collect, see MetricGroups.
+	SetGroups(groups []string)
 }
 
 type Metric interface {
 	Register(prometheus.Registerer)
-	UpdateMetrics(context.Context, []daemon.DeviceInfo)
+	UpdateMetrics(context.Context, Snapshot)
 	Reset()
 }
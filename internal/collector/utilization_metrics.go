@@ -4,18 +4,14 @@ import (
 	"context"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
 )
 
 type UtilizationMetric struct {
-	utilization       *prometheus.GaugeVec
-	podResourceMapper *PodResourceMapper
-	nodeName          string
-	includePodLabels  bool
+	utilization *prometheus.GaugeVec
 }
 
-func NewUtilizationMetric(podResourceMapper *PodResourceMapper, nodeName string, includePodLabels bool) *UtilizationMetric {
-	labels := labelNames(includePodLabels)
+func NewUtilizationMetric(includePodLabels, includeSourceLabel bool) *UtilizationMetric {
+	labels := labelNames(includePodLabels, includeSourceLabel)
 	return &UtilizationMetric{
 		utilization: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -23,9 +19,6 @@ func NewUtilizationMetric(podResourceMapper *PodResourceMapper, nodeName string,
 				Help: "Utilization (%)",
 			}, labels,
 		),
-		podResourceMapper: podResourceMapper,
-		nodeName:          nodeName,
-		includePodLabels:  includePodLabels,
 	}
 }
 
@@ -37,11 +30,10 @@ func (u *UtilizationMetric) Reset() {
 	u.utilization.Reset()
 }
 
-func (u *UtilizationMetric) UpdateMetrics(ctx context.Context, devices []daemon.DeviceInfo) {
-	podResourceInfo := u.podResourceMapper.Snapshot()
-
-	for _, device := range devices {
-		labels := buildLabels(device, u.nodeName, podResourceInfo, u.includePodLabels)
-		u.utilization.With(labels).Set(device.Utilization)
+func (u *UtilizationMetric) UpdateMetrics(ctx context.Context, snapshot Snapshot) {
+	for _, device := range snapshot.Devices {
+		for _, labels := range snapshot.Labels(device) {
+			u.utilization.With(labels).Set(device.Utilization)
+		}
 	}
 }
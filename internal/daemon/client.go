@@ -1,14 +1,18 @@
 package daemon
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/rebellions-sw/rbln-metrics-exporter/pkg/rblnservicespb"
@@ -64,10 +68,37 @@ func NewClient(ctx context.Context, endpoint string) (*Client, error) {
 	}, nil
 }
 
+// NewLazyClient creates a client without waiting for the connection, so that
+// an unreachable daemon does not block startup. The connection is established
+// and re-established with backoff by gRPC on demand.
+func NewLazyClient(endpoint string) (*Client, error) {
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rbln-daemon client %s: %w", endpoint, err)
+	}
+
+	return &Client{
+		conn:   conn,
+		client: rblnservicespb.NewRBLNServicesClient(conn),
+	}, nil
+}
+
 func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
+// CheckConnection reports an error unless the gRPC connection to the daemon
+// is usable. A broken connection is asked to reconnect.
+func (c *Client) CheckConnection() error {
+	switch state := c.conn.GetState(); state {
+	case connectivity.Ready, connectivity.Idle:
+		return nil
+	default:
+		c.conn.Connect()
+		return fmt.Errorf("rbln-daemon connection is %s", state)
+	}
+}
+
 type DeviceInfo struct {
 	UUID            string
 	Name            string
@@ -81,6 +112,10 @@ type DeviceInfo struct {
 	DriverVersion   string
 	FirmwareVersion string
 	DeviceStatus    int
+	// Source and Hostname identify the daemon endpoint the device was read
+	// from. They are set by the collector when several endpoints are polled.
+	Source   string
+	Hostname string
 }
 
 func (c *Client) GetDeviceInfo(ctx context.Context) ([]DeviceInfo, error) {
@@ -128,6 +163,11 @@ func (c *Client) GetDeviceInfo(ctx context.Context) ([]DeviceInfo, error) {
 		merged = append(merged, di)
 	}
 
+	// Order rbln2 before rbln10 so that every consumer sees a stable device order.
+	slices.SortFunc(merged, func(a, b DeviceInfo) int {
+		return cmp.Or(cmp.Compare(len(a.Name), len(b.Name)), strings.Compare(a.Name, b.Name))
+	})
+
 	return merged, nil
 }
 
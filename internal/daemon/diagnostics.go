@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/pkg/rblnservicespb"
+)
+
+// Call is the raw outcome of one RPC made by CallAll.
+type Call struct {
+	Method string
+	// Device is set for per-device RPCs.
+	Device    string
+	Duration  time.Duration
+	Responses []proto.Message
+	Err       error
+}
+
+// CallAll calls every read-only RPC of the daemon for diagnostics; the
+// per-device RPCs are called for each serviceable device. The reset RPCs and
+// the never-ending event stream are not called.
+func (c *Client) CallAll(ctx context.Context) []Call {
+	var calls []Call
+	call := func(method, device string, fn func() ([]proto.Message, error)) {
+		start := time.Now()
+		responses, err := fn()
+		calls = append(calls, Call{
+			Method:    method,
+			Device:    device,
+			Duration:  time.Since(start),
+			Responses: responses,
+			Err:       err,
+		})
+	}
+
+	call("GetDeviceList", "", func() ([]proto.Message, error) {
+		stream, err := c.client.GetDeviceList(ctx, &rblnservicespb.Empty{})
+		return recvAll(stream, err)
+	})
+	var devices []*rblnservicespb.Device
+	call("GetServiceableDeviceList", "", func() ([]proto.Message, error) {
+		stream, err := c.client.GetServiceableDeviceList(ctx, &rblnservicespb.Empty{})
+		responses, err := recvAll(stream, err)
+		for _, r := range responses {
+			devices = append(devices, r.(*rblnservicespb.Device))
+		}
+		return responses, err
+	})
+	call("GetTotalInfo", "", func() ([]proto.Message, error) {
+		stream, err := c.client.GetTotalInfo(ctx, &rblnservicespb.Empty{})
+		return recvAll(stream, err)
+	})
+
+	for _, device := range devices {
+		name := device.GetName()
+		call("GetVersion", name, func() ([]proto.Message, error) {
+			return single(c.client.GetVersion(ctx, device))
+		})
+		call("GetHWInfo", name, func() ([]proto.Message, error) {
+			return single(c.client.GetHWInfo(ctx, device))
+		})
+		call("GetMemoryInfo", name, func() ([]proto.Message, error) {
+			return single(c.client.GetMemoryInfo(ctx, device))
+		})
+		call("GetClockInfo", name, func() ([]proto.Message, error) {
+			return single(c.client.GetClockInfo(ctx, device))
+		})
+		call("GetUtilization", name, func() ([]proto.Message, error) {
+			return single(c.client.GetUtilization(ctx, device))
+		})
+	}
+	return calls
+}
+
+func recvAll[T any, PT interface {
+	*T
+	proto.Message
+}](stream grpc.ServerStreamingClient[T], err error) ([]proto.Message, error) {
+	if err != nil {
+		return nil, err
+	}
+	var responses []proto.Message
+	for {
+		r, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return responses, nil
+		}
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, PT(r))
+	}
+}
+
+func single[T proto.Message](r T, err error) ([]proto.Message, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []proto.Message{r}, nil
+}
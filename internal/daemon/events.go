@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/pkg/rblnservicespb"
+)
+
+// Event is a device event reported by the daemon, such as a hard reset or a
+// timeout detection and recovery (TDR).
+type Event struct {
+	Device string
+	// Source is the name of the daemon endpoint in multi-endpoint mode.
+	Source string
+	// Type tells whether the kernel expects the daemon to respond to the event.
+	Type       string
+	Cause      string
+	SubValue   int32
+	KernelTime float64
+	UTCTime    string
+}
+
+var eventCauseNames = map[rblnservicespb.EventSource]string{
+	rblnservicespb.EventSource_SIGNLE_HARD_RESET: "single_hard_reset",
+	rblnservicespb.EventSource_RSD_HARD_RESET:    "rsd_hard_reset",
+	rblnservicespb.EventSource_TDR_EVENT:         "tdr",
+	rblnservicespb.EventSource_CP_EVENT:          "cp",
+}
+
+var eventTypeNames = map[rblnservicespb.EventType]string{
+	rblnservicespb.EventType_NO_RESPONSE:       "no_response",
+	rblnservicespb.EventType_RESPONSE_REQUIRED: "response_required",
+}
+
+// EventCauses returns the names of the event causes the daemon reports.
+func EventCauses() []string {
+	return slices.Sorted(maps.Values(eventCauseNames))
+}
+
+// EventTypes returns the names of the event types the daemon reports.
+func EventTypes() []string {
+	return slices.Sorted(maps.Values(eventTypeNames))
+}
+
+// WatchEvents streams the events of a single device and calls fn for each of
+// them. It returns when the stream ends or ctx is canceled.
+func (c *Client) WatchEvents(ctx context.Context, device string, fn func(Event)) error {
+	stream, err := c.client.GetEventInfo(ctx, &rblnservicespb.Device{Name: device})
+	if err != nil {
+		return fmt.Errorf("failed to GetEventInfo RPC: %w", err)
+	}
+
+	for {
+		info, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to receive event: %w", err)
+		}
+		fn(newEvent(info))
+	}
+}
+
+func newEvent(info *rblnservicespb.EventInfo) Event {
+	cause, ok := eventCauseNames[info.GetValue()]
+	if !ok {
+		cause = strconv.Itoa(int(info.GetValue()))
+	}
+	typ, ok := eventTypeNames[info.GetEventType()]
+	if !ok {
+		typ = strconv.Itoa(int(info.GetEventType()))
+	}
+	return Event{
+		Device:     info.GetDevName(),
+		Type:       typ,
+		Cause:      cause,
+		SubValue:   info.GetSubValue(),
+		KernelTime: info.GetKernelTime(),
+		UTCTime:    info.GetUtcTime(),
+	}
+}
@@ -0,0 +1,294 @@
+// Package dashboards generates Grafana dashboards from the exporter's metric
+// catalog, so that they only use metrics and labels the exporter exposes
+// with the configured collectors and label options.
+package dashboards
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+)
+
+const (
+	tag             = "rbln"
+	metricHealth    = "RBLN_DEVICE_STATUS:HEALTH"
+	metricDRAMUsed  = "RBLN_DEVICE_STATUS:DRAM_USED"
+	metricDRAMTotal = "RBLN_DEVICE_STATUS:DRAM_TOTAL"
+	metricPower     = "RBLN_DEVICE_STATUS:CARD_POWER"
+	metricUtil      = "RBLN_DEVICE_STATUS:UTILIZATION"
+
+	labelHostname  = "hostname"
+	labelName      = "name"
+	labelSource    = "source"
+	labelNamespace = "namespace"
+	labelPod       = "pod"
+	labelContainer = "container"
+)
+
+var grafanaUnits = map[string]string{
+	collector.UnitCelsius: "celsius",
+	collector.UnitWatts:   "watt",
+	collector.UnitBytes:   "bytes",
+	collector.UnitPercent: "percent",
+	collector.UnitSeconds: "s",
+	collector.UnitNone:    "none",
+}
+
+// catalog is the metric catalog with lookups used by the dashboards.
+type catalog struct {
+	metrics []collector.MetricDescription
+	// ref is the metric used for device counts and label values; every
+	// device metric carries the same labels.
+	ref collector.MetricDescription
+}
+
+func (c catalog) get(name string) (collector.MetricDescription, bool) {
+	i := slices.IndexFunc(c.metrics, func(m collector.MetricDescription) bool { return m.Name == name })
+	if i < 0 {
+		return collector.MetricDescription{}, false
+	}
+	return c.metrics[i], true
+}
+
+func (c catalog) hasLabel(label string) bool {
+	return slices.Contains(c.ref.Labels, label)
+}
+
+// series returns the expression of the metric with one series per device, or
+// per device and the kept pod labels. A device shared by several containers
+// has a series per container that only differ in their pod labels.
+func (c catalog) series(name string, sel selector, keep ...string) string {
+	if !c.hasLabel(labelPod) {
+		return name + sel.String()
+	}
+	var drop []string
+	for _, label := range []string{labelNamespace, labelPod, labelContainer} {
+		if !slices.Contains(keep, label) {
+			drop = append(drop, label)
+		}
+	}
+	return fmt.Sprintf("max without (%s) (%s%s)", strings.Join(drop, ", "), name, sel)
+}
+
+// Generate returns the node, device, workload and fleet dashboards for the
+// metrics of the catalog. The workload dashboard is only generated when the
+// metrics carry pod labels.
+func Generate(metrics []collector.MetricDescription) ([]Dashboard, error) {
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no device metrics to generate dashboards for")
+	}
+	c := catalog{metrics: metrics, ref: metrics[0]}
+
+	dashboards := []Dashboard{nodeDashboard(c), deviceDashboard(c)}
+	if c.hasLabel(labelPod) {
+		dashboards = append(dashboards, workloadDashboard(c))
+	}
+	return append(dashboards, fleetDashboard(c)), nil
+}
+
+func newDashboard(uid, title, description string, variables []variable, panels []panel) Dashboard {
+	return Dashboard{
+		UID:           uid,
+		Title:         title,
+		Description:   description,
+		Tags:          []string{tag},
+		Timezone:      "browser",
+		Editable:      true,
+		Refresh:       "30s",
+		SchemaVersion: schemaVersion,
+		Time:          timeRange{From: "now-6h", To: "now"},
+		Templating:    templating{List: variables},
+		Links: []link{{
+			Title:       "RBLN dashboards",
+			Type:        "dashboards",
+			Tags:        []string{tag},
+			AsDropdown:  true,
+			IncludeVars: true,
+			KeepTime:    true,
+		}},
+		Panels: panels,
+	}
+}
+
+func nodeDashboard(c catalog) Dashboard {
+	sel := selector{labelHostname + `="$hostname"`}
+	legend := "{{name}}"
+	vars := []variable{datasourceVariable(), labelVariable(labelHostname, "Node", c.ref.Name, "", false)}
+	if c.hasLabel(labelSource) {
+		sel = append(sel, labelSource+`=~"$source"`)
+		legend = "{{source}}/{{name}}"
+		vars = append(vars, labelVariable(labelSource, "Daemon", c.ref.Name, `hostname="$hostname"`, true))
+	}
+
+	var b layout
+	b.add(statPanel("Devices", fmt.Sprintf("count(%s)", c.series(c.ref.Name, sel)), collector.UnitNone), 4, 4)
+	summaryStats(&b, c, sel)
+	b.newline()
+	for _, m := range c.metrics {
+		if m.Name == metricHealth {
+			b.add(healthTimeline(m, c.series(m.Name, sel), legend), 24, 6)
+			continue
+		}
+		b.add(timeseriesPanel(title(m), m.Help, c.series(m.Name, sel), legend, m.Unit), 12, 8)
+	}
+	if used, total, ok := memoryMetrics(c); ok {
+		b.add(timeseriesPanel("DRAM usage", "DRAM used relative to the total DRAM of the device",
+			fmt.Sprintf("100 * %s / %s", c.series(used.Name, sel), c.series(total.Name, sel)), legend, collector.UnitPercent), 12, 8)
+	}
+	return newDashboard("rbln-node", "RBLN / Node", "RBLN devices of a single node", vars, b.panels)
+}
+
+func deviceDashboard(c catalog) Dashboard {
+	sel := selector{labelHostname + `="$hostname"`, labelName + `="$device"`}
+	devices := selector{labelHostname + `="$hostname"`}
+	vars := []variable{
+		datasourceVariable(),
+		labelVariable(labelHostname, "Node", c.ref.Name, "", false),
+	}
+	if c.hasLabel(labelSource) {
+		sel = append(sel, labelSource+`="$source"`)
+		devices = append(devices, labelSource+`="$source"`)
+		vars = append(vars, labelVariable(labelSource, "Daemon", c.ref.Name, `hostname="$hostname"`, false))
+	}
+	vars = append(vars, queryVariable("device", "Device", fmt.Sprintf("label_values(%s%s, %s)", c.ref.Name, devices, labelName), false))
+
+	var b layout
+	b.add(infoTable("Device", fmt.Sprintf("%s%s", c.ref.Name, sel), "Value", "__name__"), 24, 4)
+	for _, m := range c.metrics {
+		expr := c.series(m.Name, sel)
+		if m.Name == metricHealth {
+			b.add(healthStat(title(m), expr), 4, 4)
+			continue
+		}
+		b.add(statPanel(title(m), expr, m.Unit), 4, 4)
+	}
+	b.newline()
+	for _, m := range c.metrics {
+		if m.Name == metricHealth {
+			b.add(healthTimeline(m, c.series(m.Name, sel), "{{name}}"), 24, 4)
+			continue
+		}
+		b.add(timeseriesPanel(title(m), m.Help, c.series(m.Name, sel), "{{name}}", m.Unit), 12, 8)
+	}
+	return newDashboard("rbln-device", "RBLN / Device", "A single RBLN device", vars, b.panels)
+}
+
+func workloadDashboard(c catalog) Dashboard {
+	sel := selector{
+		labelHostname + `=~"$hostname"`,
+		labelNamespace + `=~"$namespace"`,
+		labelPod + `=~"$pod"`,
+		labelPod + `!=""`,
+	}
+	by := "namespace, pod"
+	vars := []variable{
+		datasourceVariable(),
+		labelVariable(labelHostname, "Node", c.ref.Name, "", true),
+		labelVariable(labelNamespace, "Namespace", c.ref.Name, `hostname=~"$hostname", pod!=""`, true),
+		labelVariable(labelPod, "Pod", c.ref.Name, `hostname=~"$hostname", namespace=~"$namespace", pod!=""`, true),
+	}
+
+	var b layout
+	b.add(statPanel("Allocated devices", fmt.Sprintf("count(%s)", c.series(c.ref.Name, sel)), collector.UnitNone), 6, 4)
+	b.add(statPanel("Pods", fmt.Sprintf("count(count by (%s) (%s%s))", by, c.ref.Name, sel), collector.UnitNone), 6, 4)
+	b.newline()
+	b.add(infoTable("Allocated devices", fmt.Sprintf("%s%s", c.ref.Name, sel), "Value", "__name__", "deviceID", "driver_version", "firmware_version", "uuid"), 24, 8)
+	for _, m := range c.metrics {
+		panelTitle, expr := aggregate(m, c.series(m.Name, sel, labelNamespace, labelPod), by, "pod")
+		b.add(timeseriesPanel(panelTitle, m.Help, expr, "{{namespace}}/{{pod}}", m.Unit), 12, 8)
+	}
+	if used, total, ok := memoryMetrics(c); ok {
+		b.add(timeseriesPanel("DRAM usage by pod", "DRAM used relative to the total DRAM of the pod's devices",
+			fmt.Sprintf("100 * sum by (%s) (%s) / sum by (%s) (%s)",
+				by, c.series(used.Name, sel, labelNamespace, labelPod), by, c.series(total.Name, sel, labelNamespace, labelPod)),
+			"{{namespace}}/{{pod}}", collector.UnitPercent), 12, 8)
+	}
+	return newDashboard("rbln-workloads", "RBLN / Workloads", "RBLN devices allocated to pods", vars, b.panels)
+}
+
+func fleetDashboard(c catalog) Dashboard {
+	var sel selector
+	vars := []variable{datasourceVariable()}
+	if c.hasLabel(labelSource) {
+		sel = selector{labelSource + `=~"$source"`}
+		vars = append(vars, labelVariable(labelSource, "Daemon", c.ref.Name, "", true))
+	}
+
+	var b layout
+	b.add(statPanel("Nodes", fmt.Sprintf("count(count by (hostname) (%s%s))", c.ref.Name, sel), collector.UnitNone), 4, 4)
+	b.add(statPanel("Devices", fmt.Sprintf("count(%s)", c.series(c.ref.Name, sel)), collector.UnitNone), 4, 4)
+	if c.hasLabel(labelPod) {
+		b.add(statPanel("Allocated devices", fmt.Sprintf("count(%s)", c.series(c.ref.Name, sel.with(labelPod+`!=""`))), collector.UnitNone), 4, 4)
+	}
+	summaryStats(&b, c, sel)
+	b.newline()
+	for _, m := range c.metrics {
+		panelTitle, expr := aggregate(m, c.series(m.Name, sel), labelHostname, "node")
+		b.add(timeseriesPanel(panelTitle, m.Help, expr, "{{hostname}}", m.Unit), 12, 8)
+	}
+	b.newline()
+	b.add(versionTable("Exporter versions",
+		fmt.Sprintf("count by (version, api_version, daemon_version) (%s%s)", collector.BuildInfoMetric, sel)), 12, 8)
+	b.add(versionTable("Driver and firmware versions",
+		fmt.Sprintf("count by (card, driver_version, firmware_version) (%s)", c.series(c.ref.Name, sel))), 12, 8)
+	return newDashboard("rbln-fleet", "RBLN / Fleet", "RBLN devices across all nodes", vars, b.panels)
+}
+
+// summaryStats adds the inactive device count, total power and average
+// utilization of the selected devices when their metrics are collected.
+func summaryStats(b *layout, c catalog, sel selector) {
+	if m, ok := c.get(metricHealth); ok {
+		b.add(inactiveStat(fmt.Sprintf("sum(%s) or vector(0)", c.series(m.Name, sel))), 4, 4)
+	}
+	if m, ok := c.get(metricPower); ok {
+		b.add(statPanel("Total power", fmt.Sprintf("sum(%s)", c.series(m.Name, sel)), m.Unit), 4, 4)
+	}
+	if m, ok := c.get(metricUtil); ok {
+		b.add(statPanel("Average utilization", fmt.Sprintf("avg(%s)", c.series(m.Name, sel)), m.Unit), 4, 4)
+	}
+}
+
+func memoryMetrics(c catalog) (used, total collector.MetricDescription, ok bool) {
+	used, usedOK := c.get(metricDRAMUsed)
+	total, totalOK := c.get(metricDRAMTotal)
+	return used, total, usedOK && totalOK
+}
+
+// aggregate returns the panel title and expression of the metric's series
+// aggregated over the devices of each group.
+func aggregate(m collector.MetricDescription, series, by, group string) (string, string) {
+	agg := "sum"
+	switch m.Unit {
+	case collector.UnitCelsius:
+		agg = "max"
+	case collector.UnitPercent:
+		agg = "avg"
+	}
+	expr := fmt.Sprintf("%s by (%s) (%s)", agg, by, series)
+	if m.Name == metricHealth {
+		return "Inactive devices by " + group, expr
+	}
+	return fmt.Sprintf("%s by %s (%s)", title(m), group, agg), expr
+}
+
+// title is the help text of the metric without the unit suffix.
+func title(m collector.MetricDescription) string {
+	t, _, _ := strings.Cut(m.Help, " (")
+	return t
+}
+
+// selector is a list of label matchers.
+type selector []string
+
+func (s selector) with(matchers ...string) selector {
+	return append(slices.Clone(s), matchers...)
+}
+
+func (s selector) String() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(s, ", ") + "}"
+}
@@ -0,0 +1,133 @@
+package dashboards
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden dashboards in testdata")
+
+// metricName matches the device metric names in expressions.
+var metricName = regexp.MustCompile(`RBLN_[A-Z_]+:[A-Z_]+`)
+
+func generate(t *testing.T, groups []string, includePodLabels, includeSourceLabel bool) []Dashboard {
+	t.Helper()
+	metrics, err := collector.Catalog(groups, includePodLabels, includeSourceLabel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boards, err := Generate(metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return boards
+}
+
+// expressions returns the queries of the panels and template variables.
+func expressions(board Dashboard) []string {
+	var out []string
+	for _, v := range board.Templating.List {
+		out = append(out, v.Query)
+	}
+	for _, p := range board.Panels {
+		for _, target := range p.Targets {
+			out = append(out, target.Expr)
+		}
+	}
+	return out
+}
+
+// TestGolden compares the dashboards of the default collectors with
+// testdata; run go test -update after an intended change.
+func TestGolden(t *testing.T) {
+	for _, board := range generate(t, collector.MetricGroups(), true, false) {
+		got, err := json.MarshalIndent(board, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, '\n')
+		path := filepath.Join("testdata", board.UID+".json")
+		if *update {
+			if err := os.WriteFile(path, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("dashboard %s differs from %s; run go test -update after an intended change", board.UID, path)
+		}
+	}
+}
+
+func TestGenerateUsesCatalog(t *testing.T) {
+	tests := []struct {
+		name                                 string
+		groups                               []string
+		includePodLabels, includeSourceLabel bool
+		wantUIDs                             []string
+	}{
+		{name: "pod labels", groups: collector.MetricGroups(), includePodLabels: true, wantUIDs: []string{"rbln-node", "rbln-device", "rbln-workloads", "rbln-fleet"}},
+		{name: "kubernetes off", groups: collector.MetricGroups(), wantUIDs: []string{"rbln-node", "rbln-device", "rbln-fleet"}},
+		{name: "source label", groups: collector.MetricGroups(), includeSourceLabel: true, wantUIDs: []string{"rbln-node", "rbln-device", "rbln-fleet"}},
+		{name: "health only", groups: []string{"health"}, wantUIDs: []string{"rbln-node", "rbln-device", "rbln-fleet"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := collector.Catalog(tt.groups, tt.includePodLabels, tt.includeSourceLabel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, m := range metrics {
+				names = append(names, m.Name)
+			}
+			boards, err := Generate(metrics)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var uids []string
+			for _, board := range boards {
+				uids = append(uids, board.UID)
+				var hasSource bool
+				for _, v := range board.Templating.List {
+					hasSource = hasSource || v.Name == labelSource
+				}
+				if hasSource != tt.includeSourceLabel {
+					t.Errorf("dashboard %s has a source variable = %v, want %v", board.UID, hasSource, tt.includeSourceLabel)
+				}
+				for _, expr := range expressions(board) {
+					for _, name := range metricName.FindAllString(expr, -1) {
+						if !slices.Contains(names, name) {
+							t.Errorf("dashboard %s queries %s, which is not in the catalog: %s", board.UID, name, expr)
+						}
+					}
+					if !tt.includePodLabels && strings.Contains(expr, labelPod) {
+						t.Errorf("dashboard %s uses pod labels without them: %s", board.UID, expr)
+					}
+				}
+			}
+			if !slices.Equal(uids, tt.wantUIDs) {
+				t.Errorf("dashboards = %v, want %v", uids, tt.wantUIDs)
+			}
+		})
+	}
+}
+
+func TestGenerateWithoutMetrics(t *testing.T) {
+	if _, err := Generate(nil); err == nil {
+		t.Error("Generate(nil) succeeded, want an error")
+	}
+}
@@ -0,0 +1,136 @@
+package dashboards
+
+// The types below cover the subset of the Grafana dashboard model the
+// generated dashboards use.
+
+const (
+	schemaVersion = 39
+	gridWidth     = 24
+)
+
+type Dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description,omitempty"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	Editable      bool       `json:"editable"`
+	Refresh       string     `json:"refresh"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Time          timeRange  `json:"time"`
+	Templating    templating `json:"templating"`
+	Links         []link     `json:"links,omitempty"`
+	Panels        []panel    `json:"panels"`
+}
+
+type timeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type templating struct {
+	List []variable `json:"list"`
+}
+
+type link struct {
+	Title       string   `json:"title"`
+	Type        string   `json:"type"`
+	Tags        []string `json:"tags"`
+	AsDropdown  bool     `json:"asDropdown"`
+	IncludeVars bool     `json:"includeVars"`
+	KeepTime    bool     `json:"keepTime"`
+}
+
+type datasourceRef struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// datasource refers to the datasource template variable, so that the
+// dashboards work with any Prometheus datasource.
+var datasource = &datasourceRef{Type: "prometheus", UID: "${datasource}"}
+
+type variable struct {
+	Name       string         `json:"name"`
+	Label      string         `json:"label"`
+	Type       string         `json:"type"`
+	Query      string         `json:"query"`
+	Definition string         `json:"definition,omitempty"`
+	Datasource *datasourceRef `json:"datasource,omitempty"`
+	Refresh    int            `json:"refresh,omitempty"`
+	Sort       int            `json:"sort,omitempty"`
+	Multi      bool           `json:"multi"`
+	IncludeAll bool           `json:"includeAll"`
+	AllValue   string         `json:"allValue,omitempty"`
+}
+
+type gridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type panel struct {
+	ID              int              `json:"id"`
+	Type            string           `json:"type"`
+	Title           string           `json:"title"`
+	Description     string           `json:"description,omitempty"`
+	GridPos         gridPos          `json:"gridPos"`
+	Datasource      *datasourceRef   `json:"datasource,omitempty"`
+	Targets         []target         `json:"targets,omitempty"`
+	FieldConfig     fieldConfig      `json:"fieldConfig"`
+	Options         map[string]any   `json:"options,omitempty"`
+	Transformations []transformation `json:"transformations,omitempty"`
+}
+
+type target struct {
+	RefID        string         `json:"refId"`
+	Datasource   *datasourceRef `json:"datasource"`
+	Expr         string         `json:"expr"`
+	LegendFormat string         `json:"legendFormat,omitempty"`
+	Instant      bool           `json:"instant,omitempty"`
+	Range        bool           `json:"range"`
+	Format       string         `json:"format,omitempty"`
+}
+
+type fieldConfig struct {
+	Defaults  fieldDefaults `json:"defaults"`
+	Overrides []any         `json:"overrides"`
+}
+
+type fieldDefaults struct {
+	Unit       string         `json:"unit,omitempty"`
+	Min        *float64       `json:"min,omitempty"`
+	Max        *float64       `json:"max,omitempty"`
+	Decimals   *int           `json:"decimals,omitempty"`
+	Mappings   []valueMapping `json:"mappings,omitempty"`
+	Thresholds *thresholds    `json:"thresholds,omitempty"`
+	Color      map[string]any `json:"color,omitempty"`
+}
+
+type valueMapping struct {
+	Type    string                  `json:"type"`
+	Options map[string]mappingValue `json:"options"`
+}
+
+type mappingValue struct {
+	Text  string `json:"text"`
+	Color string `json:"color"`
+	Index int    `json:"index"`
+}
+
+type thresholds struct {
+	Mode  string      `json:"mode"`
+	Steps []threshold `json:"steps"`
+}
+
+type threshold struct {
+	Color string   `json:"color"`
+	Value *float64 `json:"value"`
+}
+
+type transformation struct {
+	ID      string         `json:"id"`
+	Options map[string]any `json:"options"`
+}
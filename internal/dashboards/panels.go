@@ -0,0 +1,201 @@
+package dashboards
+
+import (
+	"fmt"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+)
+
+// layout places panels left to right and wraps them into rows of the
+// Grafana grid.
+type layout struct {
+	panels    []panel
+	x, y      int
+	rowHeight int
+}
+
+func (l *layout) add(p panel, w, h int) {
+	if l.x+w > gridWidth {
+		l.newline()
+	}
+	p.ID = len(l.panels) + 1
+	p.GridPos = gridPos{X: l.x, Y: l.y, W: w, H: h}
+	p.Datasource = datasource
+	l.x += w
+	l.rowHeight = max(l.rowHeight, h)
+	l.panels = append(l.panels, p)
+}
+
+func (l *layout) newline() {
+	if l.x == 0 {
+		return
+	}
+	l.x = 0
+	l.y += l.rowHeight
+	l.rowHeight = 0
+}
+
+func rangeQuery(expr, legend string) target {
+	return target{RefID: "A", Datasource: datasource, Expr: expr, LegendFormat: legend, Range: true}
+}
+
+func instantQuery(expr string) target {
+	return target{RefID: "A", Datasource: datasource, Expr: expr, Instant: true, Format: "table"}
+}
+
+func unitDefaults(unit string) fieldDefaults {
+	d := fieldDefaults{Unit: grafanaUnits[unit]}
+	if unit == collector.UnitPercent {
+		d.Min, d.Max = ptr(0.0), ptr(100.0)
+	}
+	return d
+}
+
+func timeseriesPanel(title, description, expr, legend, unit string) panel {
+	return panel{
+		Type:        "timeseries",
+		Title:       title,
+		Description: description,
+		Targets:     []target{rangeQuery(expr, legend)},
+		FieldConfig: fieldConfig{Defaults: unitDefaults(unit), Overrides: []any{}},
+		Options: map[string]any{
+			"legend":  map[string]any{"displayMode": "table", "placement": "right", "calcs": []string{"lastNotNull", "max"}},
+			"tooltip": map[string]any{"mode": "multi", "sort": "desc"},
+		},
+	}
+}
+
+func statPanel(title, expr, unit string) panel {
+	return panel{
+		Type:        "stat",
+		Title:       title,
+		Targets:     []target{rangeQuery(expr, "")},
+		FieldConfig: fieldConfig{Defaults: unitDefaults(unit), Overrides: []any{}},
+		Options: map[string]any{
+			"reduceOptions": map[string]any{"calcs": []string{"lastNotNull"}, "fields": "", "values": false},
+			"colorMode":     "none",
+			"graphMode":     "area",
+		},
+	}
+}
+
+// healthMappings render the HEALTH values 0 and 1.
+var healthMappings = []valueMapping{{
+	Type: "value",
+	Options: map[string]mappingValue{
+		"0": {Text: "Active", Color: "green", Index: 0},
+		"1": {Text: "Inactive", Color: "red", Index: 1},
+	},
+}}
+
+func healthStat(title, expr string) panel {
+	p := statPanel(title, expr, collector.UnitNone)
+	p.FieldConfig.Defaults.Mappings = healthMappings
+	p.Options["colorMode"] = "background"
+	p.Options["graphMode"] = "none"
+	return p
+}
+
+func healthTimeline(m collector.MetricDescription, expr, legend string) panel {
+	return panel{
+		Type:        "state-timeline",
+		Title:       title(m),
+		Description: m.Help + " (0 = active, 1 = inactive)",
+		Targets:     []target{rangeQuery(expr, legend)},
+		FieldConfig: fieldConfig{
+			Defaults:  fieldDefaults{Mappings: healthMappings, Color: map[string]any{"mode": "thresholds"}},
+			Overrides: []any{},
+		},
+		Options: map[string]any{
+			"showValue":   "never",
+			"mergeValues": true,
+			"legend":      map[string]any{"displayMode": "list", "placement": "bottom", "showLegend": false},
+		},
+	}
+}
+
+func inactiveStat(expr string) panel {
+	p := statPanel("Inactive devices", expr, collector.UnitNone)
+	p.FieldConfig.Defaults.Thresholds = &thresholds{
+		Mode:  "absolute",
+		Steps: []threshold{{Color: "green"}, {Color: "red", Value: ptr(1.0)}},
+	}
+	p.Options["colorMode"] = "background"
+	return p
+}
+
+// infoTable lists the label sets of the series returned by expr, without the
+// excluded columns.
+func infoTable(title, expr string, exclude ...string) panel {
+	excluded := map[string]bool{"Time": true}
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	return panel{
+		Type:        "table",
+		Title:       title,
+		Targets:     []target{instantQuery(expr)},
+		FieldConfig: fieldConfig{Defaults: fieldDefaults{}, Overrides: []any{}},
+		Transformations: []transformation{{
+			ID:      "organize",
+			Options: map[string]any{"excludeByName": excluded},
+		}},
+	}
+}
+
+// versionTable lists the label sets of expr with the number of series as
+// the Count column.
+func versionTable(title, expr string) panel {
+	return panel{
+		Type:        "table",
+		Title:       title,
+		Targets:     []target{instantQuery(expr)},
+		FieldConfig: fieldConfig{Defaults: fieldDefaults{Decimals: ptr(0)}, Overrides: []any{}},
+		Transformations: []transformation{{
+			ID: "organize",
+			Options: map[string]any{
+				"excludeByName": map[string]bool{"Time": true},
+				"renameByName":  map[string]string{"Value": "Count"},
+			},
+		}},
+	}
+}
+
+func datasourceVariable() variable {
+	return variable{Name: "datasource", Label: "Datasource", Type: "datasource", Query: "prometheus"}
+}
+
+// labelVariable selects values of label among the series of metric that
+// match the matchers.
+func labelVariable(label, title, metric, matchers string, multi bool) variable {
+	sel := metric
+	if matchers != "" {
+		sel += "{" + matchers + "}"
+	}
+	return queryVariable(label, title, fmt.Sprintf("label_values(%s, %s)", sel, label), multi)
+}
+
+func queryVariable(name, title, query string, multi bool) variable {
+	v := variable{
+		Name:       name,
+		Label:      title,
+		Type:       "query",
+		Query:      query,
+		Definition: query,
+		Datasource: datasource,
+		// Refresh the values when the time range changes.
+		Refresh: 2,
+		// Sort alphabetically, case-insensitive.
+		Sort:  5,
+		Multi: multi,
+	}
+	if multi {
+		v.IncludeAll = true
+		v.AllValue = ".*"
+	}
+	return v
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
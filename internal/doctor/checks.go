@@ -0,0 +1,369 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	rpcTimeout  = 30 * time.Second
+)
+
+// Ranges of plausible converted values. Values outside of them usually mean
+// that the daemon reports in different units than the exporter expects.
+const (
+	maxTemperatureCelsius = 150
+	maxPowerWatts         = 1000
+	maxMemoryGiB          = 1024
+)
+
+func daemonCheckName(d Daemon, check string) string {
+	if d.Name == "" {
+		return "daemon " + check
+	}
+	return fmt.Sprintf("daemon %s %s", d.Name, check)
+}
+
+// unixSocketPath returns the path of a unix:// or unix: gRPC target.
+func unixSocketPath(address string) (string, bool) {
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		return path, true
+	}
+	return strings.CutPrefix(address, "unix:")
+}
+
+// checkDaemon resolves and dials the daemon, calls every read-only RPC and
+// checks the reported values. It returns the names of the daemon's devices.
+func checkDaemon(ctx context.Context, d Daemon) ([]string, []Result) {
+	var results []Result
+	network, address := "tcp", d.Address
+	if path, ok := unixSocketPath(d.Address); ok {
+		network, address = "unix", path
+	} else {
+		r := timed(func() Result { return checkResolve(ctx, d) })
+		results = append(results, r)
+		if r.Status == StatusFail {
+			return nil, append(results, skipRPCs(d))
+		}
+	}
+
+	r := timed(func() Result {
+		r := Result{Check: daemonCheckName(d, "connect")}
+		conn, err := (&net.Dialer{Timeout: dialTimeout}).DialContext(ctx, network, address)
+		if err != nil {
+			r.Status = StatusFail
+			r.Message = fmt.Sprintf("cannot connect to %s: %v", d.Address, err)
+			r.Hint = "make sure rbln-daemon is running and listening on this address (--rbln-daemon-url or --rbln-daemon-endpoint), and that no firewall or network policy blocks it"
+			return r
+		}
+		_ = conn.Close()
+		r.Status = StatusPass
+		r.Message = "connected to " + d.Address
+		return r
+	})
+	results = append(results, r)
+	if r.Status == StatusFail {
+		return nil, append(results, skipRPCs(d))
+	}
+
+	client, err := daemon.NewClient(ctx, d.Address)
+	if err != nil {
+		return nil, append(results, Result{
+			Check:   daemonCheckName(d, "grpc"),
+			Status:  StatusFail,
+			Message: err.Error(),
+			Hint:    "the address accepts connections but does not speak gRPC; check that it points to rbln-daemon",
+		})
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+	results = append(results, rpcResults(d, client.CallAll(rpcCtx))...)
+
+	devices, err := client.GetDeviceInfo(rpcCtx)
+	if err != nil {
+		return nil, results
+	}
+	names := make([]string, 0, len(devices))
+	for _, device := range devices {
+		names = append(names, device.Name)
+	}
+	return names, append(results, checkUnits(d, devices))
+}
+
+func checkResolve(ctx context.Context, d Daemon) Result {
+	r := Result{Check: daemonCheckName(d, "resolve")}
+	host, _, err := net.SplitHostPort(d.Address)
+	if err != nil {
+		r.Status = StatusFail
+		r.Message = fmt.Sprintf("invalid address %q: %v", d.Address, err)
+		r.Hint = "use host:port or unix:///path/to.sock"
+		return r
+	}
+	if net.ParseIP(host) != nil {
+		r.Status = StatusPass
+		r.Message = host + " is an IP address"
+		return r
+	}
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		r.Status = StatusFail
+		r.Message = fmt.Sprintf("cannot resolve %s: %v", host, err)
+		r.Hint = "check the host name and the DNS configuration of the node or pod"
+		return r
+	}
+	r.Status = StatusPass
+	r.Message = fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", "))
+	return r
+}
+
+func skipRPCs(d Daemon) Result {
+	return Result{
+		Check:   daemonCheckName(d, "rpc"),
+		Status:  StatusSkip,
+		Message: "daemon is not reachable",
+	}
+}
+
+// rpcResults summarizes the calls per method; per-device calls are reported
+// with the slowest call.
+func rpcResults(d Daemon, calls []daemon.Call) []Result {
+	var (
+		order   []string
+		byCheck = make(map[string]*Result)
+	)
+	for _, call := range calls {
+		check := daemonCheckName(d, "rpc "+call.Method)
+		r, ok := byCheck[check]
+		if !ok {
+			r = &Result{Check: check, Status: StatusPass}
+			byCheck[check] = r
+			order = append(order, check)
+		}
+		r.DurationSeconds = max(r.DurationSeconds, call.Duration.Seconds())
+
+		switch {
+		case call.Err == nil:
+			if call.Device == "" {
+				r.Message = fmt.Sprintf("%d responses", len(call.Responses))
+			} else if r.Status == StatusPass {
+				r.Message = appendDevice(r.Message, call.Device, "ok for")
+			}
+		case status.Code(call.Err) == codes.Unimplemented:
+			r.Status = StatusWarn
+			r.Message = "not implemented by this rbln-daemon version"
+			r.Hint = "upgrade rbln-daemon; the exporter does not use this RPC for metrics"
+		default:
+			if r.Status != StatusFail {
+				r.Message = ""
+			}
+			r.Status = StatusFail
+			if call.Device == "" {
+				r.Message = call.Err.Error()
+			} else {
+				r.Message = appendDevice(r.Message, call.Device+": "+call.Err.Error(), "failed for")
+			}
+			r.Hint = "check the rbln-daemon logs; the driver and daemon versions may be incompatible"
+		}
+	}
+
+	results := make([]Result, 0, len(order))
+	for _, check := range order {
+		results = append(results, *byCheck[check])
+	}
+	return results
+}
+
+func appendDevice(message, device, prefix string) string {
+	if message == "" {
+		return prefix + " " + device
+	}
+	return message + ", " + device
+}
+
+// checkUnits reports converted values outside of plausible ranges.
+func checkUnits(d Daemon, devices []daemon.DeviceInfo) Result {
+	r := Result{Check: daemonCheckName(d, "units")}
+	if len(devices) == 0 {
+		r.Status = StatusWarn
+		r.Message = "the daemon reports no serviceable devices"
+		r.Hint = "check that the rebellions driver is loaded and the devices are healthy (rbln-stat)"
+		return r
+	}
+
+	var problems []string
+	for _, device := range devices {
+		if device.Temperature <= 0 || device.Temperature > maxTemperatureCelsius {
+			problems = append(problems, fmt.Sprintf("%s temperature %g°C", device.Name, device.Temperature))
+		}
+		if device.Power < 0 || device.Power > maxPowerWatts {
+			problems = append(problems, fmt.Sprintf("%s power %gW", device.Name, device.Power))
+		}
+		if device.DRAMTotalGiB <= 0 || device.DRAMTotalGiB > maxMemoryGiB || device.DRAMUsedGiB < 0 || device.DRAMUsedGiB > device.DRAMTotalGiB {
+			problems = append(problems, fmt.Sprintf("%s memory %g/%g GiB", device.Name, device.DRAMUsedGiB, device.DRAMTotalGiB))
+		}
+		if device.Utilization < 0 || device.Utilization > 100 {
+			problems = append(problems, fmt.Sprintf("%s utilization %g%%", device.Name, device.Utilization))
+		}
+	}
+	if len(problems) > 0 {
+		r.Status = StatusWarn
+		r.Message = "implausible values: " + strings.Join(problems, ", ")
+		r.Hint = "the exporter expects temperature in milli-Celsius and power in micro-Watts from rbln-daemon; older daemons report other units, so upgrade rbln-daemon and the driver together"
+		return r
+	}
+	r.Status = StatusPass
+	r.Message = fmt.Sprintf("values of %d devices are within plausible ranges", len(devices))
+	return r
+}
+
+func checkPodResources(ctx context.Context, required bool) []Result {
+	failed := StatusWarn
+	if required {
+		failed = StatusFail
+	}
+
+	socket := Result{Check: "pod-resources socket"}
+	if _, err := os.Stat(collector.PodResourceSocket); err != nil {
+		socket.Status = failed
+		socket.Message = err.Error()
+		socket.Hint = "mount the host's /var/lib/kubelet/pod-resources into the container (see deployments/kubernetes/daemonset.yaml); without it metrics have no pod labels"
+		return []Result{socket, {Check: "pod-resources list", Status: StatusSkip, Message: "socket is missing"}}
+	}
+	socket.Status = StatusPass
+	socket.Message = collector.PodResourceSocket + " exists"
+
+	list := timed(func() Result {
+		r := Result{Check: "pod-resources list"}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		mapper, err := collector.NewPodResourceMapper(ctx)
+		if err == nil {
+			err = mapper.SyncError()
+		}
+		if err != nil {
+			r.Status = failed
+			r.Message = err.Error()
+			switch {
+			case errors.Is(err, os.ErrPermission) || status.Code(errors.Unwrap(err)) == codes.PermissionDenied || strings.Contains(err.Error(), "permission denied"):
+				r.Hint = "the kubelet socket is only accessible to root; run the exporter as root or adjust the securityContext"
+			case strings.Contains(err.Error(), collector.SysfsDriverDir):
+				r.Hint = "allocated device IDs are resolved through sysfs; mount the host's /sys into the container"
+			default:
+				r.Hint = "make sure the kubelet serves the pod-resources API (KubeletPodResources feature, enabled by default since Kubernetes 1.15)"
+			}
+			return r
+		}
+		r.Status = StatusPass
+		r.Message = fmt.Sprintf("%d RBLN devices are allocated to pods (pod-resources %s)", len(mapper.Snapshot()), mapper.APIVersion())
+		return r
+	})
+	return []Result{socket, list}
+}
+
+// checkSysfs checks the driver directory and the pools files used to map
+// allocated PCI addresses to device names.
+func checkSysfs(localDevices []string, local bool) []Result {
+	driver := Result{Check: "sysfs driver"}
+	if _, err := os.Stat(collector.SysfsDriverDir); err != nil {
+		driver.Status = StatusFail
+		if !local {
+			driver.Status = StatusSkip
+		}
+		driver.Message = err.Error()
+		driver.Hint = "load the rebellions driver and mount the host's /sys into the container"
+		return []Result{driver, {Check: "sysfs pools", Status: StatusSkip, Message: "driver directory is missing"}}
+	}
+	driver.Status = StatusPass
+	driver.Message = collector.SysfsDriverDir + " exists"
+
+	pools := Result{Check: "sysfs pools"}
+	addresses, err := collector.DevicePCIAddresses()
+	switch {
+	case err != nil:
+		pools.Status = StatusFail
+		pools.Message = err.Error()
+		pools.Hint = "the pools files map PCI addresses to device names for pod labels; check the driver version and that /sys is mounted read-only, not masked"
+	case len(addresses) == 0:
+		pools.Status = StatusWarn
+		pools.Message = "no devices are bound to the rebellions driver"
+		pools.Hint = "check lspci and dmesg for devices that failed to bind"
+	default:
+		var missing []string
+		for _, name := range localDevices {
+			if _, ok := addresses[collector.DeviceName(name)]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			pools.Status = StatusWarn
+			pools.Message = "no PCI address for " + strings.Join(missing, ", ")
+			pools.Hint = "pod labels cannot be resolved for these devices; check that the daemon and sysfs see the same devices"
+			break
+		}
+		var mapped []string
+		for name, address := range addresses {
+			mapped = append(mapped, fmt.Sprintf("%s=%s", name, address))
+		}
+		slices.Sort(mapped)
+		pools.Status = StatusPass
+		pools.Message = strings.Join(mapped, ", ")
+	}
+	return []Result{driver, pools}
+}
+
+// checkListenAddress checks that the address can be bound. Unix sockets are
+// dialed instead, because binding would remove a socket in use.
+func checkListenAddress(address string) Result {
+	r := Result{Check: "listen " + address}
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			r.Status = StatusFail
+			r.Message = "another process is serving on the socket"
+			r.Hint = "an exporter may already be running on this node; stop it or use another socket path"
+			return r
+		}
+		r.Status = StatusPass
+		r.Message = "socket is not in use"
+		return r
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		r.Status = StatusFail
+		r.Message = err.Error()
+		switch {
+		case errors.Is(err, syscall.EADDRINUSE):
+			r.Hint = "another process uses the port, possibly a running exporter; stop it or change --port or --listen-address"
+		case errors.Is(err, syscall.EACCES):
+			r.Hint = "ports below 1024 need root or CAP_NET_BIND_SERVICE"
+		default:
+			r.Hint = "check the address syntax (host:port, [ipv6]:port) and that the host address exists on this node"
+		}
+		return r
+	}
+	_ = l.Close()
+	r.Status = StatusPass
+	r.Message = "address is available"
+	return r
+}
@@ -0,0 +1,141 @@
+// Package doctor actively checks the environment the exporter depends on:
+// the rbln-daemon, the kubelet pod-resources API, sysfs and the listen
+// addresses.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Result is the outcome of one check. Hint tells how to fix a failure.
+type Result struct {
+	Check           string  `json:"check"`
+	Status          Status  `json:"status"`
+	Message         string  `json:"message"`
+	Hint            string  `json:"hint,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+// Daemon is an rbln-daemon endpoint. Name is empty for the local daemon.
+type Daemon struct {
+	Name    string
+	Address string
+}
+
+type Config struct {
+	Daemons []Daemon
+	// ListenAddresses are the HTTP and gRPC addresses the exporter would bind.
+	ListenAddresses []string
+	// PodResources enables the pod-resources checks; failures are only
+	// warnings unless PodResourcesRequired is set.
+	PodResources         bool
+	PodResourcesRequired bool
+}
+
+// Run runs every check and returns the results in order.
+func Run(ctx context.Context, cfg Config) []Result {
+	var results []Result
+	var localDevices []string
+	for _, d := range cfg.Daemons {
+		devices, r := checkDaemon(ctx, d)
+		results = append(results, r...)
+		if d.Name == "" {
+			localDevices = devices
+		}
+	}
+	if cfg.PodResources {
+		results = append(results, checkPodResources(ctx, cfg.PodResourcesRequired)...)
+	}
+	results = append(results, checkSysfs(localDevices, hasLocalDaemon(cfg.Daemons))...)
+	for _, address := range cfg.ListenAddresses {
+		results = append(results, checkListenAddress(address))
+	}
+	return results
+}
+
+func hasLocalDaemon(daemons []Daemon) bool {
+	for _, d := range daemons {
+		if d.Name == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Failed returns the number of failed checks.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Status == StatusFail {
+			n++
+		}
+	}
+	return n
+}
+
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{
+		"results": results,
+		"failed":  Failed(results),
+	})
+}
+
+func WriteText(w io.Writer, results []Result) error {
+	counts := make(map[Status]int)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		counts[r.Status]++
+		message := r.Message
+		if r.DurationSeconds > 0 {
+			message += fmt.Sprintf(" (%s)", roundDuration(time.Duration(r.DurationSeconds*float64(time.Second))))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", statusLabels[r.Status], r.Check, message)
+		if r.Hint != "" && (r.Status == StatusFail || r.Status == StatusWarn) {
+			fmt.Fprintf(tw, "\t\thint: %s\n", r.Hint)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed, %d skipped\n",
+		counts[StatusPass], counts[StatusWarn], counts[StatusFail], counts[StatusSkip])
+	return err
+}
+
+var statusLabels = map[Status]string{
+	StatusPass: "PASS",
+	StatusWarn: "WARN",
+	StatusFail: "FAIL",
+	StatusSkip: "SKIP",
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond / 10)
+}
+
+// timed runs fn and sets the duration of its result.
+func timed(fn func() Result) Result {
+	start := time.Now()
+	r := fn()
+	r.DurationSeconds = time.Since(start).Seconds()
+	return r
+}
@@ -0,0 +1,153 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+func TestCheckUnits(t *testing.T) {
+	plausible := daemon.DeviceInfo{Name: "rbln0", Temperature: 45, Power: 80, DRAMUsedGiB: 2, DRAMTotalGiB: 16, Utilization: 30}
+	tests := []struct {
+		name    string
+		devices []daemon.DeviceInfo
+		want    Status
+		message string
+	}{
+		{name: "plausible", devices: []daemon.DeviceInfo{plausible}, want: StatusPass},
+		{name: "no devices", want: StatusWarn, message: "no serviceable devices"},
+		{
+			name: "milli-Celsius and micro-Watts",
+			devices: []daemon.DeviceInfo{plausible, func() daemon.DeviceInfo {
+				d := plausible
+				d.Name, d.Temperature, d.Power = "rbln1", 45000, 80e6
+				return d
+			}()},
+			want:    StatusWarn,
+			message: "rbln1 temperature 45000°C, rbln1 power 8e+07W",
+		},
+		{
+			name: "memory and utilization",
+			devices: []daemon.DeviceInfo{func() daemon.DeviceInfo {
+				d := plausible
+				d.DRAMUsedGiB, d.Utilization = 32, 101
+				return d
+			}()},
+			want:    StatusWarn,
+			message: "rbln0 memory 32/16 GiB, rbln0 utilization 101%",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := checkUnits(Daemon{Address: "127.0.0.1:50051"}, tt.devices)
+			if r.Status != tt.want || !strings.Contains(r.Message, tt.message) {
+				t.Errorf("checkUnits() = %s %q, want %s containing %q", r.Status, r.Message, tt.want, tt.message)
+			}
+			if r.Check != "daemon units" {
+				t.Errorf("check = %q, want daemon units", r.Check)
+			}
+		})
+	}
+}
+
+func TestCheckDaemonUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	tests := []struct {
+		name    string
+		daemon  Daemon
+		want    []string
+		wantMsg string
+	}{
+		{name: "invalid address", daemon: Daemon{Name: "tray", Address: "tray-1"}, want: []string{"daemon tray resolve", "daemon tray rpc"}, wantMsg: "invalid address"},
+		{name: "closed port", daemon: Daemon{Address: address}, want: []string{"daemon resolve", "daemon connect", "daemon rpc"}, wantMsg: "cannot connect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devices, results := checkDaemon(context.Background(), tt.daemon)
+			if devices != nil {
+				t.Errorf("devices = %v, want none", devices)
+			}
+			var checks []string
+			for _, r := range results {
+				checks = append(checks, r.Check)
+			}
+			if strings.Join(checks, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("checks = %v, want %v", checks, tt.want)
+			}
+			if failed := results[len(results)-2]; failed.Status != StatusFail || !strings.Contains(failed.Message, tt.wantMsg) || failed.Hint == "" {
+				t.Errorf("%s = %s %q, want a failure with %q and a hint", failed.Check, failed.Status, failed.Message, tt.wantMsg)
+			}
+			if skipped := results[len(results)-1]; skipped.Status != StatusSkip {
+				t.Errorf("rpc status = %s, want skip", skipped.Status)
+			}
+		})
+	}
+}
+
+func TestCheckListenAddress(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if r := checkListenAddress(l.Addr().String()); r.Status != StatusFail || !strings.Contains(r.Hint, "another process uses the port") {
+		t.Errorf("address in use = %s %q, want a failure with the in-use hint", r.Status, r.Hint)
+	}
+	if r := checkListenAddress("127.0.0.1:0"); r.Status != StatusPass {
+		t.Errorf("free address = %s %q, want pass", r.Status, r.Message)
+	}
+	if r := checkListenAddress("unix://" + t.TempDir() + "/exporter.sock"); r.Status != StatusPass {
+		t.Errorf("unused socket = %s %q, want pass", r.Status, r.Message)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	results := []Result{
+		{Check: "daemon connect", Status: StatusPass, Message: "connected", DurationSeconds: 0.0123},
+		{Check: "sysfs driver", Status: StatusFail, Message: "not loaded", Hint: "load the driver"},
+		{Check: "daemon rpc", Status: StatusSkip, Message: "daemon is not reachable", Hint: "not shown"},
+	}
+	if got := Failed(results); got != 1 {
+		t.Errorf("Failed() = %d, want 1", got)
+	}
+
+	var text bytes.Buffer
+	if err := WriteText(&text, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"PASS  daemon connect  connected (12.3ms)", "FAIL  sysfs driver    not loaded", "hint: load the driver", "1 passed, 0 warnings, 1 failed, 1 skipped"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("WriteText() does not contain %q:\n%s", want, text.String())
+		}
+	}
+	if strings.Contains(text.String(), "not shown") {
+		t.Errorf("WriteText() shows the hint of a skipped check:\n%s", text.String())
+	}
+
+	var out struct {
+		Results []Result `json:"results"`
+		Failed  int      `json:"failed"`
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Failed != 1 || len(out.Results) != 3 || out.Results[1].Hint != "load the driver" {
+		t.Errorf("WriteJSON() = %s, want the results and the failed count", buf.String())
+	}
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// subscriberBuffer is the number of events a subscriber may fall behind
+// before new events are dropped for it.
+const subscriberBuffer = 64
+
+// Subscription receives daemon events. Dropped counts the events that were
+// discarded because the subscriber did not keep up.
+type Subscription struct {
+	C <-chan daemon.Event
+
+	ch      chan daemon.Event
+	mu      sync.Mutex
+	dropped int
+}
+
+// Dropped returns and resets the number of events dropped since the last call.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.dropped
+	s.dropped = 0
+	return n
+}
+
+// Broker fans daemon events out to subscribers without ever blocking the
+// publisher.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[*Subscription]struct{})}
+}
+
+func (b *Broker) Publish(event daemon.Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			sub.mu.Lock()
+			sub.dropped++
+			sub.mu.Unlock()
+		}
+	}
+}
+
+// Subscribe registers a subscriber and returns a function that cancels it.
+func (b *Broker) Subscribe() (*Subscription, func()) {
+	ch := make(chan daemon.Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, sub)
+	}
+}
@@ -0,0 +1,116 @@
+package events
+
+import (
+	"cmp"
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+const DeviceEventsMetric = "rbln_device_events_total"
+
+// Metrics counts the daemon events per device, cause and type, so that
+// resets and TDRs can be alerted on. The counters of every known cause and
+// type start at zero once a device is collected, because increase() cannot
+// see the first event of a series that did not exist before it.
+type Metrics struct {
+	events      *prometheus.CounterVec
+	hostnames   map[string]string
+	nodeName    string
+	multiSource bool
+}
+
+func NewMetrics(sources []collector.Source, nodeName string) *Metrics {
+	multiSource := len(sources) > 1 || (len(sources) == 1 && sources[0].Name != "")
+	labels := []string{"name", "hostname", "cause", "type"}
+	if multiSource {
+		labels = append(labels, "source")
+	}
+	hostnames := make(map[string]string, len(sources))
+	for _, source := range sources {
+		hostnames[source.Name] = source.Hostname
+	}
+	return &Metrics{
+		events: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: DeviceEventsMetric,
+				Help: "Device events reported by the rbln-daemon, such as resets and TDRs",
+			}, labels,
+		),
+		hostnames:   hostnames,
+		nodeName:    nodeName,
+		multiSource: multiSource,
+	}
+}
+
+func (m *Metrics) Register(registerer prometheus.Registerer) {
+	registerer.MustRegister(m.events)
+}
+
+// Run counts the broker's events until ctx is canceled, creating the
+// counters of the devices in the published snapshots.
+func (m *Metrics) Run(ctx context.Context, broker *Broker, snapshots *collector.SnapshotStore) {
+	sub, cancel := broker.Subscribe()
+	defer cancel()
+	updates, unsubscribe := snapshots.Subscribe()
+	defer unsubscribe()
+
+	if snapshot, ok := snapshots.Latest(); ok {
+		m.addDevices(snapshot)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snapshot := <-updates:
+			m.addDevices(snapshot)
+		case event := <-sub.C:
+			m.observe(event)
+		}
+	}
+}
+
+// addDevices creates the counters of every known cause and type of the
+// snapshot's devices, leaving existing counters untouched.
+func (m *Metrics) addDevices(snapshot collector.Snapshot) {
+	for _, device := range snapshot.Devices {
+		for _, cause := range daemon.EventCauses() {
+			for _, typ := range daemon.EventTypes() {
+				m.events.With(m.labels(daemon.Event{Device: device.Name, Source: device.Source, Cause: cause, Type: typ}))
+			}
+		}
+	}
+}
+
+func (m *Metrics) observe(event daemon.Event) {
+	m.events.With(m.labels(event)).Inc()
+}
+
+func (m *Metrics) labels(event daemon.Event) prometheus.Labels {
+	labels := prometheus.Labels{
+		"name":     event.Device,
+		"hostname": cmp.Or(m.hostnames[event.Source], m.nodeName),
+		"cause":    event.Cause,
+		"type":     event.Type,
+	}
+	if m.multiSource {
+		labels["source"] = event.Source
+	}
+	return labels
+}
+
+// Catalog describes the event metrics with the label options of the exporter.
+func Catalog(includeSourceLabel bool) ([]collector.MetricDescription, error) {
+	source := collector.Source{}
+	if includeSourceLabel {
+		source.Name = "catalog"
+	}
+	m := NewMetrics([]collector.Source{source}, "")
+	m.observe(daemon.Event{Source: source.Name})
+
+	registry := prometheus.NewRegistry()
+	m.Register(registry)
+	return collector.DescribeMetrics(registry, "events")
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// counters returns the event counters of registry by cause and type.
+func counters(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	for _, mf := range families {
+		for _, metric := range mf.GetMetric() {
+			var name, cause, typ string
+			for _, pair := range metric.GetLabel() {
+				switch pair.GetName() {
+				case "name":
+					name = pair.GetValue()
+				case "cause":
+					cause = pair.GetValue()
+				case "type":
+					typ = pair.GetValue()
+				}
+			}
+			values[name+"/"+cause+"/"+typ] = metric.GetCounter().GetValue()
+		}
+	}
+	return values
+}
+
+func TestMetricsStartCountersAtZero(t *testing.T) {
+	snapshots := collector.NewSnapshotStore()
+	snapshots.Publish(collector.Snapshot{Devices: []daemon.DeviceInfo{{Name: "rbln0"}}})
+	broker := NewBroker()
+	m := NewMetrics([]collector.Source{{}}, "node-1")
+	registry := prometheus.NewRegistry()
+	m.Register(registry)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx, broker, snapshots)
+
+	want := len(daemon.EventCauses()) * len(daemon.EventTypes())
+	deadline := time.Now().Add(5 * time.Second)
+	for len(counters(t, registry)) < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("got counters %v, want %d", counters(t, registry), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for series, value := range counters(t, registry) {
+		if value != 0 {
+			t.Errorf("counter %s = %v before any event, want 0", series, value)
+		}
+	}
+
+	broker.Publish(daemon.Event{Device: "rbln0", Cause: "tdr", Type: "no_response"})
+	for counters(t, registry)["rbln0/tdr/no_response"] != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("tdr counter was not incremented: %v", counters(t, registry))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := len(counters(t, registry)); got != want {
+		t.Errorf("got %d counters after the event, want %d", got, want)
+	}
+}
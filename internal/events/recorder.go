@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// Record is an event with the time the exporter received it.
+type Record struct {
+	Received time.Time
+	daemon.Event
+}
+
+// Recorder keeps the most recent events of a Broker, e.g. for support bundles.
+type Recorder struct {
+	mu      sync.Mutex
+	size    int
+	records []Record
+	dropped int
+}
+
+func NewRecorder(size int) *Recorder {
+	return &Recorder{size: size}
+}
+
+// Run records the broker's events until ctx is canceled.
+func (r *Recorder) Run(ctx context.Context, broker *Broker) {
+	sub, cancel := broker.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-sub.C:
+			r.mu.Lock()
+			r.records = append(r.records, Record{Received: time.Now(), Event: event})
+			if len(r.records) > r.size {
+				r.records = r.records[len(r.records)-r.size:]
+			}
+			r.dropped += sub.Dropped()
+			r.mu.Unlock()
+		}
+	}
+}
+
+// Events returns the recorded events, oldest first, and the number of events
+// that were dropped because the recorder did not keep up.
+func (r *Recorder) Events() ([]Record, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Record(nil), r.records...), r.dropped
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+const (
+	minRetryBackoff = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// Watcher opens one daemon event stream per device and publishes the events
+// to a Broker. Devices are discovered from the collection snapshots, so the
+// daemon is not polled for the device list a second time.
+type Watcher struct {
+	sources   map[string]*daemon.Client
+	snapshots *collector.SnapshotStore
+	broker    *Broker
+}
+
+type watchKey struct {
+	source string
+	device string
+}
+
+func NewWatcher(sources []collector.Source, snapshots *collector.SnapshotStore, broker *Broker) *Watcher {
+	clients := make(map[string]*daemon.Client, len(sources))
+	for _, source := range sources {
+		clients[source.Name] = source.Client
+	}
+	return &Watcher{
+		sources:   clients,
+		snapshots: snapshots,
+		broker:    broker,
+	}
+}
+
+func (w *Watcher) Run(ctx context.Context) {
+	updates, unsubscribe := w.snapshots.Subscribe()
+	defer unsubscribe()
+
+	watching := make(map[watchKey]struct{})
+	watch := func(snapshot collector.Snapshot) {
+		for _, device := range snapshot.Devices {
+			key := watchKey{source: device.Source, device: device.Name}
+			client, ok := w.sources[key.source]
+			if _, watched := watching[key]; watched || !ok {
+				continue
+			}
+			watching[key] = struct{}{}
+			go w.watchDevice(ctx, client, key)
+		}
+	}
+
+	if snapshot, ok := w.snapshots.Latest(); ok {
+		watch(snapshot)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snapshot := <-updates:
+			watch(snapshot)
+		}
+	}
+}
+
+func (w *Watcher) watchDevice(ctx context.Context, client *daemon.Client, key watchKey) {
+	publish := func(event daemon.Event) {
+		event.Source = key.source
+		w.broker.Publish(event)
+	}
+
+	backoff := minRetryBackoff
+	for {
+		start := time.Now()
+		err := client.WatchEvents(ctx, key.device, publish)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > maxRetryBackoff {
+			backoff = minRetryBackoff
+		}
+		slog.Debug("daemon event stream ended, reconnecting", "source", key.source, "device", key.device, "backoff", backoff, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
@@ -0,0 +1,242 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/restapi"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/server"
+	"github.com/rebellions-sw/rbln-metrics-exporter/pkg/rblnexporterpb"
+)
+
+const gracefulStopTimeout = 5 * time.Second
+
+type Config struct {
+	// ListenAddress is a host:port pair or a unix socket written as
+	// unix:///path/to.sock.
+	ListenAddress string
+	// WebConfigFile and BearerTokenFile are shared with the HTTP server:
+	// the tls_server_config section enables TLS and the token is required
+	// in the authorization metadata of every call.
+	WebConfigFile   string
+	BearerTokenFile string
+}
+
+func (c Config) Enabled() bool {
+	return c.ListenAddress != ""
+}
+
+// Server serves the collection snapshots over gRPC so that other node agents
+// can share the exporter's connection to rbln-daemon.
+type Server struct {
+	rblnexporterpb.UnimplementedRBLNExporterServer
+
+	cfg       Config
+	snapshots *collector.SnapshotStore
+	listener  net.Listener
+	tlsConfig *tls.Config
+	token     *server.BearerToken
+}
+
+// NewServer listens on the configured address right away so that an invalid
+// address fails startup. Addresses other than loopback and unix sockets are
+// refused unless both TLS and a bearer token are configured, because the
+// service otherwise exposes the devices and their pods to the network.
+func NewServer(cfg Config, snapshots *collector.SnapshotStore) (*Server, error) {
+	tlsConfig, err := server.TLSConfig(cfg.WebConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if !isLocalAddress(cfg.ListenAddress) && (tlsConfig == nil || cfg.BearerTokenFile == "") {
+		return nil, fmt.Errorf("grpc-listen-address %s is reachable from the network and requires tls_server_config in web-config-file and web-bearer-token-file; use a loopback address or a unix socket otherwise", cfg.ListenAddress)
+	}
+
+	l, err := server.Listen(cfg.ListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		cfg:       cfg,
+		snapshots: snapshots,
+		listener:  l,
+		tlsConfig: tlsConfig,
+	}
+	if cfg.BearerTokenFile != "" {
+		s.token = server.NewBearerToken(cfg.BearerTokenFile)
+	}
+	return s, nil
+}
+
+func (s *Server) Run(ctx context.Context) error {
+	var opts []grpc.ServerOption
+	if s.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	if s.token != nil {
+		opts = append(opts, grpc.UnaryInterceptor(s.authorizeUnary), grpc.StreamInterceptor(s.authorizeStream))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	rblnexporterpb.RegisterRBLNExporterServer(grpcServer, s)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(s.listener)
+	}()
+	slog.Info("grpc server listening", "address", s.cfg.ListenAddress, "tls", s.tlsConfig != nil, "bearer_token", s.token != nil)
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("grpc server stopped: %w", err)
+	case <-ctx.Done():
+	}
+
+	// Watch streams only end when their clients go away, so do not wait for them forever.
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(gracefulStopTimeout):
+		grpcServer.Stop()
+	}
+	return nil
+}
+
+func (s *Server) authorizeUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authorizeStream(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// authorize checks the bearer token in the authorization metadata, the gRPC
+// counterpart of the HTTP Authorization header.
+func (s *Server) authorize(ctx context.Context) error {
+	var got string
+	var ok bool
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		got, ok = strings.CutPrefix(values[0], "Bearer ")
+	}
+	valid, err := s.token.Verify(got)
+	if err != nil {
+		slog.Error("failed to read bearer token file", "file", s.token.File(), "err", err)
+		return status.Error(codes.Internal, "failed to read bearer token")
+	}
+	if !ok || !valid {
+		return status.Error(codes.Unauthenticated, "missing or invalid bearer token")
+	}
+	return nil
+}
+
+// isLocalAddress reports whether address is a unix socket or a loopback
+// host:port, which only processes on the node can reach.
+func isLocalAddress(address string) bool {
+	if strings.HasPrefix(address, "unix://") {
+		return true
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) GetSnapshot(_ context.Context, req *rblnexporterpb.SnapshotRequest) (*rblnexporterpb.Snapshot, error) {
+	snapshot, ok := s.snapshots.Latest()
+	if !ok {
+		return nil, status.Error(codes.Unavailable, "no collection has completed yet")
+	}
+	return toProto(snapshot, req.GetDevices()), nil
+}
+
+// WatchDevices sends the latest snapshot and then every published one. The
+// subscription keeps only the newest pending snapshot, so a slow client never
+// blocks collection.
+func (s *Server) WatchDevices(req *rblnexporterpb.SnapshotRequest, stream grpc.ServerStreamingServer[rblnexporterpb.Snapshot]) error {
+	updates, unsubscribe := s.snapshots.Subscribe()
+	defer unsubscribe()
+
+	if snapshot, ok := s.snapshots.Latest(); ok {
+		if err := stream.Send(toProto(snapshot, req.GetDevices())); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case snapshot := <-updates:
+			if err := stream.Send(toProto(snapshot, req.GetDevices())); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toProto(snapshot collector.Snapshot, devices []string) *rblnexporterpb.Snapshot {
+	list := restapi.NewDeviceList(snapshot).Filter(devices)
+
+	out := &rblnexporterpb.Snapshot{
+		Hostname:  list.Hostname,
+		Timestamp: timestamppb.New(list.Timestamp),
+		Devices:   make([]*rblnexporterpb.Device, 0, len(list.Devices)),
+	}
+	for _, d := range list.Devices {
+		device := &rblnexporterpb.Device{
+			Name:               d.Name,
+			Uuid:               d.UUID,
+			DeviceId:           d.DeviceID,
+			Card:               d.Card,
+			DriverVersion:      d.DriverVersion,
+			FirmwareVersion:    d.FirmwareVersion,
+			TemperatureCelsius: d.TemperatureCelsius,
+			PowerWatts:         d.PowerWatts,
+			MemoryUsedBytes:    d.MemoryUsedBytes,
+			MemoryTotalBytes:   d.MemoryTotalBytes,
+			UtilizationPercent: d.UtilizationPercent,
+			HealthStatus:       int32(d.HealthStatus),
+			Healthy:            d.Healthy,
+			Source:             d.Source,
+			Hostname:           d.Hostname,
+		}
+		for _, pod := range d.Pods {
+			device.Pods = append(device.Pods, &rblnexporterpb.Pod{
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				Container: pod.Container,
+			})
+		}
+		if len(device.Pods) > 0 {
+			device.Pod = device.Pods[0]
+		}
+		out.Devices = append(out.Devices, device)
+	}
+	return out
+}
@@ -0,0 +1,207 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/pkg/rblnexporterpb"
+)
+
+func TestIsLocalAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{address: "unix:///run/rbln/exporter.sock", want: true},
+		{address: "127.0.0.1:50061", want: true},
+		{address: "[::1]:50061", want: true},
+		{address: "localhost:50061", want: true},
+		{address: ":50061"},
+		{address: "0.0.0.0:50061"},
+		{address: "10.0.0.1:50061"},
+		{address: "node-1:50061"},
+	}
+	for _, tt := range tests {
+		if got := isLocalAddress(tt.address); got != tt.want {
+			t.Errorf("isLocalAddress(%q) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestNewServerRefusesNetworkAddressWithoutAuth(t *testing.T) {
+	dir := t.TempDir()
+	webConfig := writeWebConfig(t, dir)
+	token := writeFile(t, dir, "token", "secret")
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "no tls and no token", cfg: Config{ListenAddress: "0.0.0.0:0"}, wantErr: true},
+		{name: "token without tls", cfg: Config{ListenAddress: "0.0.0.0:0", BearerTokenFile: token}, wantErr: true},
+		{name: "tls without token", cfg: Config{ListenAddress: "0.0.0.0:0", WebConfigFile: webConfig}, wantErr: true},
+		{name: "tls and token", cfg: Config{ListenAddress: "0.0.0.0:0", WebConfigFile: webConfig, BearerTokenFile: token}},
+		{name: "loopback", cfg: Config{ListenAddress: "127.0.0.1:0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewServer(tt.cfg, collector.NewSnapshotStore())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewServer() error = %v, want error %v", err, tt.wantErr)
+			}
+			if s != nil {
+				_ = s.listener.Close()
+			}
+		})
+	}
+}
+
+func TestBearerTokenOverTLS(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewServer(Config{
+		ListenAddress:   "0.0.0.0:0",
+		WebConfigFile:   writeWebConfig(t, dir),
+		BearerTokenFile: writeFile(t, dir, "token", "secret\n"),
+	}, collector.NewSnapshotStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = s.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(readFile(t, filepath.Join(dir, "cert.pem")))
+	address := fmt.Sprintf("127.0.0.1:%d", s.listener.Addr().(*net.TCPAddr).Port)
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: roots})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := rblnexporterpb.NewRBLNExporterClient(conn)
+
+	tests := []struct {
+		name  string
+		token string
+		want  codes.Code
+	}{
+		{name: "missing token", want: codes.Unauthenticated},
+		{name: "invalid token", token: "wrong", want: codes.Unauthenticated},
+		// No collection has completed, so an authorized call gets past the interceptor only to find no snapshot.
+		{name: "valid token", token: "secret", want: codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tt.token)
+			}
+			_, err := client.GetSnapshot(ctx, &rblnexporterpb.SnapshotRequest{})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("GetSnapshot() code = %v, want %v (%v)", got, tt.want, err)
+			}
+
+			stream, err := client.WatchDevices(ctx, &rblnexporterpb.SnapshotRequest{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == codes.Unauthenticated {
+				if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+					t.Errorf("WatchDevices() error = %v, want %v", err, codes.Unauthenticated)
+				}
+			}
+		})
+	}
+
+	plain, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	callCtx, callCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer callCancel()
+	callCtx = metadata.AppendToOutgoingContext(callCtx, "authorization", "Bearer secret")
+	_, err = rblnexporterpb.NewRBLNExporterClient(plain).GetSnapshot(callCtx, &rblnexporterpb.SnapshotRequest{})
+	if err == nil || strings.Contains(status.Convert(err).Message(), "no collection") {
+		t.Errorf("plaintext GetSnapshot() reached the service: %v", err)
+	}
+}
+
+// writeWebConfig writes a self-signed certificate for 127.0.0.1 and a web
+// config file enabling TLS with it.
+func writeWebConfig(t *testing.T, dir string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "rbln-metrics-exporter"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "cert.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	writeFile(t, dir, "key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return writeFile(t, dir, "web-config.yml", "tls_server_config:\n  cert_file: cert.pem\n  key_file: key.pem\n")
+}
+
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
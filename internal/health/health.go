@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+
+	checkTimeout = 3 * time.Second
+)
+
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type response struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// LivenessHandler reports that the process is alive and serving HTTP.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, http.StatusOK, response{Status: statusOK})
+	})
+}
+
+type namedCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// Checker runs the registered readiness checks on every request.
+type Checker struct {
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+func (c *Checker) Add(name string, check func(context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	resp := response{Status: statusOK, Checks: make(map[string]checkResult, len(checks))}
+	for _, nc := range checks {
+		if err := nc.check(ctx); err != nil {
+			resp.Status = statusFail
+			resp.Checks[nc.name] = checkResult{Status: statusFail, Error: err.Error()}
+			continue
+		}
+		resp.Checks[nc.name] = checkResult{Status: statusOK}
+	}
+
+	code := http.StatusOK
+	if resp.Status != statusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeResponse(w, code, resp)
+}
+
+func writeResponse(w http.ResponseWriter, code int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,116 @@
+package kube
+
+import (
+	"context"
+)
+
+const (
+	tokenReviewPath          = "/apis/authentication.k8s.io/v1/tokenreviews"
+	subjectAccessReviewPath  = "/apis/authorization.k8s.io/v1/subjectaccessreviews"
+	authenticationAPIVersion = "authentication.k8s.io/v1"
+	authorizationAPIVersion  = "authorization.k8s.io/v1"
+)
+
+type UserInfo struct {
+	Username string              `json:"username,omitempty"`
+	UID      string              `json:"uid,omitempty"`
+	Groups   []string            `json:"groups,omitempty"`
+	Extra    map[string][]string `json:"extra,omitempty"`
+}
+
+type tokenReview struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Spec       tokenReviewSpec   `json:"spec"`
+	Status     tokenReviewStatus `json:"status,omitempty"`
+}
+
+type tokenReviewSpec struct {
+	Token     string   `json:"token"`
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+type tokenReviewStatus struct {
+	Authenticated bool     `json:"authenticated,omitempty"`
+	User          UserInfo `json:"user,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// ResourceAttributes describes a resource request, e.g. get nodes/metrics.
+type ResourceAttributes struct {
+	Namespace   string `json:"namespace,omitempty"`
+	Verb        string `json:"verb,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// NonResourceAttributes describes a request to a plain URL path, e.g. get /metrics.
+type NonResourceAttributes struct {
+	Path string `json:"path,omitempty"`
+	Verb string `json:"verb,omitempty"`
+}
+
+type subjectAccessReview struct {
+	APIVersion string                    `json:"apiVersion"`
+	Kind       string                    `json:"kind"`
+	Spec       subjectAccessReviewSpec   `json:"spec"`
+	Status     subjectAccessReviewStatus `json:"status,omitempty"`
+}
+
+type subjectAccessReviewSpec struct {
+	ResourceAttributes    *ResourceAttributes    `json:"resourceAttributes,omitempty"`
+	NonResourceAttributes *NonResourceAttributes `json:"nonResourceAttributes,omitempty"`
+	User                  string                 `json:"user,omitempty"`
+	Groups                []string               `json:"groups,omitempty"`
+	Extra                 map[string][]string    `json:"extra,omitempty"`
+	UID                   string                 `json:"uid,omitempty"`
+}
+
+type subjectAccessReviewStatus struct {
+	Allowed         bool   `json:"allowed"`
+	Denied          bool   `json:"denied,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+	EvaluationError string `json:"evaluationError,omitempty"`
+}
+
+// ReviewToken validates a bearer token with the TokenReview API. It returns
+// nil user info when the token is not authenticated.
+func (c *Client) ReviewToken(ctx context.Context, token string, audiences []string) (*UserInfo, error) {
+	review := tokenReview{
+		APIVersion: authenticationAPIVersion,
+		Kind:       "TokenReview",
+		Spec:       tokenReviewSpec{Token: token, Audiences: audiences},
+	}
+	var out tokenReview
+	if err := c.Create(ctx, tokenReviewPath, review, &out); err != nil {
+		return nil, err
+	}
+	if !out.Status.Authenticated {
+		return nil, nil
+	}
+	return &out.Status.User, nil
+}
+
+// ReviewAccess checks with the SubjectAccessReview API whether the user may
+// perform the request described by exactly one of res or nonRes.
+func (c *Client) ReviewAccess(ctx context.Context, user UserInfo, res *ResourceAttributes, nonRes *NonResourceAttributes) (bool, string, error) {
+	review := subjectAccessReview{
+		APIVersion: authorizationAPIVersion,
+		Kind:       "SubjectAccessReview",
+		Spec: subjectAccessReviewSpec{
+			ResourceAttributes:    res,
+			NonResourceAttributes: nonRes,
+			User:                  user.Username,
+			Groups:                user.Groups,
+			Extra:                 user.Extra,
+			UID:                   user.UID,
+		},
+	}
+	var out subjectAccessReview
+	if err := c.Create(ctx, subjectAccessReviewPath, review, &out); err != nil {
+		return false, "", err
+	}
+	return out.Status.Allowed && !out.Status.Denied, out.Status.Reason, nil
+}
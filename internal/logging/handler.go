@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Handler serves the log level. GET returns it, PUT or POST with a level
+// query parameter (e.g. ?level=debug) changes it until the next restart or
+// config reload. Changes are refused with 403 unless allowUpdates is set.
+func Handler(allowUpdates bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			if !allowUpdates {
+				http.Error(w, "changing the log level is disabled", http.StatusForbidden)
+				return
+			}
+			lvl, err := ParseLevel(r.URL.Query().Get("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			SetLevel(lvl)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"level": LevelName(Level())})
+	})
+}
+
+// ToggleDebugOnSignal switches between debug logging and the configured
+// level on every SIGUSR2.
+func ToggleDebugOnSignal(ctx context.Context, configured func() slog.Level) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR2)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			if Level() == slog.LevelDebug {
+				SetLevel(configured())
+			} else {
+				SetLevel(slog.LevelDebug)
+			}
+		}
+	}
+}
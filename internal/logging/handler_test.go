@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerUpdates(t *testing.T) {
+	SetLevel(slog.LevelInfo)
+	t.Cleanup(func() { SetLevel(slog.LevelInfo) })
+
+	tests := []struct {
+		name         string
+		allowUpdates bool
+		method       string
+		query        string
+		want         int
+		wantLevel    slog.Level
+	}{
+		{name: "read", method: http.MethodGet, want: http.StatusOK, wantLevel: slog.LevelInfo},
+		{name: "update disabled", method: http.MethodPut, query: "?level=debug", want: http.StatusForbidden, wantLevel: slog.LevelInfo},
+		{name: "invalid level", allowUpdates: true, method: http.MethodPut, query: "?level=loud", want: http.StatusBadRequest, wantLevel: slog.LevelInfo},
+		{name: "update enabled", allowUpdates: true, method: http.MethodPost, query: "?level=debug", want: http.StatusOK, wantLevel: slog.LevelDebug},
+		{name: "unsupported method", allowUpdates: true, method: http.MethodDelete, want: http.StatusMethodNotAllowed, wantLevel: slog.LevelDebug},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler(tt.allowUpdates).ServeHTTP(rec, httptest.NewRequest(tt.method, "/-/log-level"+tt.query, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := Level(); got != tt.wantLevel {
+				t.Errorf("level = %v, want %v", got, tt.wantLevel)
+			}
+		})
+	}
+}
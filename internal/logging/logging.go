@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// level is shared by every logger created by Setup, so that it can be changed
+// at runtime.
+var level = new(slog.LevelVar)
+
+// Setup installs the default logger. Repeated warnings and errors are rate
+// limited, see repeatInterval, and the most recent records are kept for
+// RecentLogs.
+func Setup(w io.Writer, lvl slog.Level, format string) {
+	level.Set(lvl)
+	w = io.MultiWriter(w, recent)
+	opts := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: true,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Value = slog.StringValue(a.Value.Time().Format(time.RFC3339))
+			}
+			return a
+		},
+	}
+
+	var handler slog.Handler
+	if format == FormatText {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(newRateLimitHandler(handler, repeatInterval)))
+}
+
+// Level returns the current log level.
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetLevel changes the level of the default logger.
+func SetLevel(lvl slog.Level) {
+	if lvl != level.Level() {
+		level.Set(lvl)
+		// Log at the new level when it is above info so the change is visible.
+		slog.Log(context.Background(), max(lvl, slog.LevelInfo), "log level changed", "level", LevelName(lvl))
+	}
+}
+
+// LevelName returns the level in the format accepted by ParseLevel.
+func LevelName(lvl slog.Level) string {
+	return strings.ToLower(lvl.String())
+}
+
+// ParseLevel parses debug, info, warn (or warning) and error.
+func ParseLevel(s string) (slog.Level, error) {
+	if strings.EqualFold(s, "warning") {
+		return slog.LevelWarn, nil
+	}
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("log level must be one of debug, info, warn, error, got %q", s)
+	}
+	return lvl, nil
+}
@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repeatInterval is how long an identical warning or error is suppressed
+// after it was logged.
+const repeatInterval = time.Minute
+
+type repeatState struct {
+	logged     time.Time
+	suppressed int
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	repeats map[string]*repeatState
+}
+
+// allow reports whether a record with the key should be logged and how many
+// identical records were dropped since the key was last logged.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if state, ok := l.repeats[key]; ok && now.Sub(state.logged) < l.window {
+		state.suppressed++
+		return false, 0
+	}
+
+	// Forget expired keys so that varying messages do not accumulate.
+	if len(l.repeats) > 1000 {
+		for k, state := range l.repeats {
+			if now.Sub(state.logged) >= l.window {
+				delete(l.repeats, k)
+			}
+		}
+	}
+
+	var suppressed int
+	if state, ok := l.repeats[key]; ok {
+		suppressed = state.suppressed
+	}
+	l.repeats[key] = &repeatState{logged: now}
+	return true, suppressed
+}
+
+// rateLimitHandler drops warnings and errors that repeat within the window.
+// Records are identical when level, message and attributes match; the err
+// attribute is ignored because error texts often carry changing details.
+type rateLimitHandler struct {
+	slog.Handler
+	limiter *rateLimiter
+	prefix  string
+}
+
+func newRateLimitHandler(h slog.Handler, window time.Duration) *rateLimitHandler {
+	return &rateLimitHandler{
+		Handler: h,
+		limiter: &rateLimiter{window: window, repeats: make(map[string]*repeatState)},
+	}
+}
+
+func (h *rateLimitHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	var key strings.Builder
+	fmt.Fprintf(&key, "%s|%s|%s", r.Level, h.prefix, r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key != "err" {
+			fmt.Fprintf(&key, "|%s=%s", a.Key, a.Value)
+		}
+		return true
+	})
+
+	ok, suppressed := h.limiter.allow(key.String(), r.Time)
+	if !ok {
+		return nil
+	}
+	if suppressed > 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Int("suppressed", suppressed))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *rateLimitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &rateLimitHandler{
+		Handler: h.Handler.WithAttrs(attrs),
+		limiter: h.limiter,
+		prefix:  fmt.Sprintf("%s%v", h.prefix, attrs),
+	}
+}
+
+func (h *rateLimitHandler) WithGroup(name string) slog.Handler {
+	return &rateLimitHandler{
+		Handler: h.Handler.WithGroup(name),
+		limiter: h.limiter,
+		prefix:  h.prefix + name + ".",
+	}
+}
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"net/url"
+)
+
+// credentialParams are URL query parameters that carry credentials, such as
+// the user and password of the InfluxDB 1.x /write endpoint.
+var credentialParams = []string{"u", "p"}
+
+// RedactURL returns raw without credentials so that it can be logged or
+// written to a support bundle: the userinfo password is masked and the
+// credential query parameters are removed. A URL that cannot be parsed is
+// replaced entirely.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "<redacted>"
+	}
+	query := u.Query()
+	stripped := false
+	for _, name := range credentialParams {
+		if query.Has(name) {
+			query.Del(name)
+			stripped = true
+		}
+	}
+	if stripped {
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
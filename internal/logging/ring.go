@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"bytes"
+	"sync"
+)
+
+// ringSize is the number of log records kept for support bundles.
+const ringSize = 2000
+
+// ring keeps the most recent log records in memory.
+type ring struct {
+	mu      sync.Mutex
+	records [][]byte
+	next    int
+}
+
+var recent = &ring{records: make([][]byte, 0, ringSize)}
+
+// Write stores one record; slog handlers write each record with a single call.
+func (r *ring) Write(p []byte) (int, error) {
+	record := bytes.Clone(p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) < ringSize {
+		r.records = append(r.records, record)
+	} else {
+		r.records[r.next] = record
+		r.next = (r.next + 1) % ringSize
+	}
+	return len(p), nil
+}
+
+// RecentLogs returns the most recent log records, oldest first, in the
+// configured log format.
+func RecentLogs() []byte {
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+
+	var buf bytes.Buffer
+	for i := range recent.records {
+		buf.Write(recent.records[(recent.next+i)%len(recent.records)])
+	}
+	return buf.Bytes()
+}
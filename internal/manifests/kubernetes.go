@@ -0,0 +1,259 @@
+package manifests
+
+// The types below cover the subset of the Kubernetes API the generated
+// manifests use.
+
+type object struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Metadata   metadata `yaml:"metadata"`
+}
+
+type metadata struct {
+	Name        string            `yaml:"name,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type labelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type serviceAccount struct {
+	object                       `yaml:",inline"`
+	AutomountServiceAccountToken bool `yaml:"automountServiceAccountToken"`
+}
+
+type configMap struct {
+	object `yaml:",inline"`
+	Data   map[string]string `yaml:"data"`
+}
+
+type clusterRole struct {
+	object `yaml:",inline"`
+	Rules  []policyRule `yaml:"rules"`
+}
+
+type policyRule struct {
+	APIGroups []string `yaml:"apiGroups"`
+	Resources []string `yaml:"resources"`
+	Verbs     []string `yaml:"verbs"`
+}
+
+type clusterRoleBinding struct {
+	object   `yaml:",inline"`
+	RoleRef  roleRef   `yaml:"roleRef"`
+	Subjects []subject `yaml:"subjects"`
+}
+
+type roleRef struct {
+	APIGroup string `yaml:"apiGroup"`
+	Kind     string `yaml:"kind"`
+	Name     string `yaml:"name"`
+}
+
+type subject struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type daemonSet struct {
+	object `yaml:",inline"`
+	Spec   daemonSetSpec `yaml:"spec"`
+}
+
+type daemonSetSpec struct {
+	Selector labelSelector   `yaml:"selector"`
+	Template podTemplateSpec `yaml:"template"`
+}
+
+type podTemplateSpec struct {
+	Metadata metadata `yaml:"metadata"`
+	Spec     podSpec  `yaml:"spec"`
+}
+
+type podSpec struct {
+	ServiceAccountName            string             `yaml:"serviceAccountName"`
+	AutomountServiceAccountToken  bool               `yaml:"automountServiceAccountToken"`
+	HostNetwork                   bool               `yaml:"hostNetwork,omitempty"`
+	DNSPolicy                     string             `yaml:"dnsPolicy,omitempty"`
+	NodeSelector                  map[string]string  `yaml:"nodeSelector,omitempty"`
+	Tolerations                   []Toleration       `yaml:"tolerations,omitempty"`
+	SecurityContext               podSecurityContext `yaml:"securityContext"`
+	Containers                    []container        `yaml:"containers"`
+	Volumes                       []volume           `yaml:"volumes"`
+	TerminationGracePeriodSeconds int                `yaml:"terminationGracePeriodSeconds"`
+}
+
+// Toleration lets the exporter run on nodes with matching taints. An empty
+// Key with the Exists operator tolerates every taint.
+type Toleration struct {
+	Key      string `yaml:"key,omitempty"`
+	Operator string `yaml:"operator"`
+	Value    string `yaml:"value,omitempty"`
+	Effect   string `yaml:"effect,omitempty"`
+}
+
+type podSecurityContext struct {
+	RunAsUser      int            `yaml:"runAsUser"`
+	RunAsGroup     int            `yaml:"runAsGroup"`
+	RunAsNonRoot   bool           `yaml:"runAsNonRoot"`
+	SeccompProfile seccompProfile `yaml:"seccompProfile"`
+}
+
+type seccompProfile struct {
+	Type string `yaml:"type"`
+}
+
+type container struct {
+	Name            string               `yaml:"name"`
+	Image           string               `yaml:"image"`
+	SecurityContext securityContext      `yaml:"securityContext"`
+	Env             []envVar             `yaml:"env"`
+	Ports           []containerPort      `yaml:"ports"`
+	LivenessProbe   probe                `yaml:"livenessProbe"`
+	ReadinessProbe  probe                `yaml:"readinessProbe"`
+	VolumeMounts    []volumeMount        `yaml:"volumeMounts"`
+	Resources       resourceRequirements `yaml:"resources"`
+}
+
+type securityContext struct {
+	Privileged               bool         `yaml:"privileged"`
+	AllowPrivilegeEscalation bool         `yaml:"allowPrivilegeEscalation"`
+	ReadOnlyRootFilesystem   bool         `yaml:"readOnlyRootFilesystem"`
+	Capabilities             capabilities `yaml:"capabilities"`
+}
+
+type capabilities struct {
+	Drop []string `yaml:"drop"`
+}
+
+type envVar struct {
+	Name      string        `yaml:"name"`
+	Value     string        `yaml:"value,omitempty"`
+	ValueFrom *envVarSource `yaml:"valueFrom,omitempty"`
+}
+
+type envVarSource struct {
+	FieldRef fieldRef `yaml:"fieldRef"`
+}
+
+type fieldRef struct {
+	FieldPath string `yaml:"fieldPath"`
+}
+
+type containerPort struct {
+	Name          string `yaml:"name"`
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol"`
+}
+
+type probe struct {
+	HTTPGet             httpGetAction `yaml:"httpGet"`
+	InitialDelaySeconds int           `yaml:"initialDelaySeconds"`
+	PeriodSeconds       int           `yaml:"periodSeconds"`
+	FailureThreshold    int           `yaml:"failureThreshold,omitempty"`
+}
+
+type httpGetAction struct {
+	Path string `yaml:"path"`
+	Port string `yaml:"port"`
+}
+
+type volumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+}
+
+type resourceRequirements struct {
+	Requests map[string]string `yaml:"requests"`
+	Limits   map[string]string `yaml:"limits"`
+}
+
+type volume struct {
+	Name      string                `yaml:"name"`
+	HostPath  *hostPathVolumeSource `yaml:"hostPath,omitempty"`
+	ConfigMap *configMapVolume      `yaml:"configMap,omitempty"`
+	EmptyDir  *emptyDirVolume       `yaml:"emptyDir,omitempty"`
+}
+
+type hostPathVolumeSource struct {
+	Path string `yaml:"path"`
+	Type string `yaml:"type"`
+}
+
+type configMapVolume struct {
+	Name string `yaml:"name"`
+}
+
+type emptyDirVolume struct {
+	SizeLimit string `yaml:"sizeLimit,omitempty"`
+}
+
+type service struct {
+	object `yaml:",inline"`
+	Spec   serviceSpec `yaml:"spec"`
+}
+
+type serviceSpec struct {
+	Selector map[string]string `yaml:"selector"`
+	Ports    []servicePort     `yaml:"ports"`
+}
+
+type servicePort struct {
+	Name       string `yaml:"name"`
+	Port       int    `yaml:"port"`
+	Protocol   string `yaml:"protocol"`
+	TargetPort string `yaml:"targetPort"`
+}
+
+type serviceMonitor struct {
+	object `yaml:",inline"`
+	Spec   serviceMonitorSpec `yaml:"spec"`
+}
+
+type serviceMonitorSpec struct {
+	Selector          labelSelector     `yaml:"selector"`
+	NamespaceSelector namespaceSelector `yaml:"namespaceSelector"`
+	Endpoints         []endpoint        `yaml:"endpoints"`
+}
+
+type namespaceSelector struct {
+	MatchNames []string `yaml:"matchNames"`
+}
+
+type endpoint struct {
+	Port            string `yaml:"port"`
+	Path            string `yaml:"path"`
+	Interval        string `yaml:"interval"`
+	ScrapeTimeout   string `yaml:"scrapeTimeout"`
+	BearerTokenFile string `yaml:"bearerTokenFile,omitempty"`
+}
+
+type networkPolicy struct {
+	object `yaml:",inline"`
+	Spec   networkPolicySpec `yaml:"spec"`
+}
+
+type networkPolicySpec struct {
+	PodSelector labelSelector       `yaml:"podSelector"`
+	PolicyTypes []string            `yaml:"policyTypes"`
+	Ingress     []networkPolicyRule `yaml:"ingress"`
+}
+
+type networkPolicyRule struct {
+	From  []networkPolicyPeer `yaml:"from"`
+	Ports []networkPolicyPort `yaml:"ports"`
+}
+
+type networkPolicyPeer struct {
+	NamespaceSelector labelSelector `yaml:"namespaceSelector"`
+}
+
+type networkPolicyPort struct {
+	Protocol string `yaml:"protocol"`
+	Port     int    `yaml:"port"`
+}
@@ -0,0 +1,400 @@
+// Package manifests renders the Kubernetes resources that deploy the exporter
+// as a DaemonSet.
+package manifests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"go.yaml.in/yaml/v2"
+)
+
+const (
+	// TransportNodeIP connects to the daemon on the node's IP address.
+	TransportNodeIP = "node-ip"
+	// TransportHostNetwork runs the exporter in the host network namespace,
+	// so that it reaches a daemon listening on the node's loopback address.
+	TransportHostNetwork = "host-network"
+	// TransportUnix mounts the directory of the daemon's unix socket.
+	TransportUnix = "unix"
+
+	// NodeSelectorDeploy schedules on nodes labeled for the exporter.
+	NodeSelectorDeploy = "deploy"
+	// NodeSelectorPresent schedules on nodes labeled by rbln-npu-feature-discovery.
+	NodeSelectorPresent = "present"
+	// NodeSelectorNone schedules on every node.
+	NodeSelectorNone = "none"
+
+	nameLabel      = "app.kubernetes.io/name"
+	versionLabel   = "app.kubernetes.io/version"
+	containerName  = "metrics-exporter"
+	portName       = "metrics"
+	configDir      = "/etc/rbln-metrics-exporter"
+	configFileName = "config.yaml"
+
+	podResourcesDir = "/var/lib/kubelet/pod-resources"
+	sysfsDir        = "/sys"
+	tmpDir          = "/tmp"
+
+	// nobody is the user the exporter runs as when it needs no host sockets.
+	nobody = 65534
+
+	// maxScrapeTimeout is the scrape timeout of the ServiceMonitor, unless
+	// the scrape interval is shorter.
+	maxScrapeTimeout = 10 * time.Second
+)
+
+var nodeSelectorLabels = map[string]string{
+	NodeSelectorDeploy:  "rebellions.ai/npu.deploy.metrics-exporter",
+	NodeSelectorPresent: "rebellions.ai/npu.present",
+}
+
+func Transports() []string {
+	return []string{TransportNodeIP, TransportHostNetwork, TransportUnix}
+}
+
+func NodeSelectors() []string {
+	return []string{NodeSelectorDeploy, NodeSelectorPresent, NodeSelectorNone}
+}
+
+// Options describe the deployment. Exporter settings that have no dedicated
+// option are passed in Config, a config file mounted from a ConfigMap.
+type Options struct {
+	Name      string
+	Namespace string
+	// ServiceName is the name of the Service, which differs from Name to
+	// keep the name of earlier releases.
+	ServiceName string
+	Image       string
+	// Version labels the resources; it is usually the image tag.
+	Version string
+
+	// Transport and DaemonURL select how the exporter reaches the daemon.
+	// With TransportNodeIP only the port of DaemonURL is used. DaemonURL is
+	// empty when the daemons are configured as endpoints in Config.
+	Transport string
+	DaemonURL string
+
+	KubernetesMode string
+	// PodResources mounts the kubelet pod-resources socket.
+	PodResources bool
+	NodeSelector string
+	Tolerations  []Toleration
+	Port         int
+	Config       []byte
+
+	// KubeAuth grants the exporter the TokenReview and SubjectAccessReview
+	// permissions of --kube-auth.
+	KubeAuth bool
+	// ServiceMonitor adds a Prometheus Operator ServiceMonitor with
+	// ServiceMonitorLabels and the ScrapeInterval.
+	ServiceMonitor       bool
+	ServiceMonitorLabels map[string]string
+	ScrapeInterval       string
+	// NetworkPolicy only admits scrapes from PrometheusNamespace. It is left
+	// out with TransportHostNetwork, as pod selectors do not apply to pods
+	// in the host network.
+	NetworkPolicy       bool
+	PrometheusNamespace string
+}
+
+func (o Options) validate() error {
+	switch o.Transport {
+	case TransportNodeIP, TransportHostNetwork:
+		if o.DaemonURL != "" && strings.HasPrefix(o.DaemonURL, "unix:") {
+			return fmt.Errorf("daemon transport %s needs a host:port daemon URL, got %q", o.Transport, o.DaemonURL)
+		}
+	case TransportUnix:
+		if o.DaemonURL != "" && !strings.HasPrefix(o.DaemonURL, "unix://") {
+			return fmt.Errorf("daemon transport %s needs a unix:///path daemon URL, got %q", o.Transport, o.DaemonURL)
+		}
+	default:
+		return fmt.Errorf("invalid daemon transport %q: must be one of %s", o.Transport, strings.Join(Transports(), ", "))
+	}
+	if _, ok := nodeSelectorLabels[o.NodeSelector]; !ok && o.NodeSelector != NodeSelectorNone {
+		return fmt.Errorf("invalid node selector %q: must be one of %s", o.NodeSelector, strings.Join(NodeSelectors(), ", "))
+	}
+	if o.Name == "" || o.Namespace == "" || o.ServiceName == "" || o.Image == "" {
+		return fmt.Errorf("name, namespace, service name and image must not be empty")
+	}
+	if o.ServiceMonitor {
+		if _, err := o.scrapeTimeout(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NetworkPolicySkipped reports whether the NetworkPolicy is requested but
+// left out because of the daemon transport.
+func (o Options) NetworkPolicySkipped() bool {
+	return o.NetworkPolicy && o.Transport == TransportHostNetwork
+}
+
+// scrapeTimeout returns the scrape timeout of the ServiceMonitor, which
+// must not exceed the scrape interval.
+func (o Options) scrapeTimeout() (string, error) {
+	interval, err := model.ParseDuration(o.ScrapeInterval)
+	if err != nil || interval <= 0 {
+		return "", fmt.Errorf("invalid scrape interval %q: must be a positive duration such as 30s", o.ScrapeInterval)
+	}
+	return model.Duration(min(maxScrapeTimeout, time.Duration(interval))).String(), nil
+}
+
+// Write renders the resources as a multi-document YAML stream.
+func Write(w io.Writer, o Options) error {
+	if err := o.validate(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for i, resource := range resources(o) {
+		data, err := yaml.Marshal(resource)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func resources(o Options) []any {
+	out := []any{serviceAccount{object: o.object("v1", "ServiceAccount", o.Name)}}
+	if o.KubeAuth {
+		out = append(out, o.clusterRole(), o.clusterRoleBinding())
+	}
+	if len(o.Config) > 0 {
+		out = append(out, configMap{
+			object: o.object("v1", "ConfigMap", o.Name),
+			Data:   map[string]string{configFileName: string(o.Config)},
+		})
+	}
+	out = append(out, o.daemonSet(), o.service())
+	if o.ServiceMonitor {
+		out = append(out, o.serviceMonitor())
+	}
+	if o.NetworkPolicy && !o.NetworkPolicySkipped() {
+		out = append(out, o.networkPolicy())
+	}
+	return out
+}
+
+func (o Options) selector() map[string]string {
+	return map[string]string{nameLabel: o.Name}
+}
+
+func (o Options) labels() map[string]string {
+	labels := o.selector()
+	if o.Version != "" {
+		labels[versionLabel] = o.Version
+	}
+	return labels
+}
+
+func (o Options) object(apiVersion, kind, name string) object {
+	return object{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Metadata:   metadata{Name: name, Namespace: o.Namespace, Labels: o.labels()},
+	}
+}
+
+func (o Options) clusterRole() clusterRole {
+	role := clusterRole{
+		object: o.object("rbac.authorization.k8s.io/v1", "ClusterRole", o.Name),
+		Rules: []policyRule{
+			{APIGroups: []string{"authentication.k8s.io"}, Resources: []string{"tokenreviews"}, Verbs: []string{"create"}},
+			{APIGroups: []string{"authorization.k8s.io"}, Resources: []string{"subjectaccessreviews"}, Verbs: []string{"create"}},
+		},
+	}
+	role.Metadata.Namespace = ""
+	return role
+}
+
+func (o Options) clusterRoleBinding() clusterRoleBinding {
+	binding := clusterRoleBinding{
+		object:   o.object("rbac.authorization.k8s.io/v1", "ClusterRoleBinding", o.Name),
+		RoleRef:  roleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: o.Name},
+		Subjects: []subject{{Kind: "ServiceAccount", Name: o.Name, Namespace: o.Namespace}},
+	}
+	binding.Metadata.Namespace = ""
+	return binding
+}
+
+// runsAsRoot reports whether the exporter has to connect to host sockets,
+// which only root may use.
+func (o Options) runsAsRoot() bool {
+	return o.PodResources || (o.Transport == TransportUnix && o.DaemonURL != "")
+}
+
+func (o Options) daemonSet() daemonSet {
+	env := []envVar{
+		{Name: "NODE_NAME", ValueFrom: &envVarSource{FieldRef: fieldRef{FieldPath: "spec.nodeName"}}},
+		{Name: "RBLN_METRICS_EXPORTER_KUBERNETES_MODE", Value: o.KubernetesMode},
+		{Name: "RBLN_METRICS_EXPORTER_PORT", Value: strconv.Itoa(o.Port)},
+	}
+	mounts := []volumeMount{
+		{Name: "sysfs", MountPath: sysfsDir, ReadOnly: true},
+		// The root filesystem is read-only; support bundles are written here.
+		{Name: "tmp", MountPath: tmpDir},
+	}
+	volumes := []volume{
+		{Name: "sysfs", HostPath: &hostPathVolumeSource{Path: sysfsDir, Type: "Directory"}},
+		{Name: "tmp", EmptyDir: &emptyDirVolume{SizeLimit: "64Mi"}},
+	}
+	spec := podSpec{
+		ServiceAccountName:            o.Name,
+		AutomountServiceAccountToken:  o.KubeAuth,
+		Tolerations:                   o.Tolerations,
+		TerminationGracePeriodSeconds: 5,
+	}
+	if label, ok := nodeSelectorLabels[o.NodeSelector]; ok {
+		spec.NodeSelector = map[string]string{label: "true"}
+	}
+
+	if o.DaemonURL != "" {
+		switch o.Transport {
+		case TransportNodeIP:
+			port := "50051"
+			if _, p, err := net.SplitHostPort(o.DaemonURL); err == nil {
+				port = p
+			}
+			env = append(env,
+				envVar{Name: "NODE_IP", ValueFrom: &envVarSource{FieldRef: fieldRef{FieldPath: "status.hostIP"}}},
+				envVar{Name: "RBLN_METRICS_EXPORTER_RBLN_DAEMON_URL", Value: "$(NODE_IP):" + port},
+			)
+		case TransportHostNetwork:
+			spec.HostNetwork = true
+			spec.DNSPolicy = "ClusterFirstWithHostNet"
+			env = append(env, envVar{Name: "RBLN_METRICS_EXPORTER_RBLN_DAEMON_URL", Value: o.DaemonURL})
+		case TransportUnix:
+			dir := path.Dir(strings.TrimPrefix(o.DaemonURL, "unix://"))
+			mounts = append(mounts, volumeMount{Name: "rbln-daemon", MountPath: dir})
+			volumes = append(volumes, volume{Name: "rbln-daemon", HostPath: &hostPathVolumeSource{Path: dir, Type: "Directory"}})
+			env = append(env, envVar{Name: "RBLN_METRICS_EXPORTER_RBLN_DAEMON_URL", Value: o.DaemonURL})
+		}
+	}
+	if o.PodResources {
+		mounts = append(mounts, volumeMount{Name: "pod-resources", MountPath: podResourcesDir, ReadOnly: true})
+		volumes = append(volumes, volume{Name: "pod-resources", HostPath: &hostPathVolumeSource{Path: podResourcesDir, Type: "Directory"}})
+	}
+	if len(o.Config) > 0 {
+		env = append(env, envVar{Name: "RBLN_METRICS_EXPORTER_CONFIG_FILE", Value: path.Join(configDir, configFileName)})
+		mounts = append(mounts, volumeMount{Name: "config", MountPath: configDir, ReadOnly: true})
+		volumes = append(volumes, volume{Name: "config", ConfigMap: &configMapVolume{Name: o.Name}})
+	}
+
+	spec.SecurityContext = podSecurityContext{
+		RunAsUser:      nobody,
+		RunAsGroup:     nobody,
+		RunAsNonRoot:   true,
+		SeccompProfile: seccompProfile{Type: "RuntimeDefault"},
+	}
+	if o.runsAsRoot() {
+		spec.SecurityContext.RunAsUser = 0
+		spec.SecurityContext.RunAsGroup = 0
+		spec.SecurityContext.RunAsNonRoot = false
+	}
+	spec.Containers = []container{{
+		Name:  containerName,
+		Image: o.Image,
+		SecurityContext: securityContext{
+			ReadOnlyRootFilesystem: true,
+			Capabilities:           capabilities{Drop: []string{"ALL"}},
+		},
+		Env:   env,
+		Ports: []containerPort{{Name: portName, ContainerPort: o.Port, Protocol: "TCP"}},
+		LivenessProbe: probe{
+			HTTPGet:             httpGetAction{Path: "/healthz", Port: portName},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+		},
+		ReadinessProbe: probe{
+			HTTPGet:             httpGetAction{Path: "/readyz", Port: portName},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       10,
+			FailureThreshold:    3,
+		},
+		VolumeMounts: mounts,
+		Resources: resourceRequirements{
+			Requests: map[string]string{"cpu": "250m", "memory": "40Mi"},
+			Limits:   map[string]string{"cpu": "1", "memory": "200Mi"},
+		},
+	}}
+	spec.Volumes = volumes
+
+	return daemonSet{
+		object: o.object("apps/v1", "DaemonSet", o.Name),
+		Spec: daemonSetSpec{
+			Selector: labelSelector{MatchLabels: o.selector()},
+			Template: podTemplateSpec{Metadata: metadata{Labels: o.labels()}, Spec: spec},
+		},
+	}
+}
+
+func (o Options) service() service {
+	svc := service{
+		object: o.object("v1", "Service", o.ServiceName),
+		Spec: serviceSpec{
+			Selector: o.selector(),
+			Ports:    []servicePort{{Name: portName, Port: o.Port, Protocol: "TCP", TargetPort: portName}},
+		},
+	}
+	svc.Metadata.Annotations = map[string]string{
+		"prometheus.io/scrape": "true",
+		"prometheus.io/path":   "/metrics",
+		"prometheus.io/port":   strconv.Itoa(o.Port),
+	}
+	return svc
+}
+
+func (o Options) serviceMonitor() serviceMonitor {
+	// validate has checked the interval.
+	scrapeTimeout, _ := o.scrapeTimeout()
+	sm := serviceMonitor{
+		object: o.object("monitoring.coreos.com/v1", "ServiceMonitor", o.Name),
+		Spec: serviceMonitorSpec{
+			Selector:          labelSelector{MatchLabels: o.selector()},
+			NamespaceSelector: namespaceSelector{MatchNames: []string{o.Namespace}},
+			Endpoints: []endpoint{{
+				Port:          portName,
+				Path:          "/metrics",
+				Interval:      o.ScrapeInterval,
+				ScrapeTimeout: scrapeTimeout,
+			}},
+		},
+	}
+	for k, v := range o.ServiceMonitorLabels {
+		sm.Metadata.Labels[k] = v
+	}
+	if o.KubeAuth {
+		sm.Spec.Endpoints[0].BearerTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	}
+	return sm
+}
+
+func (o Options) networkPolicy() networkPolicy {
+	return networkPolicy{
+		object: o.object("networking.k8s.io/v1", "NetworkPolicy", o.Name),
+		Spec: networkPolicySpec{
+			PodSelector: labelSelector{MatchLabels: o.selector()},
+			PolicyTypes: []string{"Ingress"},
+			Ingress: []networkPolicyRule{{
+				From: []networkPolicyPeer{{NamespaceSelector: labelSelector{
+					MatchLabels: map[string]string{"kubernetes.io/metadata.name": o.PrometheusNamespace},
+				}}},
+				Ports: []networkPolicyPort{{Protocol: "TCP", Port: o.Port}},
+			}},
+		},
+	}
+}
@@ -0,0 +1,209 @@
+package otlp
+
+import (
+	"math"
+
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+)
+
+const scopeName = "github.com/rebellions-sw/rbln-metrics-exporter"
+
+// ucumUnits are the UCUM codes of the collectors' units.
+var ucumUnits = map[string]string{
+	collector.UnitCelsius: "Cel",
+	collector.UnitWatts:   "W",
+	collector.UnitBytes:   "By",
+	collector.UnitPercent: "%",
+	collector.UnitSeconds: "s",
+}
+
+// Prometheus labels that are lifted from data points into resource attributes.
+const (
+	hostnameLabel  = "hostname"
+	namespaceLabel = "namespace"
+	podLabel       = "pod"
+)
+
+type resourceKey struct {
+	hostname  string
+	namespace string
+	pod       string
+}
+
+type resourceBuilder struct {
+	nodeName     string
+	isKubernetes bool
+	startTime    uint64
+}
+
+// buildResourceMetrics converts gathered metric families to OTLP. Data points are
+// grouped into one resource per owning pod so that collectors can apply k8s
+// resource processors; devices without an owner share the node resource.
+func (b resourceBuilder) buildResourceMetrics(families []*dto.MetricFamily, now uint64) []*metricspb.ResourceMetrics {
+	type resourceEntry struct {
+		metrics map[string]*metricspb.Metric
+		order   []string
+	}
+	resources := make(map[resourceKey]*resourceEntry)
+	var keys []resourceKey
+
+	for _, mf := range families {
+		switch mf.GetType() {
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED, dto.MetricType_COUNTER, dto.MetricType_HISTOGRAM:
+		default:
+			// Summaries have no OTLP equivalent that receivers handle well.
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			key, attrs := b.splitLabels(m.GetLabel())
+			entry, ok := resources[key]
+			if !ok {
+				entry = &resourceEntry{metrics: make(map[string]*metricspb.Metric)}
+				resources[key] = entry
+				keys = append(keys, key)
+			}
+
+			metric, ok := entry.metrics[mf.GetName()]
+			if !ok {
+				metric = newMetric(mf)
+				entry.metrics[mf.GetName()] = metric
+				entry.order = append(entry.order, mf.GetName())
+			}
+			b.appendDataPoint(metric, mf.GetType(), m, attrs, now)
+		}
+	}
+
+	out := make([]*metricspb.ResourceMetrics, 0, len(keys))
+	for _, key := range keys {
+		entry := resources[key]
+		metrics := make([]*metricspb.Metric, 0, len(entry.order))
+		for _, name := range entry.order {
+			metrics = append(metrics, entry.metrics[name])
+		}
+		out = append(out, &metricspb.ResourceMetrics{
+			Resource: &resourcepb.Resource{Attributes: b.resourceAttributes(key)},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: scopeName},
+				Metrics: metrics,
+			}},
+		})
+	}
+	return out
+}
+
+func (b resourceBuilder) splitLabels(labels []*dto.LabelPair) (resourceKey, []*commonpb.KeyValue) {
+	key := resourceKey{hostname: b.nodeName}
+	attrs := make([]*commonpb.KeyValue, 0, len(labels))
+	for _, lp := range labels {
+		switch lp.GetName() {
+		case hostnameLabel:
+			key.hostname = lp.GetValue()
+		case namespaceLabel:
+			key.namespace = lp.GetValue()
+		case podLabel:
+			key.pod = lp.GetValue()
+		default:
+			attrs = append(attrs, stringAttr(lp.GetName(), lp.GetValue()))
+		}
+	}
+	return key, attrs
+}
+
+func (b resourceBuilder) resourceAttributes(key resourceKey) []*commonpb.KeyValue {
+	attrs := []*commonpb.KeyValue{
+		stringAttr("service.name", "rbln-metrics-exporter"),
+		stringAttr("host.name", key.hostname),
+	}
+	if b.isKubernetes {
+		attrs = append(attrs, stringAttr("k8s.node.name", b.nodeName))
+	}
+	if key.pod != "" {
+		attrs = append(attrs,
+			stringAttr("k8s.pod.name", key.pod),
+			stringAttr("k8s.namespace.name", key.namespace),
+		)
+	}
+	return attrs
+}
+
+func newMetric(mf *dto.MetricFamily) *metricspb.Metric {
+	metric := &metricspb.Metric{
+		Name:        mf.GetName(),
+		Description: mf.GetHelp(),
+		Unit:        ucumUnits[collector.UnitFromHelp(mf.GetHelp())],
+	}
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		metric.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+	case dto.MetricType_HISTOGRAM:
+		metric.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}}
+	default:
+		metric.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+	}
+	return metric
+}
+
+func (b resourceBuilder) appendDataPoint(metric *metricspb.Metric, typ dto.MetricType, m *dto.Metric, attrs []*commonpb.KeyValue, now uint64) {
+	if typ == dto.MetricType_HISTOGRAM {
+		metric.GetHistogram().DataPoints = append(metric.GetHistogram().DataPoints, b.histogramDataPoint(m.GetHistogram(), attrs, now))
+		return
+	}
+	dp := &metricspb.NumberDataPoint{
+		Attributes:   attrs,
+		TimeUnixNano: now,
+	}
+	switch typ {
+	case dto.MetricType_GAUGE:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: m.GetGauge().GetValue()}
+		metric.GetGauge().DataPoints = append(metric.GetGauge().DataPoints, dp)
+	case dto.MetricType_UNTYPED:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: m.GetUntyped().GetValue()}
+		metric.GetGauge().DataPoints = append(metric.GetGauge().DataPoints, dp)
+	case dto.MetricType_COUNTER:
+		dp.StartTimeUnixNano = b.startTime
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: m.GetCounter().GetValue()}
+		metric.GetSum().DataPoints = append(metric.GetSum().DataPoints, dp)
+	}
+}
+
+// histogramDataPoint converts the cumulative buckets of a Prometheus
+// histogram to the per-bucket counts of OTLP. The +Inf bucket is implied by
+// the count.
+func (b resourceBuilder) histogramDataPoint(h *dto.Histogram, attrs []*commonpb.KeyValue, now uint64) *metricspb.HistogramDataPoint {
+	sum := h.GetSampleSum()
+	dp := &metricspb.HistogramDataPoint{
+		Attributes:        attrs,
+		StartTimeUnixNano: b.startTime,
+		TimeUnixNano:      now,
+		Count:             h.GetSampleCount(),
+		Sum:               &sum,
+	}
+	var previous uint64
+	for _, bucket := range h.GetBucket() {
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			break
+		}
+		dp.ExplicitBounds = append(dp.ExplicitBounds, bucket.GetUpperBound())
+		dp.BucketCounts = append(dp.BucketCounts, bucket.GetCumulativeCount()-previous)
+		previous = bucket.GetCumulativeCount()
+	}
+	dp.BucketCounts = append(dp.BucketCounts, h.GetSampleCount()-previous)
+	return dp
+}
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
@@ -0,0 +1,104 @@
+package otlp
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// attributes returns the string attributes as a map.
+func attributes(kvs []*commonpb.KeyValue) map[string]string {
+	out := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		out[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	return out
+}
+
+func testRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	power := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "RBLN_DEVICE_STATUS:CARD_POWER", Help: "Card power usage (W)"}, []string{"name", "hostname", "namespace", "pod"})
+	power.WithLabelValues("rbln0", "node-1", "team-a", "train").Set(120)
+	power.WithLabelValues("rbln1", "node-1", "", "").Set(30)
+	events := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "rbln_device_events_total", Help: "Device events"}, []string{"name", "cause"})
+	events.WithLabelValues("rbln1", "tdr").Add(2)
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "Latency (s)", Buckets: []float64{0.1, 1}})
+	for _, v := range []float64{0.05, 0.5, 0.7, 5} {
+		latency.Observe(v)
+	}
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "summary", Help: "Summary"})
+	summary.Observe(1)
+	registry.MustRegister(power, events, latency, summary)
+	return registry
+}
+
+func TestBuildResourceMetrics(t *testing.T) {
+	families, err := testRegistry(t).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := resourceBuilder{nodeName: "node-1", isKubernetes: true, startTime: 1}
+	resources := b.buildResourceMetrics(families, 2)
+
+	// One resource for the pod and one for the node.
+	metrics := make(map[string]map[string]*metricspb.Metric)
+	for _, rm := range resources {
+		attrs := attributes(rm.GetResource().GetAttributes())
+		if attrs["service.name"] != "rbln-metrics-exporter" || attrs["host.name"] != "node-1" || attrs["k8s.node.name"] != "node-1" {
+			t.Errorf("resource attributes = %v, want service, host and node names", attrs)
+		}
+		if pod := attrs["k8s.pod.name"]; pod != "" && attrs["k8s.namespace.name"] != "team-a" {
+			t.Errorf("resource attributes = %v, want the namespace of pod %s", attrs, pod)
+		}
+		byName := make(map[string]*metricspb.Metric)
+		for _, m := range rm.GetScopeMetrics()[0].GetMetrics() {
+			byName[m.GetName()] = m
+		}
+		metrics[attrs["k8s.pod.name"]] = byName
+	}
+	if len(metrics) != 2 {
+		t.Fatalf("got resources %v, want one for pod train and one for the node", slices.Collect(maps.Keys(metrics)))
+	}
+
+	power := metrics["train"]["RBLN_DEVICE_STATUS:CARD_POWER"]
+	if power.GetUnit() != "W" {
+		t.Errorf("power unit = %q, want W", power.GetUnit())
+	}
+	points := power.GetGauge().GetDataPoints()
+	if len(points) != 1 || points[0].GetAsDouble() != 120 || points[0].GetTimeUnixNano() != 2 {
+		t.Fatalf("power data points = %v, want 120 at time 2", points)
+	}
+	if attrs := attributes(points[0].GetAttributes()); attrs["name"] != "rbln0" || attrs["pod"] != "" || attrs["hostname"] != "" {
+		t.Errorf("power attributes = %v, want name without the resource labels", attrs)
+	}
+
+	node := metrics[""]
+	events := node["rbln_device_events_total"].GetSum()
+	if !events.GetIsMonotonic() || events.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Errorf("events sum = %v, want a cumulative monotonic sum", events)
+	}
+	if dp := events.GetDataPoints()[0]; dp.GetAsDouble() != 2 || dp.GetStartTimeUnixNano() != 1 {
+		t.Errorf("events data point = %v, want 2 since the start time", dp)
+	}
+
+	latency := node["latency_seconds"]
+	if latency.GetUnit() != "s" {
+		t.Errorf("latency unit = %q, want s", latency.GetUnit())
+	}
+	hist := latency.GetHistogram().GetDataPoints()[0]
+	if hist.GetCount() != 4 || hist.GetSum() != 6.25 {
+		t.Errorf("histogram count and sum = %d, %v, want 4, 6.25", hist.GetCount(), hist.GetSum())
+	}
+	if !slices.Equal(hist.GetExplicitBounds(), []float64{0.1, 1}) || !slices.Equal(hist.GetBucketCounts(), []uint64{1, 2, 1}) {
+		t.Errorf("histogram bounds and counts = %v, %v, want [0.1 1], [1 2 1]", hist.GetExplicitBounds(), hist.GetBucketCounts())
+	}
+
+	if _, ok := node["summary"]; ok {
+		t.Error("summary was converted, want it left out")
+	}
+}
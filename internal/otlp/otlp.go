@@ -0,0 +1,202 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+
+	httpMetricsPath = "/v1/metrics"
+)
+
+type Config struct {
+	Endpoint string
+	Protocol string
+	Interval time.Duration
+	Timeout  time.Duration
+	Headers  map[string]string
+	Insecure bool
+}
+
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Exporter periodically gathers the registry and pushes it to an OTLP receiver
+// such as the OpenTelemetry Collector.
+type Exporter struct {
+	cfg             Config
+	gatherer        prometheus.Gatherer
+	builder         resourceBuilder
+	intervalUpdates chan time.Duration
+	send            func(context.Context, *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error)
+	close           func()
+}
+
+func NewExporter(cfg Config, gatherer prometheus.Gatherer, nodeName string, isKubernetes bool) (*Exporter, error) {
+	e := &Exporter{
+		cfg:      cfg,
+		gatherer: gatherer,
+		builder: resourceBuilder{
+			nodeName:     nodeName,
+			isKubernetes: isKubernetes,
+			startTime:    uint64(time.Now().UnixNano()),
+		},
+		intervalUpdates: make(chan time.Duration, 1),
+		close:           func() {},
+	}
+
+	switch cfg.Protocol {
+	case ProtocolGRPC:
+		creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+		if cfg.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp grpc client %s: %w", cfg.Endpoint, err)
+		}
+		client := collectorpb.NewMetricsServiceClient(conn)
+		md := metadata.New(cfg.Headers)
+		e.send = func(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+			return client.Export(metadata.NewOutgoingContext(ctx, md), req)
+		}
+		e.close = func() { _ = conn.Close() }
+	case ProtocolHTTP:
+		endpoint, err := httpEndpoint(cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		client := &http.Client{}
+		e.send = func(ctx context.Context, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+			return exportHTTP(ctx, client, endpoint, cfg.Headers, req)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol %q", cfg.Protocol)
+	}
+
+	return e, nil
+}
+
+// SetInterval changes the export interval of a running exporter.
+func (e *Exporter) SetInterval(interval time.Duration) {
+	select {
+	case <-e.intervalUpdates:
+	default:
+	}
+	e.intervalUpdates <- interval
+}
+
+func (e *Exporter) Run(ctx context.Context) {
+	defer e.close()
+
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case interval := <-e.intervalUpdates:
+			e.cfg.Interval = interval
+			ticker.Reset(interval)
+		case <-ticker.C:
+			exportCtx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+			if err := e.export(exportCtx); err != nil {
+				slog.Warn("otlp export failed", "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (e *Exporter) export(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	req := &collectorpb.ExportMetricsServiceRequest{
+		ResourceMetrics: e.builder.buildResourceMetrics(families, uint64(time.Now().UnixNano())),
+	}
+	if len(req.ResourceMetrics) == 0 {
+		return nil
+	}
+
+	resp, err := e.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if ps := resp.GetPartialSuccess(); ps.GetRejectedDataPoints() > 0 {
+		slog.Warn("otlp receiver rejected data points", "rejected", ps.GetRejectedDataPoints(), "message", ps.GetErrorMessage())
+	}
+	return nil
+}
+
+func exportHTTP(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, req *collectorpb.ExportMetricsServiceRequest) (*collectorpb.ExportMetricsServiceResponse, error) {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal otlp request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send otlp request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read otlp response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("otlp receiver returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	out := &collectorpb.ExportMetricsServiceResponse{}
+	if err := proto.Unmarshal(respBody, out); err != nil {
+		// Some receivers reply with an empty or JSON body; the export itself succeeded.
+		return &collectorpb.ExportMetricsServiceResponse{}, nil
+	}
+	return out, nil
+}
+
+// httpEndpoint appends the default OTLP/HTTP metrics path when the endpoint has none.
+func httpEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("otlp http endpoint must be an absolute http(s) URL, got %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = httpMetricsPath
+	}
+	return u.String(), nil
+}
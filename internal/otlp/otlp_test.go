@@ -0,0 +1,91 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHTTPEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		{endpoint: "http://collector:4318", want: "http://collector:4318/v1/metrics"},
+		{endpoint: "https://collector:4318/", want: "https://collector:4318/v1/metrics"},
+		{endpoint: "http://collector:4318/custom/metrics", want: "http://collector:4318/custom/metrics"},
+		{endpoint: "collector:4318", wantErr: true},
+		{endpoint: "ftp://collector", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := httpEndpoint(tt.endpoint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("httpEndpoint(%q) error = %v, wantErr %v", tt.endpoint, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("httpEndpoint(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestExportHTTP(t *testing.T) {
+	var got struct {
+		path, contentType, auth string
+		req                     collectorpb.ExportMetricsServiceRequest
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path = r.URL.Path
+		got.contentType = r.Header.Get("Content-Type")
+		got.auth = r.Header.Get("Authorization")
+		body, err := io.ReadAll(r.Body)
+		if err == nil {
+			err = proto.Unmarshal(body, &got.req)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	endpoint, err := httpEndpoint(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &collectorpb.ExportMetricsServiceRequest{ResourceMetrics: []*metricspb.ResourceMetrics{{SchemaUrl: "test"}}}
+	if _, err := exportHTTP(context.Background(), srv.Client(), endpoint, map[string]string{"Authorization": "Bearer token"}, req); err != nil {
+		t.Fatalf("exportHTTP() error = %v", err)
+	}
+	if got.path != httpMetricsPath {
+		t.Errorf("path = %q, want %q", got.path, httpMetricsPath)
+	}
+	if got.contentType != "application/x-protobuf" {
+		t.Errorf("Content-Type = %q, want application/x-protobuf", got.contentType)
+	}
+	if got.auth != "Bearer token" {
+		t.Errorf("Authorization = %q, want the configured header", got.auth)
+	}
+	if !proto.Equal(&got.req, req) {
+		t.Errorf("request = %v, want %v", &got.req, req)
+	}
+}
+
+func TestExportHTTPRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := exportHTTP(context.Background(), srv.Client(), srv.URL+httpMetricsPath, nil, &collectorpb.ExportMetricsServiceRequest{})
+	if err == nil || !strings.Contains(err.Error(), "429") || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("exportHTTP() error = %v, want the status and body", err)
+	}
+}
@@ -0,0 +1,221 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+const (
+	// scrapeTimeoutOffset leaves Prometheus time to receive the response
+	// before its own scrape timeout expires.
+	scrapeTimeoutOffset = 500 * time.Millisecond
+	janitorInterval     = time.Minute
+)
+
+type Config struct {
+	// AllowedTargets are host:port patterns in path.Match syntax, e.g.
+	// "tray-*.example.com:50051". /probe is disabled when empty.
+	AllowedTargets []string
+	Timeout        time.Duration
+	// IdleTimeout closes pooled daemon connections that were not probed for this long.
+	IdleTimeout time.Duration
+}
+
+func (c Config) Enabled() bool {
+	return len(c.AllowedTargets) > 0
+}
+
+// Handler serves /probe?target=host:port in the style of the blackbox
+// exporter: it collects the devices of a remote rbln-daemon on demand and
+// returns their metrics together with probe_success.
+type Handler struct {
+	cfg Config
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+}
+
+type pooledClient struct {
+	mu       sync.Mutex
+	client   *daemon.Client
+	lastUsed time.Time
+	// closed is set when the entry was removed from the pool.
+	closed bool
+}
+
+func NewHandler(cfg Config) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		clients: make(map[string]*pooledClient),
+	}
+}
+
+// Run closes idle connections until ctx is canceled and then closes all of them.
+func (h *Handler) Run(ctx context.Context) {
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.closeIdle(0)
+			return
+		case <-ticker.C:
+			h.closeIdle(h.cfg.IdleTimeout)
+		}
+	}
+}
+
+func (h *Handler) closeIdle(idle time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for target, pc := range h.clients {
+		if !pc.mu.TryLock() {
+			// A probe is using the connection right now.
+			continue
+		}
+		if time.Since(pc.lastUsed) >= idle {
+			if pc.client != nil {
+				_ = pc.client.Close()
+			}
+			pc.closed = true
+			delete(h.clients, target)
+			slog.Debug("closed idle probe connection", "target", target)
+		}
+		pc.mu.Unlock()
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	if !h.allowed(target) {
+		http.Error(w, fmt.Sprintf("target %q is not allowed", target), http.StatusForbidden)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout(r))
+	defer cancel()
+
+	registry := prometheus.NewRegistry()
+	start := time.Now()
+	snapshot, err := h.collect(ctx, target)
+	if err != nil {
+		slog.Warn("probe failed", "target", target, "err", err)
+	} else {
+		registry, err = collector.NewSnapshotRegistry(ctx, snapshot, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	success := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_success",
+		Help: "Whether the probe of the rbln-daemon succeeded",
+	})
+	duration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_duration_seconds",
+		Help: "Duration of the probe (s)",
+	})
+	registry.MustRegister(success, duration)
+	if err == nil {
+		success.Set(1)
+	}
+	duration.Set(time.Since(start).Seconds())
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+func (h *Handler) collect(ctx context.Context, target string) (collector.Snapshot, error) {
+	pc := h.lockedClient(target)
+	defer pc.mu.Unlock()
+
+	pc.lastUsed = time.Now()
+	if pc.client == nil {
+		client, err := daemon.NewClient(ctx, target)
+		if err != nil {
+			return collector.Snapshot{}, err
+		}
+		pc.client = client
+	}
+
+	devices, err := pc.client.GetDeviceInfo(ctx)
+	if err != nil {
+		return collector.Snapshot{}, err
+	}
+
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		host = target
+	}
+	return collector.Snapshot{
+		Timestamp: time.Now(),
+		NodeName:  host,
+		Devices:   devices,
+	}, nil
+}
+
+// lockedClient returns the locked pool entry of the target, creating it when needed.
+func (h *Handler) lockedClient(target string) *pooledClient {
+	for {
+		h.mu.Lock()
+		pc, ok := h.clients[target]
+		if !ok {
+			pc = &pooledClient{}
+			h.clients[target] = pc
+		}
+		h.mu.Unlock()
+
+		pc.mu.Lock()
+		if !pc.closed {
+			return pc
+		}
+		// The entry was closed as idle in the meantime.
+		pc.mu.Unlock()
+	}
+}
+
+// allowed reports whether target is a host:port matching one of the allowed patterns.
+func (h *Handler) allowed(target string) bool {
+	if host, port, err := net.SplitHostPort(target); err != nil || host == "" || port == "" {
+		return false
+	}
+	for _, pattern := range h.cfg.AllowedTargets {
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// timeout honors the scrape timeout announced by Prometheus when it is
+// shorter than the configured one.
+func (h *Handler) timeout(r *http.Request) time.Duration {
+	timeout := h.cfg.Timeout
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			scrapeTimeout := time.Duration(seconds*float64(time.Second)) - scrapeTimeoutOffset
+			if scrapeTimeout > 0 && scrapeTimeout < timeout {
+				timeout = scrapeTimeout
+			}
+		}
+	}
+	return timeout
+}
@@ -0,0 +1,157 @@
+package probe
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllowed(t *testing.T) {
+	h := NewHandler(Config{AllowedTargets: []string{"tray-*.example.com:50051", "10.0.0.?:*"}})
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{target: "tray-1.example.com:50051", want: true},
+		{target: "tray-12.example.com:50051", want: true},
+		{target: "tray-1.example.com:50052", want: false},
+		{target: "tray-1.example.com", want: false},
+		{target: "other.example.com:50051", want: false},
+		{target: "10.0.0.7:50051", want: true},
+		{target: "10.0.0.17:50051", want: false},
+		{target: "10.0.0.7:", want: false},
+		{target: "10.0.0.7", want: false},
+	}
+	for _, tt := range tests {
+		if got := h.allowed(tt.target); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+
+	// A catch-all pattern still requires a port.
+	h = NewHandler(Config{AllowedTargets: []string{"*"}})
+	if h.allowed("tray-1") {
+		t.Error(`allowed("tray-1") = true with pattern "*", want a host without a port rejected`)
+	}
+}
+
+func TestServeHTTPRejectsTargets(t *testing.T) {
+	h := NewHandler(Config{AllowedTargets: []string{"tray-*:50051"}, Timeout: time.Second})
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: "", want: http.StatusBadRequest},
+		{query: "target=" + url.QueryEscape("other:50051"), want: http.StatusForbidden},
+		{query: "target=" + url.QueryEscape("tray-1"), want: http.StatusForbidden},
+		{query: "target=" + url.QueryEscape("tray-1:50052"), want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe?"+tt.query, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET /probe?%s status = %d, want %d", tt.query, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	h := NewHandler(Config{Timeout: 10 * time.Second})
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "", want: 10 * time.Second},
+		{header: "5", want: 4500 * time.Millisecond},
+		{header: "2.5", want: 2 * time.Second},
+		{header: "30", want: 10 * time.Second},
+		{header: "0.5", want: 10 * time.Second},
+		{header: "invalid", want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/probe", nil)
+		if tt.header != "" {
+			r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", tt.header)
+		}
+		if got := h.timeout(r); got != tt.want {
+			t.Errorf("timeout with scrape timeout %q = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestServeHTTPHonorsScrapeTimeout(t *testing.T) {
+	// A closed port makes the blocking dial retry until the probe times out.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := l.Addr().String()
+	l.Close()
+
+	h := NewHandler(Config{AllowedTargets: []string{"127.0.0.1:*"}, Timeout: 30 * time.Second})
+	r := httptest.NewRequest(http.MethodGet, "/probe?target="+url.QueryEscape(target), nil)
+	r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "0.7")
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	h.ServeHTTP(rec, r)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("probe took %v, want it bounded by the 0.2s left of the scrape timeout", elapsed)
+	}
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "probe_success 0") {
+		t.Errorf("probe response = %d %q, want probe_success 0", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCloseIdle(t *testing.T) {
+	h := NewHandler(Config{IdleTimeout: time.Minute})
+	idle := h.lockedClient("idle:50051")
+	idle.lastUsed = time.Now().Add(-2 * time.Minute)
+	idle.mu.Unlock()
+	recent := h.lockedClient("recent:50051")
+	recent.lastUsed = time.Now()
+	recent.mu.Unlock()
+	// A probe in progress holds the lock of its entry.
+	busy := h.lockedClient("busy:50051")
+	busy.lastUsed = time.Now().Add(-2 * time.Minute)
+
+	h.closeIdle(h.cfg.IdleTimeout)
+	busy.mu.Unlock()
+
+	if _, ok := h.clients["idle:50051"]; ok || !idle.closed {
+		t.Error("idle connection was kept, want it closed")
+	}
+	if _, ok := h.clients["recent:50051"]; !ok || recent.closed {
+		t.Error("recently used connection was closed, want it kept")
+	}
+	if _, ok := h.clients["busy:50051"]; !ok || busy.closed {
+		t.Error("connection in use was closed, want it kept")
+	}
+
+	// On shutdown every connection is closed.
+	h.closeIdle(0)
+	if len(h.clients) != 0 || !recent.closed || !busy.closed {
+		t.Errorf("got %d pooled connections after closing all, want none", len(h.clients))
+	}
+}
+
+func TestLockedClientAfterClose(t *testing.T) {
+	h := NewHandler(Config{})
+	pc := h.lockedClient("tray-1:50051")
+	pc.mu.Unlock()
+
+	h.closeIdle(0)
+
+	next := h.lockedClient("tray-1:50051")
+	defer next.mu.Unlock()
+	if next == pc || next.closed {
+		t.Fatal("lockedClient returned the closed entry, want a new one")
+	}
+	if h.clients["tray-1:50051"] != next {
+		t.Error("new entry is not pooled")
+	}
+}
@@ -0,0 +1,161 @@
+package pushgateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+)
+
+const (
+	MethodPut  = "put"
+	MethodPost = "post"
+
+	// instanceLabel groups pushes per node so that exporters on different
+	// hosts do not overwrite each other.
+	instanceLabel = "instance"
+)
+
+type Config struct {
+	URL          string
+	Job          string
+	Method       string
+	Grouping     map[string]string
+	Timeout      time.Duration
+	DeleteOnExit bool
+}
+
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// Pusher pushes the gathered registry to a Prometheus Pushgateway.
+type Pusher struct {
+	cfg             Config
+	client          *http.Client
+	pusher          *push.Pusher
+	newPusher       func(push.HTTPDoer) *push.Pusher
+	intervalUpdates chan time.Duration
+}
+
+// contextDoer sends the requests of a push client with ctx, for the calls of
+// the client that take no context.
+type contextDoer struct {
+	ctx    context.Context
+	client *http.Client
+}
+
+func (d contextDoer) Do(req *http.Request) (*http.Response, error) {
+	return d.client.Do(req.WithContext(d.ctx))
+}
+
+func NewPusher(cfg Config, gatherer prometheus.Gatherer, nodeName string) *Pusher {
+	// Credentials in the URL are sent as basic auth instead, so that the
+	// errors of the push client, which repeat the URL, do not leak them.
+	pushURL := cfg.URL
+	var user *url.Userinfo
+	if u, err := url.Parse(cfg.URL); err == nil && u.User != nil {
+		user = u.User
+		u.User = nil
+		pushURL = u.String()
+	}
+	newPusher := func(client push.HTTPDoer) *push.Pusher {
+		p := push.New(pushURL, cfg.Job).
+			Gatherer(gatherer).
+			Client(client).
+			Grouping(instanceLabel, nodeName)
+		for _, k := range slices.Sorted(maps.Keys(cfg.Grouping)) {
+			p = p.Grouping(k, cfg.Grouping[k])
+		}
+		if user != nil {
+			password, _ := user.Password()
+			p = p.BasicAuth(user.Username(), password)
+		}
+		return p
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+	return &Pusher{
+		cfg:             cfg,
+		client:          client,
+		pusher:          newPusher(client),
+		newPusher:       newPusher,
+		intervalUpdates: make(chan time.Duration, 1),
+	}
+}
+
+// SetInterval changes the push interval of a running pusher.
+func (p *Pusher) SetInterval(interval time.Duration) {
+	select {
+	case <-p.intervalUpdates:
+	default:
+	}
+	p.intervalUpdates <- interval
+}
+
+// Push sends the registry with PUT (replace every metric in the group) or
+// POST (replace only metrics with the same name) semantics.
+func (p *Pusher) Push(ctx context.Context) error {
+	var err error
+	if p.cfg.Method == MethodPost {
+		err = p.pusher.AddContext(ctx)
+	} else {
+		err = p.pusher.PushContext(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to push to pushgateway %s: %w", logging.RedactURL(p.cfg.URL), err)
+	}
+	return nil
+}
+
+// Delete removes the group from the Pushgateway. The push client has no
+// context-aware Delete, so ctx is attached to the request by a client of its own.
+func (p *Pusher) Delete(ctx context.Context) error {
+	if err := p.newPusher(contextDoer{ctx: ctx, client: p.client}).Delete(); err != nil {
+		return fmt.Errorf("failed to delete group from pushgateway %s: %w", logging.RedactURL(p.cfg.URL), err)
+	}
+	return nil
+}
+
+// Run pushes right away and then every interval until ctx is done and, if
+// configured, deletes the group from the Pushgateway on the way out. The
+// delete gets its own timeout, as ctx is already canceled by then.
+func (p *Pusher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pushOnce := func() {
+		pushCtx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+		defer cancel()
+		if err := p.Push(pushCtx); err != nil {
+			slog.Warn("pushgateway push failed", "err", err)
+		}
+	}
+	pushOnce()
+	for {
+		select {
+		case <-ctx.Done():
+			if p.cfg.DeleteOnExit {
+				deleteCtx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+				if err := p.Delete(deleteCtx); err != nil {
+					slog.Warn("pushgateway delete failed", "err", err)
+				}
+				cancel()
+			}
+			return
+		case interval := <-p.intervalUpdates:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			pushOnce()
+		}
+	}
+}
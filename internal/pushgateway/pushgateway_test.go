@@ -0,0 +1,143 @@
+package pushgateway
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// request is a request received by the fake Pushgateway.
+type request struct {
+	method string
+	path   string
+	user   string
+}
+
+// fakePushgateway records the requests it receives and accepts all of them.
+type fakePushgateway struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []request
+	received chan struct{}
+}
+
+func newFakePushgateway(t *testing.T) *fakePushgateway {
+	t.Helper()
+	f := &fakePushgateway{received: make(chan struct{}, 16)}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		f.mu.Lock()
+		f.requests = append(f.requests, request{method: r.Method, path: r.URL.Path, user: user})
+		f.mu.Unlock()
+		f.received <- struct{}{}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakePushgateway) wait(t *testing.T) request {
+	t.Helper()
+	select {
+	case <-f.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pushgateway received no request")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[len(f.requests)-1]
+}
+
+// groupingFromPath returns the label pairs of a /metrics/<name>/<value>/...
+// push path.
+func groupingFromPath(t *testing.T, path string) map[string]string {
+	t.Helper()
+	parts := strings.Split(strings.TrimPrefix(path, "/metrics/"), "/")
+	if len(parts)%2 != 0 {
+		t.Fatalf("path %s has no label pairs", path)
+	}
+	grouping := make(map[string]string, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		grouping[parts[i]] = parts[i+1]
+	}
+	return grouping
+}
+
+func testGatherer() prometheus.Gatherer {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "g", Help: "g"})
+	gauge.Set(1)
+	registry.MustRegister(gauge)
+	return registry
+}
+
+func TestPushMethodAndGrouping(t *testing.T) {
+	tests := []struct {
+		method     string
+		wantMethod string
+	}{
+		{method: MethodPut, wantMethod: http.MethodPut},
+		{method: MethodPost, wantMethod: http.MethodPost},
+		{method: "", wantMethod: http.MethodPut},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantMethod+"/"+tt.method, func(t *testing.T) {
+			gateway := newFakePushgateway(t)
+			p := NewPusher(Config{
+				URL:      gateway.URL,
+				Job:      "rbln",
+				Method:   tt.method,
+				Grouping: map[string]string{"zone": "b", "cluster": "a"},
+				Timeout:  time.Second,
+			}, testGatherer(), "node-1")
+			if err := p.Push(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got := gateway.wait(t)
+			if got.method != tt.wantMethod {
+				t.Errorf("method = %s, want %s", got.method, tt.wantMethod)
+			}
+			want := map[string]string{"job": "rbln", "instance": "node-1", "cluster": "a", "zone": "b"}
+			if grouping := groupingFromPath(t, got.path); !maps.Equal(grouping, want) {
+				t.Errorf("grouping of %s = %v, want %v", got.path, grouping, want)
+			}
+		})
+	}
+}
+
+func TestRunPushesAtOnceAndDeletesOnExit(t *testing.T) {
+	gateway := newFakePushgateway(t)
+	gatewayURL := "http://pusher:secret@" + gateway.Listener.Addr().String()
+	p := NewPusher(Config{URL: gatewayURL, Job: "rbln", Timeout: time.Second, DeleteOnExit: true}, testGatherer(), "node-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx, time.Hour)
+		close(done)
+	}()
+
+	if got := gateway.wait(t); got.method != http.MethodPut || got.user != "pusher" {
+		t.Errorf("first request = %+v, want a PUT with basic auth before the first interval", got)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after ctx was canceled")
+	}
+	// Run returns only after the delete, so the request must already be there.
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+	last := gateway.requests[len(gateway.requests)-1]
+	if last.method != http.MethodDelete || last.path != "/metrics/job/rbln/instance/node-1" || last.user != "pusher" {
+		t.Errorf("last request = %+v, want a DELETE of the group with basic auth", last)
+	}
+}
@@ -0,0 +1,141 @@
+package remotewrite
+
+import (
+	"maps"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const metricNameLabel = "__name__"
+
+type label struct {
+	Name  string
+	Value string
+}
+
+type timeSeries struct {
+	Labels    []label
+	Value     float64
+	Timestamp int64
+}
+
+// Field numbers of the prometheus.WriteRequest protobuf message and its children.
+const (
+	writeRequestTimeseriesField = 1
+	timeSeriesLabelsField       = 1
+	timeSeriesSamplesField      = 2
+	labelNameField              = 1
+	labelValueField             = 2
+	sampleValueField            = 1
+	sampleTimestampField        = 2
+)
+
+// encodeWriteRequest serialises series as a snappy-compressed prometheus.WriteRequest.
+func encodeWriteRequest(series []timeSeries) []byte {
+	var buf []byte
+	for _, ts := range series {
+		buf = protowire.AppendTag(buf, writeRequestTimeseriesField, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, encodeTimeSeries(ts))
+	}
+	return snappy.Encode(nil, buf)
+}
+
+func encodeTimeSeries(ts timeSeries) []byte {
+	var buf []byte
+	for _, l := range ts.Labels {
+		var lb []byte
+		lb = protowire.AppendTag(lb, labelNameField, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.Name)
+		lb = protowire.AppendTag(lb, labelValueField, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.Value)
+
+		buf = protowire.AppendTag(buf, timeSeriesLabelsField, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, lb)
+	}
+
+	var sb []byte
+	sb = protowire.AppendTag(sb, sampleValueField, protowire.Fixed64Type)
+	sb = protowire.AppendFixed64(sb, math.Float64bits(ts.Value))
+	sb = protowire.AppendTag(sb, sampleTimestampField, protowire.VarintType)
+	sb = protowire.AppendVarint(sb, uint64(ts.Timestamp))
+
+	buf = protowire.AppendTag(buf, timeSeriesSamplesField, protowire.BytesType)
+	buf = protowire.AppendBytes(buf, sb)
+	return buf
+}
+
+// seriesFromFamilies flattens gathered metric families into remote write series.
+// Histograms and summaries are expanded the same way the text exposition format does.
+func seriesFromFamilies(families []*dto.MetricFamily, externalLabels map[string]string, timestamp int64) []timeSeries {
+	var series []timeSeries
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			base := make(map[string]string, len(m.GetLabel())+len(externalLabels)+1)
+			maps.Copy(base, externalLabels)
+			for _, lp := range m.GetLabel() {
+				base[lp.GetName()] = lp.GetValue()
+			}
+
+			add := func(metricName string, value float64, extra ...string) {
+				labels := make(map[string]string, len(base)+2)
+				maps.Copy(labels, base)
+				for i := 0; i+1 < len(extra); i += 2 {
+					labels[extra[i]] = extra[i+1]
+				}
+				labels[metricNameLabel] = metricName
+				series = append(series, timeSeries{
+					Labels:    sortedLabels(labels),
+					Value:     value,
+					Timestamp: timestamp,
+				})
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_COUNTER:
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, q.GetValue(), "quantile", formatFloat(q.GetQuantile()))
+				}
+				add(name+"_sum", s.GetSampleSum())
+				add(name+"_count", float64(s.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), +1) {
+						continue
+					}
+					add(name+"_bucket", float64(b.GetCumulativeCount()), "le", formatFloat(b.GetUpperBound()))
+				}
+				add(name+"_bucket", float64(h.GetSampleCount()), "le", "+Inf")
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			}
+		}
+	}
+	return series
+}
+
+func sortedLabels(labels map[string]string) []label {
+	out := make([]label, 0, len(labels))
+	for k, v := range labels {
+		out = append(out, label{Name: k, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
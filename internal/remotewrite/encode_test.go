@@ -0,0 +1,80 @@
+package remotewrite
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+func TestEncodeWriteRequestRoundTrip(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "temperature", Help: "t"}, []string{"name"})
+	gauge.WithLabelValues("rbln0").Set(42.5)
+	gauge.WithLabelValues("rbln1").Set(math.Inf(-1))
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "events_total", Help: "e"})
+	counter.Add(3)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "latency_seconds", Help: "l", Buckets: []float64{0.5}})
+	histogram.Observe(0.25)
+	histogram.Observe(1)
+	registry.MustRegister(gauge, counter, histogram)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const timestamp = 1700000000123
+	series := seriesFromFamilies(families, map[string]string{"cluster": "c1", "name": "overridden"}, timestamp)
+
+	data, err := snappy.Decode(nil, encodeWriteRequest(series))
+	if err != nil {
+		t.Fatalf("snappy decode: %v", err)
+	}
+	var req prompb.WriteRequest
+	if err := req.Unmarshal(data); err != nil {
+		t.Fatalf("unmarshal WriteRequest: %v", err)
+	}
+
+	got := make(map[string]prompb.Sample)
+	for _, ts := range req.Timeseries {
+		if len(ts.Samples) != 1 {
+			t.Fatalf("series %v has %d samples, want 1", ts.Labels, len(ts.Samples))
+		}
+		for i := 1; i < len(ts.Labels); i++ {
+			if ts.Labels[i-1].Name >= ts.Labels[i].Name {
+				t.Errorf("labels of %v are not sorted", ts.Labels)
+			}
+		}
+		key := ""
+		for _, l := range ts.Labels {
+			key += l.Name + "=" + l.Value + ","
+		}
+		got[key] = ts.Samples[0]
+	}
+
+	// Labels of the metric take precedence over external labels.
+	want := map[string]float64{
+		"__name__=temperature,cluster=c1,name=rbln0,":                         42.5,
+		"__name__=temperature,cluster=c1,name=rbln1,":                         math.Inf(-1),
+		"__name__=events_total,cluster=c1,name=overridden,":                   3,
+		"__name__=latency_seconds_bucket,cluster=c1,le=0.5,name=overridden,":  1,
+		"__name__=latency_seconds_bucket,cluster=c1,le=+Inf,name=overridden,": 2,
+		"__name__=latency_seconds_sum,cluster=c1,name=overridden,":            1.25,
+		"__name__=latency_seconds_count,cluster=c1,name=overridden,":          2,
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d series, want %d: %v", len(got), len(want), got)
+	}
+	for key, value := range want {
+		sample, ok := got[key]
+		if !ok {
+			t.Errorf("missing series %s", key)
+			continue
+		}
+		if sample.Value != value || sample.Timestamp != timestamp {
+			t.Errorf("series %s = %v@%d, want %v@%d", key, sample.Value, sample.Timestamp, value, timestamp)
+		}
+	}
+}
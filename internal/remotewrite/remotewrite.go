@@ -0,0 +1,228 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const userAgent = "rbln-metrics-exporter"
+
+type Config struct {
+	URL                   string
+	Timeout               time.Duration
+	BatchSize             int
+	QueueCapacity         int
+	MaxRetries            int
+	MinBackoff            time.Duration
+	MaxBackoff            time.Duration
+	BearerTokenFile       string
+	BasicAuthUsername     string
+	BasicAuthPasswordFile string
+	ExternalLabels        map[string]string
+}
+
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// Writer periodically gathers the registry and pushes the samples to a
+// Prometheus remote_write receiver.
+type Writer struct {
+	cfg             Config
+	gatherer        prometheus.Gatherer
+	interval        time.Duration
+	intervalUpdates chan time.Duration
+	client          *http.Client
+	queue           chan timeSeries
+}
+
+func NewWriter(cfg Config, gatherer prometheus.Gatherer, interval time.Duration) *Writer {
+	return &Writer{
+		cfg:             cfg,
+		gatherer:        gatherer,
+		interval:        interval,
+		intervalUpdates: make(chan time.Duration, 1),
+		client:          &http.Client{Timeout: cfg.Timeout},
+		queue:           make(chan timeSeries, cfg.QueueCapacity),
+	}
+}
+
+// SetInterval changes the gather interval of a running writer.
+func (w *Writer) SetInterval(interval time.Duration) {
+	select {
+	case <-w.intervalUpdates:
+	default:
+	}
+	w.intervalUpdates <- interval
+}
+
+func (w *Writer) Run(ctx context.Context) {
+	go w.runSender(ctx)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case interval := <-w.intervalUpdates:
+			w.interval = interval
+			ticker.Reset(interval)
+		case <-ticker.C:
+			if err := w.enqueue(); err != nil {
+				slog.Warn("remote write gather failed", "err", err)
+			}
+		}
+	}
+}
+
+func (w *Writer) enqueue() error {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	dropped := 0
+	for _, ts := range seriesFromFamilies(families, w.cfg.ExternalLabels, time.Now().UnixMilli()) {
+		select {
+		case w.queue <- ts:
+			continue
+		default:
+		}
+		// Queue is full: drop the oldest sample so the freshest data wins.
+		select {
+		case <-w.queue:
+			dropped++
+		default:
+		}
+		select {
+		case w.queue <- ts:
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		slog.Warn("remote write queue full, dropped samples", "dropped", dropped, "capacity", w.cfg.QueueCapacity)
+	}
+	return nil
+}
+
+func (w *Writer) runSender(ctx context.Context) {
+	batch := make([]timeSeries, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ts := <-w.queue:
+			batch = append(batch, ts)
+		drain:
+			for len(batch) < w.cfg.BatchSize {
+				select {
+				case ts := <-w.queue:
+					batch = append(batch, ts)
+				default:
+					break drain
+				}
+			}
+			w.sendWithRetry(ctx, batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (w *Writer) sendWithRetry(ctx context.Context, batch []timeSeries) {
+	body := encodeWriteRequest(batch)
+	backoff := w.cfg.MinBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := w.send(ctx, body)
+		if err == nil {
+			return
+		}
+
+		var recErr *recoverableError
+		if !errors.As(err, &recErr) || attempt >= w.cfg.MaxRetries {
+			slog.Warn("remote write failed, dropping batch", "samples", len(batch), "attempts", attempt+1, "err", err)
+			return
+		}
+
+		slog.Debug("remote write failed, retrying", "backoff", backoff, "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, w.cfg.MaxBackoff)
+	}
+}
+
+type recoverableError struct {
+	error
+}
+
+func (w *Writer) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create remote write request: %w", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if err := w.setAuth(req); err != nil {
+		return err
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return &recoverableError{fmt.Errorf("failed to send remote write request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("remote write server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return &recoverableError{err}
+	}
+	return err
+}
+
+// setAuth reads credentials on every request so that rotated secrets are picked up.
+func (w *Writer) setAuth(req *http.Request) error {
+	if w.cfg.BearerTokenFile != "" {
+		token, err := os.ReadFile(w.cfg.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token file: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+		return nil
+	}
+	if w.cfg.BasicAuthUsername != "" {
+		var password []byte
+		if w.cfg.BasicAuthPasswordFile != "" {
+			var err error
+			password, err = os.ReadFile(w.cfg.BasicAuthPasswordFile)
+			if err != nil {
+				return fmt.Errorf("failed to read basic auth password file: %w", err)
+			}
+		}
+		req.SetBasicAuth(w.cfg.BasicAuthUsername, strings.TrimSpace(string(password)))
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func gaugeFamily(name string, values ...float64) *dto.MetricFamily {
+	mf := &dto.MetricFamily{Name: &name, Type: dto.MetricType_GAUGE.Enum()}
+	for i, v := range values {
+		labelName, labelValue := "index", strconv.Itoa(i)
+		mf.Metric = append(mf.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{{Name: &labelName, Value: &labelValue}},
+			Gauge: &dto.Gauge{Value: &v},
+		})
+	}
+	return mf
+}
+
+func TestEnqueueDropsOldestWhenFull(t *testing.T) {
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return []*dto.MetricFamily{gaugeFamily("g", 1, 2, 3, 4, 5)}, nil
+	})
+	w := NewWriter(Config{URL: "http://unused", QueueCapacity: 2}, gatherer, time.Second)
+
+	if err := w.enqueue(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.queue) != 2 {
+		t.Fatalf("queue holds %d samples, want 2", len(w.queue))
+	}
+	for _, want := range []float64{4, 5} {
+		if got := (<-w.queue).Value; got != want {
+			t.Errorf("queued sample %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEnqueueGatherError(t *testing.T) {
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return nil, errors.New("boom")
+	})
+	w := NewWriter(Config{URL: "http://unused", QueueCapacity: 2}, gatherer, time.Second)
+	if err := w.enqueue(); err == nil {
+		t.Fatal("expected gather error")
+	}
+}
+
+func TestSendClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		status      int
+		wantErr     bool
+		recoverable bool
+	}{
+		{status: http.StatusNoContent},
+		{status: http.StatusOK},
+		{status: http.StatusBadRequest, wantErr: true},
+		{status: http.StatusUnauthorized, wantErr: true},
+		{status: http.StatusTooManyRequests, wantErr: true, recoverable: true},
+		{status: http.StatusInternalServerError, wantErr: true, recoverable: true},
+		{status: http.StatusServiceUnavailable, wantErr: true, recoverable: true},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+					t.Errorf("unexpected headers %v", r.Header)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			w := NewWriter(Config{URL: srv.URL, Timeout: time.Second}, nil, time.Second)
+			err := w.send(context.Background(), encodeWriteRequest(nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("send() error = %v, want error %v", err, tt.wantErr)
+			}
+			var recErr *recoverableError
+			if got := errors.As(err, &recErr); got != tt.recoverable {
+				t.Errorf("recoverable = %v, want %v (%v)", got, tt.recoverable, err)
+			}
+		})
+	}
+}
+
+func TestSendConnectionErrorIsRecoverable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	w := NewWriter(Config{URL: srv.URL, Timeout: time.Second}, nil, time.Second)
+	var recErr *recoverableError
+	if err := w.send(context.Background(), nil); !errors.As(err, &recErr) {
+		t.Errorf("send() error = %v, want recoverable", err)
+	}
+}
+
+func TestSendWithRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int32
+	}{
+		{name: "succeeds after retries", statuses: []int{503, 429, 204}, wantAttempts: 3},
+		{name: "gives up after max retries", statuses: []int{500, 500, 500, 500, 500}, wantAttempts: 3},
+		{name: "does not retry client errors", statuses: []int{400, 204}, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := attempts.Add(1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			w := NewWriter(Config{
+				URL:        srv.URL,
+				Timeout:    time.Second,
+				MaxRetries: 2,
+				MinBackoff: time.Millisecond,
+				MaxBackoff: 2 * time.Millisecond,
+			}, nil, time.Second)
+			w.sendWithRetry(context.Background(), []timeSeries{{Labels: []label{{Name: metricNameLabel, Value: "g"}}, Value: 1}})
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSetIntervalResetsTicker(t *testing.T) {
+	gathered := make(chan struct{}, 1)
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		select {
+		case gathered <- struct{}{}:
+		default:
+		}
+		return nil, nil
+	})
+	w := NewWriter(Config{URL: "http://unused", QueueCapacity: 1}, gatherer, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	w.SetInterval(time.Millisecond)
+	select {
+	case <-gathered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer did not gather after the interval was shortened")
+	}
+}
@@ -0,0 +1,91 @@
+package restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler serves the latest collection snapshot as JSON:
+//
+//	GET /api/v1/devices                 all devices
+//	GET /api/v1/devices/{id}            a single device by name or UUID
+//	GET /api/v1/devices/{source}/{id}   a device of a named daemon endpoint
+//	GET /api/v1/stream                  snapshots and daemon events as Server-Sent Events
+type Handler struct {
+	snapshots *collector.SnapshotStore
+	events    *events.Broker
+	mux       *http.ServeMux
+}
+
+func NewHandler(snapshots *collector.SnapshotStore, broker *events.Broker) *Handler {
+	h := &Handler{
+		snapshots: snapshots,
+		events:    broker,
+		mux:       http.NewServeMux(),
+	}
+	h.mux.HandleFunc("GET /api/v1/devices", h.listDevices)
+	h.mux.HandleFunc("GET /api/v1/devices/{id}", h.getDevice)
+	h.mux.HandleFunc("GET /api/v1/devices/{source}/{id}", h.getDevice)
+	h.mux.HandleFunc("GET /api/v1/stream", h.stream)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) listDevices(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := h.snapshots.Latest()
+	if !ok {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "no collection has completed yet"})
+		return
+	}
+	writeJSON(w, http.StatusOK, NewDeviceList(snapshot))
+}
+
+func (h *Handler) getDevice(w http.ResponseWriter, r *http.Request) {
+	snapshot, ok := h.snapshots.Latest()
+	if !ok {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "no collection has completed yet"})
+		return
+	}
+
+	// Device names repeat across daemon endpoints, so a name alone may match
+	// several devices; the source path segment disambiguates them.
+	id, source := r.PathValue("id"), r.PathValue("source")
+	var matches []daemon.DeviceInfo
+	for _, device := range snapshot.Devices {
+		if (device.Name == id || device.UUID == id) && (source == "" || device.Source == source) {
+			matches = append(matches, device)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "device " + path.Join(source, id) + " not found"})
+	case 1:
+		writeJSON(w, http.StatusOK, NewDevice(snapshot, matches[0]))
+	default:
+		sources := make([]string, 0, len(matches))
+		for _, device := range matches {
+			sources = append(sources, device.Source)
+		}
+		writeJSON(w, http.StatusConflict, errorResponse{Error: fmt.Sprintf("device %s exists on several sources (%s), use /api/v1/devices/{source}/%s", id, strings.Join(sources, ", "), id)})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
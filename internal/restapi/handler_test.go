@@ -0,0 +1,61 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+)
+
+func TestGetDevice(t *testing.T) {
+	snapshots := collector.NewSnapshotStore()
+	snapshots.Publish(collector.Snapshot{
+		Timestamp:          time.Now(),
+		NodeName:           "node-1",
+		IncludeSourceLabel: true,
+		Devices: []daemon.DeviceInfo{
+			{Name: "rbln0", UUID: "uuid-a0", Source: "tray1", Hostname: "tray1"},
+			{Name: "rbln1", UUID: "uuid-a1", Source: "tray1", Hostname: "tray1"},
+			{Name: "rbln0", UUID: "uuid-b0", Source: "tray2", Hostname: "tray2"},
+		},
+	})
+	h := NewHandler(snapshots, events.NewBroker())
+
+	tests := []struct {
+		path     string
+		want     int
+		wantUUID string
+	}{
+		{path: "/api/v1/devices/rbln1", want: http.StatusOK, wantUUID: "uuid-a1"},
+		{path: "/api/v1/devices/uuid-b0", want: http.StatusOK, wantUUID: "uuid-b0"},
+		{path: "/api/v1/devices/rbln0", want: http.StatusConflict},
+		{path: "/api/v1/devices/tray2/rbln0", want: http.StatusOK, wantUUID: "uuid-b0"},
+		{path: "/api/v1/devices/tray1/rbln0", want: http.StatusOK, wantUUID: "uuid-a0"},
+		{path: "/api/v1/devices/tray2/rbln1", want: http.StatusNotFound},
+		{path: "/api/v1/devices/rbln9", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.wantUUID == "" {
+				return
+			}
+			var device Device
+			if err := json.NewDecoder(rec.Body).Decode(&device); err != nil {
+				t.Fatal(err)
+			}
+			if device.UUID != tt.wantUUID {
+				t.Errorf("uuid = %s, want %s", device.UUID, tt.wantUUID)
+			}
+		})
+	}
+}
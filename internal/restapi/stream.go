@@ -0,0 +1,176 @@
+package restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+const (
+	keepaliveInterval = 15 * time.Second
+	// streamWriteTimeout disconnects clients that stop reading.
+	streamWriteTimeout = 10 * time.Second
+)
+
+// Event is the JSON representation of a daemon event.
+type Event struct {
+	Device     string  `json:"device"`
+	Source     string  `json:"source,omitempty"`
+	Type       string  `json:"type"`
+	Cause      string  `json:"cause"`
+	SubValue   int32   `json:"sub_value"`
+	KernelTime float64 `json:"kernel_time_seconds"`
+	UTCTime    string  `json:"utc_time"`
+}
+
+func NewEvent(event daemon.Event) Event {
+	return Event{
+		Device:     event.Device,
+		Source:     event.Source,
+		Type:       event.Type,
+		Cause:      event.Cause,
+		SubValue:   event.SubValue,
+		KernelTime: event.KernelTime,
+		UTCTime:    event.UTCTime,
+	}
+}
+
+type droppedEvents struct {
+	Dropped int `json:"dropped"`
+}
+
+// deviceFilter matches devices by name or UUID. An empty filter matches all devices.
+type deviceFilter map[string]struct{}
+
+func newDeviceFilter(values []string) deviceFilter {
+	filter := make(deviceFilter)
+	for _, value := range values {
+		for id := range strings.SplitSeq(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				filter[id] = struct{}{}
+			}
+		}
+	}
+	return filter
+}
+
+func (f deviceFilter) match(name, uuid string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	_, byName := f[name]
+	_, byUUID := f[uuid]
+	return byName || byUUID
+}
+
+// Filter returns the devices matching any of ids, given as names or UUIDs
+// and optionally comma-separated. No ids match all devices.
+func (l DeviceList) Filter(ids []string) DeviceList {
+	return newDeviceFilter(ids).filterList(l)
+}
+
+func (f deviceFilter) filterList(list DeviceList) DeviceList {
+	if len(f) == 0 {
+		return list
+	}
+	devices := make([]Device, 0, len(list.Devices))
+	for _, device := range list.Devices {
+		if f.match(device.Name, device.UUID) {
+			devices = append(devices, device)
+		}
+	}
+	list.Devices = devices
+	return list
+}
+
+// matchEvent resolves the UUID of the event's device from the latest snapshot,
+// because daemon events only carry the device name.
+func (f deviceFilter) matchEvent(event daemon.Event, latest collector.Snapshot) bool {
+	if len(f) == 0 {
+		return true
+	}
+	uuid := ""
+	for _, device := range latest.Devices {
+		if device.Name == event.Device {
+			uuid = device.UUID
+			break
+		}
+	}
+	return f.match(event.Device, uuid)
+}
+
+// stream serves snapshots and daemon events as Server-Sent Events. Every
+// client reads from its own subscription: snapshots are latest-wins and events
+// are buffered and dropped when the client falls behind, so a slow client
+// never blocks collection or other clients.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request) {
+	filter := newDeviceFilter(r.URL.Query()["device"])
+
+	snapshots, unsubscribeSnapshots := h.snapshots.Subscribe()
+	defer unsubscribeSnapshots()
+	events, unsubscribeEvents := h.events.Subscribe()
+	defer unsubscribeEvents()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	send := func(event string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if snapshot, ok := h.snapshots.Latest(); ok {
+		if err := send("snapshot", filter.filterList(NewDeviceList(snapshot))); err != nil {
+			return
+		}
+	}
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case snapshot := <-snapshots:
+			err = send("snapshot", filter.filterList(NewDeviceList(snapshot)))
+		case event := <-events.C:
+			if n := events.Dropped(); n > 0 {
+				if err = send("dropped", droppedEvents{Dropped: n}); err != nil {
+					return
+				}
+			}
+			latest, _ := h.snapshots.Latest()
+			if filter.matchEvent(event, latest) {
+				err = send("event", NewEvent(event))
+			}
+		case <-keepalive.C:
+			_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if _, err = fmt.Fprint(w, ": keepalive\n\n"); err == nil {
+				err = rc.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
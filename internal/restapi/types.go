@@ -0,0 +1,89 @@
+package restapi
+
+import (
+	"cmp"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// Device is the stable JSON representation of a device in a snapshot. Field
+// names carry their unit so clients do not have to guess.
+type Device struct {
+	Name               string    `json:"name"`
+	UUID               string    `json:"uuid"`
+	DeviceID           string    `json:"device_id"`
+	Card               string    `json:"card"`
+	Hostname           string    `json:"hostname"`
+	Source             string    `json:"source,omitempty"`
+	DriverVersion      string    `json:"driver_version"`
+	FirmwareVersion    string    `json:"firmware_version"`
+	TemperatureCelsius float64   `json:"temperature_celsius"`
+	PowerWatts         float64   `json:"power_watts"`
+	MemoryUsedBytes    uint64    `json:"memory_used_bytes"`
+	MemoryTotalBytes   uint64    `json:"memory_total_bytes"`
+	UtilizationPercent float64   `json:"utilization_percent"`
+	HealthStatus       int       `json:"health_status"`
+	Healthy            bool      `json:"healthy"`
+	Pod                *Pod      `json:"pod,omitempty"`  // first of Pods, for clients that expect a single owner
+	Pods               []Pod     `json:"pods,omitempty"` // every container the device is allocated to
+	Timestamp          time.Time `json:"timestamp"`
+}
+
+// Pod identifies the container a device is allocated to.
+type Pod struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Container string `json:"container"`
+}
+
+type DeviceList struct {
+	Hostname  string    `json:"hostname"`
+	Timestamp time.Time `json:"timestamp"`
+	Devices   []Device  `json:"devices"`
+}
+
+func NewDevice(snapshot collector.Snapshot, device daemon.DeviceInfo) Device {
+	d := Device{
+		Name:               device.Name,
+		UUID:               device.UUID,
+		DeviceID:           device.DeviceID,
+		Card:               device.Card,
+		Hostname:           cmp.Or(device.Hostname, snapshot.NodeName),
+		Source:             device.Source,
+		DriverVersion:      device.DriverVersion,
+		FirmwareVersion:    device.FirmwareVersion,
+		TemperatureCelsius: device.Temperature,
+		PowerWatts:         device.Power,
+		MemoryUsedBytes:    collector.BytesFromGiB(device.DRAMUsedGiB),
+		MemoryTotalBytes:   collector.BytesFromGiB(device.DRAMTotalGiB),
+		UtilizationPercent: device.Utilization,
+		HealthStatus:       device.DeviceStatus,
+		Healthy:            device.DeviceStatus == 0,
+		Timestamp:          snapshot.Timestamp,
+	}
+	for _, owner := range snapshot.Owners(device) {
+		d.Pods = append(d.Pods, Pod{
+			Namespace: owner.Namespace,
+			Name:      owner.Name,
+			Container: owner.ContainerName,
+		})
+	}
+	if len(d.Pods) > 0 {
+		d.Pod = &d.Pods[0]
+	}
+	return d
+}
+
+func NewDeviceList(snapshot collector.Snapshot) DeviceList {
+	list := DeviceList{
+		Hostname:  snapshot.NodeName,
+		Timestamp: snapshot.Timestamp,
+		Devices:   make([]Device, 0, len(snapshot.Devices)),
+	}
+	for _, device := range snapshot.Devices {
+		list.Devices = append(list.Devices, NewDevice(snapshot, device))
+	}
+	return list
+}
@@ -0,0 +1,186 @@
+package rules
+
+import (
+	"fmt"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+)
+
+const (
+	groupDevices   = "rbln-devices"
+	groupExporter  = "rbln-metrics-exporter"
+	groupWorkloads = "rbln-workloads"
+	groupRecording = "rbln-namespace.rules"
+)
+
+// allocated selects the devices that are allocated to a pod.
+const allocated = `{pod!=""}`
+
+// definitions are the generated rules in output order.
+var definitions = []definition{
+	{
+		group:        groupDevices,
+		requires:     []requirement{{metric: metricTemperature, labels: []string{"card"}}},
+		unconfigured: noCardLimits,
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNDeviceHighTemperature",
+				Expr:   limitExpr(metricTemperature, t, t.TemperaturePercent, func(l CardLimits) float64 { return l.MaxTemperature }),
+				For:    duration(t.For),
+				Labels: map[string]string{"severity": severityWarning},
+				Annotations: map[string]string{
+					"summary":     deviceSummary("is running hot"),
+					"description": fmt.Sprintf("The temperature of the %s device is {{ $value }}°C, above %g%% of the card's limit.", "{{ $labels.card }}", t.TemperaturePercent),
+				},
+			}
+		},
+	},
+	{
+		group:        groupDevices,
+		requires:     []requirement{{metric: metricPower, labels: []string{"card"}}},
+		unconfigured: noCardLimits,
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNDeviceHighPower",
+				Expr:   limitExpr(metricPower, t, t.PowerPercent, func(l CardLimits) float64 { return l.MaxPower }),
+				For:    duration(t.For),
+				Labels: map[string]string{"severity": severityWarning},
+				Annotations: map[string]string{
+					"summary":     deviceSummary("draws high power"),
+					"description": fmt.Sprintf("The %s device draws {{ $value }} W, above %g%% of the card's limit.", "{{ $labels.card }}", t.PowerPercent),
+				},
+			}
+		},
+	},
+	{
+		group:    groupDevices,
+		requires: []requirement{{metric: metricHealth}},
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNDeviceUnhealthy",
+				Expr:   metricHealth + " != 0",
+				For:    duration(t.For),
+				Labels: map[string]string{"severity": severityCritical},
+				Annotations: map[string]string{
+					"summary":     deviceSummary("is inactive"),
+					"description": "The daemon reports the device as inactive.",
+				},
+			}
+		},
+	},
+	{
+		group:    groupDevices,
+		requires: []requirement{{metric: metricDRAMUsed}, {metric: metricDRAMTotal}},
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNDeviceMemoryNearlyFull",
+				Expr:   fmt.Sprintf("100 * %s / (%s > 0) > %g", metricDRAMUsed, metricDRAMTotal, t.MemoryPercent),
+				For:    duration(t.For),
+				Labels: map[string]string{"severity": severityWarning},
+				Annotations: map[string]string{
+					"summary":     deviceSummary("is running out of DRAM"),
+					"description": fmt.Sprintf("{{ $value | printf \"%%.1f\" }}%% of the device DRAM is used, above %g%%.", t.MemoryPercent),
+				},
+			}
+		},
+	},
+	{
+		group:    groupDevices,
+		requires: []requirement{{metric: events.DeviceEventsMetric, labels: []string{"cause"}}},
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNDeviceTDR",
+				Expr:   fmt.Sprintf(`increase(%s{cause="tdr"}[%s]) > 0`, events.DeviceEventsMetric, duration(t.EventWindow)),
+				Labels: map[string]string{"severity": severityWarning},
+				Annotations: map[string]string{
+					"summary":     deviceSummary("recovered from a timeout"),
+					"description": fmt.Sprintf("The daemon reported a timeout detection and recovery (TDR) event in the last %s.", duration(t.EventWindow)),
+				},
+			}
+		},
+	},
+	{
+		group:    groupDevices,
+		requires: []requirement{{metric: events.DeviceEventsMetric, labels: []string{"cause"}}},
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNDeviceReset",
+				Expr:   fmt.Sprintf(`increase(%s{cause=~"single_hard_reset|rsd_hard_reset"}[%s]) > 0`, events.DeviceEventsMetric, duration(t.EventWindow)),
+				Labels: map[string]string{"severity": severityWarning},
+				Annotations: map[string]string{
+					"summary":     deviceSummary("was reset"),
+					"description": fmt.Sprintf("The daemon reported a {{ $labels.cause }} event in the last %s.", duration(t.EventWindow)),
+				},
+			}
+		},
+	},
+	{
+		group:    groupExporter,
+		requires: []requirement{{metric: collector.DaemonUpMetric}},
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNDaemonDown",
+				Expr:   collector.DaemonUpMetric + " == 0",
+				For:    duration(t.For),
+				Labels: map[string]string{"severity": severityCritical},
+				Annotations: map[string]string{
+					"summary":     "RBLN daemon on {{ $labels.hostname }} is unreachable",
+					"description": "The exporter cannot list the devices of the RBLN daemon, so no device metrics are collected.",
+				},
+			}
+		},
+	},
+	{
+		group:    groupExporter,
+		requires: []requirement{{metric: collector.LastCollectionMetric}},
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNMetricsStale",
+				Expr:   fmt.Sprintf("time() - %s > %g", collector.LastCollectionMetric, t.StaleAfter.Seconds()),
+				Labels: map[string]string{"severity": severityWarning},
+				Annotations: map[string]string{
+					"summary":     "RBLN metrics on {{ $labels.hostname }} are stale",
+					"description": fmt.Sprintf("The exporter has not completed a collection for more than %s.", duration(t.StaleAfter)),
+				},
+			}
+		},
+	},
+	{
+		group:    groupWorkloads,
+		requires: []requirement{{metric: metricUtilization, labels: []string{"namespace", "pod"}}},
+		rule: func(t Thresholds) Rule {
+			return Rule{
+				Alert:  "RBLNAllocatedDeviceIdle",
+				Expr:   fmt.Sprintf("%s%s < %g", metricUtilization, allocated, t.IdleUtilizationPercent),
+				For:    duration(t.IdleFor),
+				Labels: map[string]string{"severity": severityInfo},
+				Annotations: map[string]string{
+					"summary":     deviceSummary("is allocated but idle"),
+					"description": fmt.Sprintf("The device is allocated to {{ $labels.namespace }}/{{ $labels.pod }} but its utilization stayed below %g%% for %s.", t.IdleUtilizationPercent, duration(t.IdleFor)),
+				},
+			}
+		},
+	},
+	namespaceRecord("namespace:rbln_device_allocated:count", "count", metricHealth),
+	namespaceRecord("namespace:rbln_device_utilization_percent:avg", "avg", metricUtilization),
+	namespaceRecord("namespace:rbln_device_power_watts:sum", "sum", metricPower),
+	namespaceRecord("namespace:rbln_device_dram_used_bytes:sum", "sum", metricDRAMUsed),
+	namespaceRecord("namespace:rbln_device_dram_total_bytes:sum", "sum", metricDRAMTotal),
+}
+
+// namespaceRecord aggregates the metric over the devices allocated to each
+// namespace. A device shared by several containers of the namespace has a
+// series per container, which are reduced to one first.
+func namespaceRecord(record, agg, metric string) definition {
+	return definition{
+		group:    groupRecording,
+		requires: []requirement{{metric: metric, labels: []string{"namespace", "pod", "container"}}},
+		rule: func(Thresholds) Rule {
+			return Rule{
+				Record: record,
+				Expr:   fmt.Sprintf("%s by (namespace) (max without (pod, container) (%s%s))", agg, metric, allocated),
+			}
+		},
+	}
+}
@@ -0,0 +1,272 @@
+// Package rules generates Prometheus alerting and recording rules for the
+// metrics the exporter registers.
+package rules
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+)
+
+const (
+	metricTemperature = "RBLN_DEVICE_STATUS:TEMPERATURE"
+	metricPower       = "RBLN_DEVICE_STATUS:CARD_POWER"
+	metricHealth      = "RBLN_DEVICE_STATUS:HEALTH"
+	metricDRAMUsed    = "RBLN_DEVICE_STATUS:DRAM_USED"
+	metricDRAMTotal   = "RBLN_DEVICE_STATUS:DRAM_TOTAL"
+	metricUtilization = "RBLN_DEVICE_STATUS:UTILIZATION"
+
+	severityCritical = "critical"
+	severityWarning  = "warning"
+	severityInfo     = "info"
+
+	// DefaultCard is the CardLimits key of the limits of the cards without
+	// limits of their own.
+	DefaultCard = "default"
+)
+
+// CardLimits are the thermal and power limits of a card model.
+type CardLimits struct {
+	// MaxTemperature is the highest operating temperature (°C).
+	MaxTemperature float64
+	// MaxPower is the board power limit (W).
+	MaxPower float64
+}
+
+// Thresholds configure the generated alerts.
+type Thresholds struct {
+	// TemperaturePercent and PowerPercent are relative to the limits of the
+	// card model.
+	TemperaturePercent float64
+	PowerPercent       float64
+	MemoryPercent      float64
+	// An allocated device is idle while its utilization stays below
+	// IdleUtilizationPercent for IdleFor.
+	IdleUtilizationPercent float64
+	IdleFor                time.Duration
+	// StaleAfter is the age of the last successful collection that is alerted on.
+	StaleAfter time.Duration
+	// EventWindow is the time an alert on a reset or TDR stays active.
+	EventWindow time.Duration
+	// For is how long a condition must hold before its alert fires.
+	For time.Duration
+	// CardLimits are the limits by card name; cards that are not listed use
+	// the limits of DefaultCard. The rbln-daemon does not report the limits,
+	// so the temperature and power alerts are left out without them.
+	CardLimits map[string]CardLimits
+}
+
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		TemperaturePercent:     90,
+		PowerPercent:           95,
+		MemoryPercent:          95,
+		IdleUtilizationPercent: 1,
+		IdleFor:                time.Hour,
+		StaleAfter:             5 * time.Minute,
+		EventWindow:            15 * time.Minute,
+		For:                    5 * time.Minute,
+		CardLimits:             make(map[string]CardLimits),
+	}
+}
+
+// Validate reports thresholds that cannot produce meaningful alerts.
+func (t Thresholds) Validate() error {
+	for name, percent := range map[string]float64{
+		"temperature": t.TemperaturePercent,
+		"power":       t.PowerPercent,
+		"memory":      t.MemoryPercent,
+	} {
+		if percent <= 0 || percent > 100 {
+			return fmt.Errorf("%s threshold must be between 0 and 100 percent, got %g", name, percent)
+		}
+	}
+	if t.IdleUtilizationPercent <= 0 || t.IdleUtilizationPercent > 100 {
+		return fmt.Errorf("idle utilization threshold must be between 0 and 100 percent, got %g", t.IdleUtilizationPercent)
+	}
+	for name, d := range map[string]time.Duration{
+		"idle duration":  t.IdleFor,
+		"stale duration": t.StaleAfter,
+		"event window":   t.EventWindow,
+	} {
+		if d <= 0 {
+			return fmt.Errorf("%s must be positive, got %s", name, d)
+		}
+	}
+	if t.For < 0 {
+		return fmt.Errorf("alert duration must not be negative, got %s", t.For)
+	}
+	return nil
+}
+
+// File is a Prometheus rule file; its groups are also the spec of a
+// PrometheusRule.
+type File struct {
+	Groups []Group `yaml:"groups" json:"groups"`
+}
+
+type Group struct {
+	Name  string `yaml:"name" json:"name"`
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+type Rule struct {
+	Record      string            `yaml:"record,omitempty" json:"record,omitempty"`
+	Alert       string            `yaml:"alert,omitempty" json:"alert,omitempty"`
+	Expr        string            `yaml:"expr" json:"expr"`
+	For         string            `yaml:"for,omitempty" json:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
+
+func (r Rule) name() string {
+	if r.Alert != "" {
+		return r.Alert
+	}
+	return r.Record
+}
+
+// requirement is a metric, with the labels a rule uses, that the exporter
+// must register for the rule to be generated.
+type requirement struct {
+	metric string
+	labels []string
+}
+
+type definition struct {
+	group    string
+	requires []requirement
+	// unconfigured returns why the thresholds do not allow the rule, if
+	// they do not.
+	unconfigured func(Thresholds) string
+	rule         func(Thresholds) Rule
+}
+
+// Skipped is a rule that was left out because the exporter does not register
+// the metric or labels it needs, e.g. when a collector is disabled, or
+// because its thresholds are not configured.
+type Skipped struct {
+	Rule   string
+	Reason string
+}
+
+// Generate returns the rules whose metrics and labels are in the catalog,
+// and the rules that were left out. The result is checked with Validate.
+func Generate(catalog []collector.MetricDescription, t Thresholds) (File, []Skipped, error) {
+	if err := t.Validate(); err != nil {
+		return File{}, nil, err
+	}
+
+	var (
+		file    File
+		skipped []Skipped
+	)
+	for _, def := range definitions {
+		rule := def.rule(t)
+		reason := missing(catalog, def.requires)
+		if reason == "" && def.unconfigured != nil {
+			reason = def.unconfigured(t)
+		}
+		if reason != "" {
+			skipped = append(skipped, Skipped{Rule: rule.name(), Reason: reason})
+			continue
+		}
+		i := slices.IndexFunc(file.Groups, func(g Group) bool { return g.Name == def.group })
+		if i < 0 {
+			file.Groups = append(file.Groups, Group{Name: def.group})
+			i = len(file.Groups) - 1
+		}
+		file.Groups[i].Rules = append(file.Groups[i].Rules, rule)
+	}
+	if err := Validate(file, catalog); err != nil {
+		return File{}, nil, err
+	}
+	return file, skipped, nil
+}
+
+func missing(catalog []collector.MetricDescription, requires []requirement) string {
+	for _, req := range requires {
+		i := slices.IndexFunc(catalog, func(m collector.MetricDescription) bool { return m.Name == req.metric })
+		if i < 0 {
+			return fmt.Sprintf("%s is not collected", req.metric)
+		}
+		for _, label := range req.labels {
+			if !slices.Contains(catalog[i].Labels, label) {
+				return fmt.Sprintf("%s has no %s label", req.metric, label)
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	quotedString = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	identifier   = regexp.MustCompile(`[a-zA-Z_:][a-zA-Z0-9_:]*`)
+)
+
+// Validate checks that every exporter metric used by the rules is in the
+// catalog. Metrics are recognized by their RBLN_ and rbln_ prefixes.
+func Validate(file File, catalog []collector.MetricDescription) error {
+	known := make(map[string]bool, len(catalog))
+	for _, m := range catalog {
+		known[m.Name] = true
+	}
+	for _, group := range file.Groups {
+		for _, rule := range group.Rules {
+			expr := quotedString.ReplaceAllString(rule.Expr, `""`)
+			for _, name := range identifier.FindAllString(expr, -1) {
+				if (strings.HasPrefix(name, "RBLN_") || strings.HasPrefix(name, "rbln_")) && !known[name] {
+					return fmt.Errorf("rule %s uses %s, which the exporter does not register", rule.name(), name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func duration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return model.Duration(d).String()
+}
+
+// noCardLimits is the unconfigured check of the rules comparing devices
+// against the limits of their card model.
+func noCardLimits(t Thresholds) string {
+	if len(t.CardLimits) == 0 {
+		return "no card limits are configured"
+	}
+	return ""
+}
+
+// limitExpr compares the metric of every card model with limits against a
+// fraction of its limit, and the remaining cards against the default limit
+// if there is one.
+func limitExpr(metric string, t Thresholds, percent float64, limit func(CardLimits) float64) string {
+	cards := slices.Sorted(maps.Keys(t.CardLimits))
+	cards = slices.DeleteFunc(cards, func(card string) bool { return card == DefaultCard })
+	terms := make([]string, 0, len(cards)+1)
+	for _, card := range cards {
+		terms = append(terms, fmt.Sprintf(`%s{card="%s"} > %g`, metric, card, limit(t.CardLimits[card])*percent/100))
+	}
+	if defaults, ok := t.CardLimits[DefaultCard]; ok {
+		if len(cards) == 0 {
+			terms = append(terms, fmt.Sprintf(`%s > %g`, metric, limit(defaults)*percent/100))
+		} else {
+			terms = append(terms, fmt.Sprintf(`%s{card!~"%s"} > %g`, metric, strings.Join(cards, "|"), limit(defaults)*percent/100))
+		}
+	}
+	return strings.Join(terms, "\nor\n")
+}
+
+func deviceSummary(what string) string {
+	return fmt.Sprintf("RBLN device {{ $labels.name }} on {{ $labels.hostname }} %s", what)
+}
@@ -0,0 +1,81 @@
+package rules
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+)
+
+func testCatalog(t *testing.T) []collector.MetricDescription {
+	t.Helper()
+	devices, err := collector.Catalog(collector.MetricGroups(), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter, err := collector.ExporterCatalog(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceEvents, err := events.Catalog(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append(devices, exporter...), deviceEvents...)
+}
+
+func TestGenerateCardLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		limits      map[string]CardLimits
+		wantSkipped bool
+		wantExpr    string
+	}{
+		{name: "no limits", wantSkipped: true},
+		{
+			name:     "default only",
+			limits:   map[string]CardLimits{DefaultCard: {MaxTemperature: 80, MaxPower: 200}},
+			wantExpr: "RBLN_DEVICE_STATUS:CARD_POWER > 190",
+		},
+		{
+			name:     "card and default",
+			limits:   map[string]CardLimits{"RBLN-CA25": {MaxTemperature: 80, MaxPower: 300}, DefaultCard: {MaxTemperature: 80, MaxPower: 200}},
+			wantExpr: "RBLN_DEVICE_STATUS:CARD_POWER{card=\"RBLN-CA25\"} > 285\nor\nRBLN_DEVICE_STATUS:CARD_POWER{card!~\"RBLN-CA25\"} > 190",
+		},
+		{
+			name:     "card only",
+			limits:   map[string]CardLimits{"RBLN-CA25": {MaxTemperature: 80, MaxPower: 300}},
+			wantExpr: "RBLN_DEVICE_STATUS:CARD_POWER{card=\"RBLN-CA25\"} > 285",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thresholds := DefaultThresholds()
+			for card, limits := range tt.limits {
+				thresholds.CardLimits[card] = limits
+			}
+			file, skipped, err := Generate(testCatalog(t), thresholds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, alert := range []string{"RBLNDeviceHighTemperature", "RBLNDeviceHighPower"} {
+				got := slices.ContainsFunc(skipped, func(s Skipped) bool { return s.Rule == alert })
+				if got != tt.wantSkipped {
+					t.Errorf("%s skipped = %v, want %v", alert, got, tt.wantSkipped)
+				}
+			}
+			if tt.wantExpr == "" {
+				return
+			}
+			for _, group := range file.Groups {
+				for _, rule := range group.Rules {
+					if rule.Alert == "RBLNDeviceHighPower" && strings.TrimSpace(rule.Expr) != tt.wantExpr {
+						t.Errorf("power expr = %q, want %q", rule.Expr, tt.wantExpr)
+					}
+				}
+			}
+		})
+	}
+}
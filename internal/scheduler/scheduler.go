@@ -11,6 +11,7 @@ import (
 type Scheduler struct {
 	collectors        []collector.Collector
 	interval          time.Duration
+	intervalUpdates   chan time.Duration
 	podResourceMapper *collector.PodResourceMapper
 }
 
@@ -18,6 +19,7 @@ func NewScheduler(podResourceMapper *collector.PodResourceMapper, collectors []c
 	return &Scheduler{
 		collectors:        collectors,
 		interval:          interval,
+		intervalUpdates:   make(chan time.Duration, 1),
 		podResourceMapper: podResourceMapper,
 	}
 }
@@ -32,6 +34,15 @@ func (s *Scheduler) RunOnce(ctx context.Context) error {
 	return nil
 }
 
+// SetInterval changes the collection interval of a running scheduler.
+func (s *Scheduler) SetInterval(interval time.Duration) {
+	select {
+	case <-s.intervalUpdates:
+	default:
+	}
+	s.intervalUpdates <- interval
+}
+
 func (s *Scheduler) Run(ctx context.Context) {
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
@@ -40,6 +51,9 @@ func (s *Scheduler) Run(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case interval := <-s.intervalUpdates:
+			s.interval = interval
+			ticker.Reset(interval)
 		case <-ticker.C:
 			cycleCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 			if err := s.RunOnce(cycleCtx); err != nil {
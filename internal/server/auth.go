@@ -0,0 +1,91 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BearerToken is a static bearer token read from a file. The file is re-read
+// when its modification time changes so rotated tokens apply without a
+// restart.
+type BearerToken struct {
+	file string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+func NewBearerToken(file string) *BearerToken {
+	return &BearerToken{file: file}
+}
+
+func (t *BearerToken) File() string {
+	return t.file
+}
+
+// Verify reports whether got matches the current token. An empty token file
+// matches nothing.
+func (t *BearerToken) Verify(got string) (bool, error) {
+	token, err := t.current()
+	if err != nil {
+		return false, err
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1, nil
+}
+
+func (t *BearerToken) current() (string, error) {
+	info, err := os.Stat(t.file)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if info.ModTime().Equal(t.modTime) {
+		return t.token, nil
+	}
+	data, err := os.ReadFile(t.file)
+	if err != nil {
+		return "", err
+	}
+	t.token = strings.TrimSpace(string(data))
+	t.modTime = info.ModTime()
+	return t.token, nil
+}
+
+// bearerAuthHandler requires a static bearer token.
+type bearerAuthHandler struct {
+	token *BearerToken
+	next  http.Handler
+}
+
+func newBearerAuthHandler(tokenFile string, next http.Handler) *bearerAuthHandler {
+	return &bearerAuthHandler{
+		token: NewBearerToken(tokenFile),
+		next:  next,
+	}
+}
+
+func (h *bearerAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	valid, err := h.token.Verify(got)
+	if err != nil {
+		slog.Error("failed to read bearer token file", "file", h.token.File(), "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if !ok || !valid {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="rbln-metrics-exporter"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
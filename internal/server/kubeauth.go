@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/kube"
+)
+
+const (
+	maxKubeAuthCacheEntries = 1024
+	updateVerb              = "update"
+)
+
+type KubeAuthConfig struct {
+	Enabled bool
+	// Kubeconfig is used instead of the in-cluster service account when set.
+	Kubeconfig string
+	// Verb is checked for reads; requests that change state, such as a PUT
+	// to /-/log-level, are checked with updateVerb instead.
+	Verb string
+	// Resource is "resource[/subresource]", e.g. "nodes/metrics". When empty,
+	// a non-resource review for the request path is performed instead.
+	Resource  string
+	APIGroup  string
+	Namespace string
+	Name      string
+	Audiences []string
+	CacheTTL  time.Duration
+}
+
+type kubeAuthDecision struct {
+	status  int
+	expires time.Time
+}
+
+// kubeAuthHandler authenticates bearer tokens with the TokenReview API and
+// authorizes them with a SubjectAccessReview, like kube-rbac-proxy does.
+type kubeAuthHandler struct {
+	cfg    KubeAuthConfig
+	client *kube.Client
+	next   http.Handler
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]kubeAuthDecision
+}
+
+func newKubeAuthHandler(cfg KubeAuthConfig, next http.Handler) (*kubeAuthHandler, error) {
+	client, err := kube.NewClient(cfg.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return &kubeAuthHandler{
+		cfg:    cfg,
+		client: client,
+		next:   next,
+		cache:  make(map[[sha256.Size]byte]kubeAuthDecision),
+	}, nil
+}
+
+func (h *kubeAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="rbln-metrics-exporter"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	status := h.decide(r.Context(), token, r.URL.Path, h.verb(r.Method))
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// verb maps the HTTP method to the verb of the SubjectAccessReview, so that
+// read access does not allow changing state.
+func (h *kubeAuthHandler) verb(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return h.cfg.Verb
+	default:
+		return updateVerb
+	}
+}
+
+func (h *kubeAuthHandler) decide(ctx context.Context, token, path, verb string) int {
+	// The path and verb are part of the key because the reviews depend on them.
+	key := sha256.Sum256([]byte(token + "\x00" + path + "\x00" + verb))
+	now := time.Now()
+
+	h.mu.Lock()
+	if d, ok := h.cache[key]; ok && now.Before(d.expires) {
+		h.mu.Unlock()
+		return d.status
+	}
+	h.mu.Unlock()
+
+	status, cacheable := h.review(ctx, token, path, verb)
+	if !cacheable {
+		return status
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.cache) >= maxKubeAuthCacheEntries {
+		for k, d := range h.cache {
+			if now.After(d.expires) {
+				delete(h.cache, k)
+			}
+		}
+		if len(h.cache) >= maxKubeAuthCacheEntries {
+			clear(h.cache)
+		}
+	}
+	h.cache[key] = kubeAuthDecision{status: status, expires: now.Add(h.cfg.CacheTTL)}
+	return status
+}
+
+// review returns the HTTP status for the token and whether it may be cached.
+// API errors are not cached so that a transient outage does not lock clients out.
+func (h *kubeAuthHandler) review(ctx context.Context, token, path, verb string) (int, bool) {
+	user, err := h.client.ReviewToken(ctx, token, h.cfg.Audiences)
+	if err != nil {
+		slog.Warn("token review failed", "err", err)
+		return http.StatusInternalServerError, false
+	}
+	if user == nil {
+		return http.StatusUnauthorized, true
+	}
+
+	var (
+		res    *kube.ResourceAttributes
+		nonRes *kube.NonResourceAttributes
+	)
+	if h.cfg.Resource != "" {
+		resource, subresource, _ := strings.Cut(h.cfg.Resource, "/")
+		res = &kube.ResourceAttributes{
+			Namespace:   h.cfg.Namespace,
+			Verb:        verb,
+			Group:       h.cfg.APIGroup,
+			Resource:    resource,
+			Subresource: subresource,
+			Name:        h.cfg.Name,
+		}
+	} else {
+		nonRes = &kube.NonResourceAttributes{Path: path, Verb: verb}
+	}
+
+	allowed, reason, err := h.client.ReviewAccess(ctx, *user, res, nonRes)
+	if err != nil {
+		slog.Warn("subject access review failed", "user", user.Username, "err", err)
+		return http.StatusInternalServerError, false
+	}
+	if !allowed {
+		slog.Debug("request forbidden", "user", user.Username, "path", path, "verb", verb, "reason", reason)
+		return http.StatusForbidden, true
+	}
+	return http.StatusOK, true
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAPIServer serves TokenReview and SubjectAccessReview. The token
+// "allowed" belongs to alice, who may read everything, "admin" to carol, who
+// may also update, and "forbidden" to bob, who may not access anything; every
+// other token is not authenticated.
+type fakeAPIServer struct {
+	*httptest.Server
+	tokenReviews  atomic.Int32
+	accessReviews atomic.Int32
+	lastVerb      atomic.Value
+	// unavailable makes every review fail with an internal server error.
+	unavailable atomic.Bool
+}
+
+func newFakeAPIServer(t *testing.T) *fakeAPIServer {
+	t.Helper()
+	f := &fakeAPIServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /apis/authentication.k8s.io/v1/tokenreviews", func(w http.ResponseWriter, r *http.Request) {
+		f.tokenReviews.Add(1)
+		if f.unavailable.Load() {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		var review map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status := map[string]any{"authenticated": false}
+		switch review["spec"].(map[string]any)["token"] {
+		case "allowed":
+			status = map[string]any{"authenticated": true, "user": map[string]any{"username": "alice"}}
+		case "admin":
+			status = map[string]any{"authenticated": true, "user": map[string]any{"username": "carol"}}
+		case "forbidden":
+			status = map[string]any{"authenticated": true, "user": map[string]any{"username": "bob"}}
+		}
+		review["status"] = status
+		_ = json.NewEncoder(w).Encode(review)
+	})
+	mux.HandleFunc("POST /apis/authorization.k8s.io/v1/subjectaccessreviews", func(w http.ResponseWriter, r *http.Request) {
+		f.accessReviews.Add(1)
+		var review struct {
+			Spec struct {
+				User                  string         `json:"user"`
+				NonResourceAttributes map[string]any `json:"nonResourceAttributes"`
+			} `json:"spec"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		verb := review.Spec.NonResourceAttributes["verb"]
+		f.lastVerb.Store(verb)
+		allowed := review.Spec.User == "carol" || (review.Spec.User == "alice" && verb != "update")
+		_ = json.NewEncoder(w).Encode(map[string]any{"status": map[string]any{"allowed": allowed}})
+	})
+	f.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeAPIServer) kubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	data := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+users:
+- name: test
+  user:
+    token: exporter
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+`, f.URL)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestKubeAuthHandler(t *testing.T, api *fakeAPIServer, ttl time.Duration) http.Handler {
+	t.Helper()
+	h, err := newKubeAuthHandler(KubeAuthConfig{
+		Enabled:    true,
+		Kubeconfig: api.kubeconfig(t),
+		Verb:       "get",
+		CacheTTL:   ttl,
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func serve(h http.Handler, method, token string) int {
+	req := httptest.NewRequest(method, "/metrics", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestKubeAuth(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "token review allowed", token: "allowed", want: http.StatusOK},
+		{name: "token review denied", token: "invalid", want: http.StatusUnauthorized},
+		{name: "subject access review forbidden", token: "forbidden", want: http.StatusForbidden},
+		{name: "missing token", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPIServer(t)
+			h := newTestKubeAuthHandler(t, api, time.Minute)
+			if got := serve(h, http.MethodGet, tt.token); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubeAuthCachesDecisions(t *testing.T) {
+	api := newFakeAPIServer(t)
+	h := newTestKubeAuthHandler(t, api, time.Minute)
+
+	for range 3 {
+		if got := serve(h, http.MethodGet, "allowed"); got != http.StatusOK {
+			t.Fatalf("status = %d, want %d", got, http.StatusOK)
+		}
+		if got := serve(h, http.MethodGet, "forbidden"); got != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d", got, http.StatusForbidden)
+		}
+	}
+	if got := api.tokenReviews.Load(); got != 2 {
+		t.Errorf("token reviews = %d, want 2", got)
+	}
+	if got := api.accessReviews.Load(); got != 2 {
+		t.Errorf("subject access reviews = %d, want 2", got)
+	}
+	if got := api.lastVerb.Load(); got != "get" {
+		t.Errorf("reviewed verb = %v, want get", got)
+	}
+}
+
+func TestKubeAuthCacheExpires(t *testing.T) {
+	api := newFakeAPIServer(t)
+	h := newTestKubeAuthHandler(t, api, time.Nanosecond)
+
+	for range 2 {
+		if got := serve(h, http.MethodGet, "allowed"); got != http.StatusOK {
+			t.Fatalf("status = %d, want %d", got, http.StatusOK)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := api.tokenReviews.Load(); got != 2 {
+		t.Errorf("token reviews = %d, want 2", got)
+	}
+}
+
+func TestKubeAuthDoesNotCacheAPIErrors(t *testing.T) {
+	api := newFakeAPIServer(t)
+	h := newTestKubeAuthHandler(t, api, time.Minute)
+
+	api.unavailable.Store(true)
+	if got := serve(h, http.MethodGet, "allowed"); got != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", got, http.StatusInternalServerError)
+	}
+	api.unavailable.Store(false)
+	if got := serve(h, http.MethodGet, "allowed"); got != http.StatusOK {
+		t.Fatalf("status after recovery = %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestKubeAuthChecksUpdatesWithUpdateVerb(t *testing.T) {
+	api := newFakeAPIServer(t)
+	h := newTestKubeAuthHandler(t, api, time.Minute)
+
+	tests := []struct {
+		method   string
+		token    string
+		want     int
+		wantVerb string
+	}{
+		{method: http.MethodGet, token: "allowed", want: http.StatusOK, wantVerb: "get"},
+		// The cached GET decision must not authorize the PUT.
+		{method: http.MethodPut, token: "allowed", want: http.StatusForbidden, wantVerb: "update"},
+		{method: http.MethodPost, token: "admin", want: http.StatusOK, wantVerb: "update"},
+		{method: http.MethodHead, token: "admin", want: http.StatusOK, wantVerb: "get"},
+	}
+	for _, tt := range tests {
+		if got := serve(h, tt.method, tt.token); got != tt.want {
+			t.Errorf("%s with %s: status = %d, want %d", tt.method, tt.token, got, tt.want)
+		}
+		if got := api.lastVerb.Load(); got != tt.wantVerb {
+			t.Errorf("%s with %s: reviewed verb = %v, want %s", tt.method, tt.token, got, tt.wantVerb)
+		}
+	}
+}
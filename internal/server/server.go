@@ -2,52 +2,221 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
+	"go.yaml.in/yaml/v2"
 )
 
+const unixSocketPrefix = "unix://"
+
+type Config struct {
+	// ListenAddresses are host:port pairs ("[::1]:9090" for IPv6) or unix
+	// sockets written as unix:///path/to.sock.
+	ListenAddresses []string
+	// WebConfigFile is an exporter-toolkit web config file enabling TLS and
+	// basic auth. It is re-read on every connection, so changes apply without
+	// a restart.
+	WebConfigFile   string
+	BearerTokenFile string
+	KubeAuth        KubeAuthConfig
+}
+
 type MetricServer struct {
-	server *http.Server
+	cfg       Config
+	mux       *http.ServeMux
+	publicMux *http.ServeMux
+	servers   []*http.Server
 }
 
-func NewMetricServer(gatherer prometheus.Gatherer, port int) *MetricServer {
+func NewMetricServer(metricsHandler http.Handler, cfg Config) *MetricServer {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
-
-	server := &http.Server{
-		Addr:              fmt.Sprintf(":%d", port),
-		Handler:           mux,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
+	mux.Handle("/metrics", metricsHandler)
 
 	metricServer := &MetricServer{
-		server: server,
+		cfg:       cfg,
+		mux:       mux,
+		publicMux: http.NewServeMux(),
 	}
 
 	return metricServer
 }
 
+// Handle registers an additional endpoint behind the configured authentication.
+func (ms *MetricServer) Handle(pattern string, handler http.Handler) {
+	ms.mux.Handle(pattern, handler)
+}
+
+// HandleProbe registers an endpoint that skips bearer token and Kubernetes
+// authentication, because kubelet probes cannot send credentials.
+func (ms *MetricServer) HandleProbe(pattern string, handler http.Handler) {
+	ms.publicMux.Handle(pattern, handler)
+}
+
 func (ms *MetricServer) Start(ctx context.Context) error {
-	serverErr := make(chan error, 1)
-	go func() {
-		if err := ms.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			serverErr <- err
+	if err := validateWebConfig(ms.cfg); err != nil {
+		return err
+	}
+
+	handler, err := ms.newHandler()
+	if err != nil {
+		return err
+	}
+
+	listeners := make([]net.Listener, 0, len(ms.cfg.ListenAddresses))
+	defer func() {
+		for _, l := range listeners {
+			_ = l.Close()
 		}
-		close(serverErr)
 	}()
+	for _, address := range ms.cfg.ListenAddresses {
+		l, err := Listen(address)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, l)
+	}
+
+	serverErr := make(chan error, len(listeners))
+	for _, l := range listeners {
+		// web.Serve wraps the server's handler, so every listener needs its own server.
+		server := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		ms.servers = append(ms.servers, server)
+
+		go func() {
+			flags := &web.FlagConfig{WebConfigFile: &ms.cfg.WebConfigFile}
+			if err := web.Serve(l, server, flags, slog.Default()); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- err
+				return
+			}
+			serverErr <- nil
+		}()
+	}
 
 	select {
 	case err := <-serverErr:
+		ms.shutdown()
 		return err
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		_ = ms.server.Shutdown(shutdownCtx)
-		return <-serverErr
+		ms.shutdown()
+		return nil
+	}
+}
+
+func (ms *MetricServer) newHandler() (http.Handler, error) {
+	var handler http.Handler = ms.mux
+	switch {
+	case ms.cfg.KubeAuth.Enabled:
+		kubeAuth, err := newKubeAuthHandler(ms.cfg.KubeAuth, handler)
+		if err != nil {
+			return nil, err
+		}
+		handler = kubeAuth
+	case ms.cfg.BearerTokenFile != "":
+		handler = newBearerAuthHandler(ms.cfg.BearerTokenFile, handler)
+	}
+	ms.publicMux.Handle("/", handler)
+	return ms.publicMux, nil
+}
+
+func (ms *MetricServer) shutdown() {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, server := range ms.servers {
+		_ = server.Shutdown(shutdownCtx)
+	}
+}
+
+func validateWebConfig(cfg Config) error {
+	if cfg.WebConfigFile == "" {
+		return nil
+	}
+	if err := web.Validate(cfg.WebConfigFile); err != nil {
+		return fmt.Errorf("invalid web config file %s: %w", cfg.WebConfigFile, err)
+	}
+	if cfg.BearerTokenFile == "" && !cfg.KubeAuth.Enabled {
+		return nil
+	}
+
+	// Basic and bearer auth share the Authorization header, so they cannot be combined.
+	data, err := os.ReadFile(cfg.WebConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read web config file %s: %w", cfg.WebConfigFile, err)
+	}
+	var webConfig struct {
+		BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+	}
+	if err := yaml.Unmarshal(data, &webConfig); err != nil {
+		return fmt.Errorf("invalid web config file %s: %w", cfg.WebConfigFile, err)
+	}
+	if len(webConfig.BasicAuthUsers) > 0 {
+		return fmt.Errorf("basic_auth_users in %s cannot be combined with bearer token authentication", cfg.WebConfigFile)
+	}
+	return nil
+}
+
+// TLSConfig builds a server TLS config from the tls_server_config section of
+// an exporter-toolkit web config file, for listeners that do not serve HTTP.
+// It returns nil when the file is empty or has no TLS section. Certificates
+// are re-read on every handshake; other changes need a restart.
+func TLSConfig(webConfigFile string) (*tls.Config, error) {
+	if webConfigFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(webConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read web config file %s: %w", webConfigFile, err)
+	}
+	webConfig := web.Config{
+		TLSConfig: web.TLSConfig{
+			MinVersion: tls.VersionTLS12,
+			MaxVersion: tls.VersionTLS13,
+		},
+	}
+	if err := yaml.Unmarshal(data, &webConfig); err != nil {
+		return nil, fmt.Errorf("invalid web config file %s: %w", webConfigFile, err)
+	}
+	if webConfig.TLSConfig.TLSCertPath == "" && webConfig.TLSConfig.TLSCert == "" {
+		return nil, nil
+	}
+	webConfig.TLSConfig.SetDirectory(filepath.Dir(webConfigFile))
+	tlsConfig, err := web.ConfigToTLSConfig(&webConfig.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tls_server_config in %s: %w", webConfigFile, err)
+	}
+	return tlsConfig, nil
+}
+
+// Listen listens on a host:port address or on a unix socket written as
+// unix:///path/to.sock, replacing a stale socket file.
+func Listen(address string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(address, unixSocketPrefix); ok {
+		// Remove a socket left behind by a previous run.
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale unix socket %s: %w", path, err)
+		}
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+		return l, nil
+	}
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
 	}
+	return l, nil
 }
@@ -0,0 +1,206 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/logging"
+)
+
+// maxUDPPayload keeps datagrams below a typical Ethernet MTU.
+const maxUDPPayload = 1432
+
+type InfluxDBConfig struct {
+	URL         string
+	TokenFile   string
+	Measurement string
+}
+
+func (c InfluxDBConfig) Enabled() bool {
+	return c.URL != ""
+}
+
+// InfluxDBSink writes snapshots in InfluxDB line protocol over HTTP (the v1
+// /write or v2 /api/v2/write endpoint, as given by the URL) or UDP (udp://host:port).
+type InfluxDBSink struct {
+	cfg    InfluxDBConfig
+	url    string
+	client *http.Client
+	conn   net.Conn
+}
+
+func NewInfluxDBSink(cfg InfluxDBConfig) (*InfluxDBSink, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid influxdb url %q", logging.RedactURL(cfg.URL))
+	}
+
+	s := &InfluxDBSink{cfg: cfg}
+	switch u.Scheme {
+	case "http", "https":
+		s.url = u.String()
+		s.client = &http.Client{}
+	case "udp":
+		conn, err := net.Dial("udp", u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial influxdb udp %s: %w", u.Host, err)
+		}
+		s.conn = conn
+	default:
+		return nil, fmt.Errorf("influxdb url must use http, https or udp scheme, got %q", logging.RedactURL(cfg.URL))
+	}
+	return s, nil
+}
+
+func (s *InfluxDBSink) Name() string {
+	return "influxdb"
+}
+
+func (s *InfluxDBSink) Write(ctx context.Context, snapshot collector.Snapshot) error {
+	lines := influxLines(s.cfg.Measurement, snapshot)
+	if len(lines) == 0 {
+		return nil
+	}
+	if s.conn != nil {
+		return writeDatagrams(s.conn, lines, "\n")
+	}
+	return s.post(ctx, strings.Join(lines, "\n"))
+}
+
+func (s *InfluxDBSink) post(ctx context.Context, body string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create influxdb request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.cfg.TokenFile != "" {
+		token, err := os.ReadFile(s.cfg.TokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read influxdb token file: %w", err)
+		}
+		req.Header.Set("Authorization", "Token "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The error repeats the URL, which may hold u and p credentials.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = logging.RedactURL(urlErr.URL)
+		}
+		return fmt.Errorf("failed to send influxdb request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influxdb returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (s *InfluxDBSink) Close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// influxLines renders one point per device, or per owner of a shared device.
+// Tags are the device's Prometheus labels; empty label values are omitted
+// because line protocol rejects them.
+func influxLines(measurement string, snapshot collector.Snapshot) []string {
+	ts := strconv.FormatInt(snapshot.Timestamp.UnixNano(), 10)
+	lines := make([]string, 0, len(snapshot.Devices))
+	for _, device := range snapshot.Devices {
+		for _, labels := range snapshot.Labels(device) {
+			lines = append(lines, influxLine(measurement, labels, deviceFields(device), ts))
+		}
+	}
+	return lines
+}
+
+func influxLine(measurement string, labels map[string]string, fields []field, ts string) string {
+	keys := make([]string, 0, len(labels))
+	for k, v := range labels {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	var b bytes.Buffer
+	b.WriteString(influxEscape(measurement, ", "))
+	for _, k := range keys {
+		b.WriteByte(',')
+		b.WriteString(influxEscape(k, ",= "))
+		b.WriteByte('=')
+		b.WriteString(influxEscape(labels[k], ",= "))
+	}
+	for i, f := range fields {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(influxEscape(f.name, ",= "))
+		b.WriteByte('=')
+		b.WriteString(strconv.FormatFloat(f.value, 'f', -1, 64))
+	}
+	b.WriteByte(' ')
+	b.WriteString(ts)
+	return b.String()
+}
+
+func influxEscape(s, special string) string {
+	if !strings.ContainsAny(s, special+"\\") {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if r == '\\' || strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// writeDatagrams packs lines into as few datagrams as possible without
+// exceeding maxUDPPayload.
+func writeDatagrams(conn net.Conn, lines []string, sep string) error {
+	var buf bytes.Buffer
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(buf.Bytes())
+		buf.Reset()
+		return err
+	}
+
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+len(sep)+len(line) > maxUDPPayload {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if buf.Len() > 0 {
+			buf.WriteString(sep)
+		}
+		buf.WriteString(line)
+	}
+	return flush()
+}
@@ -0,0 +1,152 @@
+package sink
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+func testSnapshot() collector.Snapshot {
+	return collector.Snapshot{
+		Timestamp: time.Unix(1700000000, 5),
+		NodeName:  "node-1",
+		Devices: []daemon.DeviceInfo{{
+			UUID:         "uuid-0",
+			Name:         "rbln0",
+			Card:         "RBLN-CA22",
+			Temperature:  41.5,
+			Power:        80,
+			DRAMUsedGiB:  1,
+			DRAMTotalGiB: 16,
+			Utilization:  12.5,
+		}},
+	}
+}
+
+func TestInfluxEscape(t *testing.T) {
+	tests := []struct {
+		s, special string
+		want       string
+	}{
+		{s: "rbln0", special: ",= ", want: "rbln0"},
+		{s: "RBLN CA22", special: ",= ", want: `RBLN\ CA22`},
+		{s: "a,b=c", special: ",= ", want: `a\,b\=c`},
+		{s: `C:\driver`, special: ",= ", want: `C:\\driver`},
+		{s: "rbln device,v2", special: ", ", want: `rbln\ device\,v2`},
+		{s: "a=b", special: ", ", want: "a=b"},
+	}
+	for _, tt := range tests {
+		if got := influxEscape(tt.s, tt.special); got != tt.want {
+			t.Errorf("influxEscape(%q, %q) = %q, want %q", tt.s, tt.special, got, tt.want)
+		}
+	}
+}
+
+func TestInfluxLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		measurement string
+		labels      map[string]string
+		fields      []field
+		want        string
+	}{
+		{
+			name:        "sorted tags",
+			measurement: "rbln_device",
+			labels:      map[string]string{"name": "rbln0", "card": "RBLN-CA22"},
+			fields:      []field{{"power_watts", 80}, {"temperature_celsius", 41.5}},
+			want:        "rbln_device,card=RBLN-CA22,name=rbln0 power_watts=80,temperature_celsius=41.5 1",
+		},
+		{
+			name:        "empty tag values are omitted",
+			measurement: "rbln_device",
+			labels:      map[string]string{"name": "rbln0", "pod": ""},
+			fields:      []field{{"health", 1}},
+			want:        "rbln_device,name=rbln0 health=1 1",
+		},
+		{
+			name:        "escaped measurement, tags and fields",
+			measurement: "rbln device,x",
+			labels:      map[string]string{"pod name": "a=b,c d"},
+			fields:      []field{{"dram used,bytes=", 1e10}},
+			want:        `rbln\ device\,x,pod\ name=a\=b\,c\ d dram\ used\,bytes\==10000000000 1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := influxLine(tt.measurement, tt.labels, tt.fields, "1"); got != tt.want {
+				t.Errorf("influxLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfluxDBSinkHTTP(t *testing.T) {
+	var gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewInfluxDBSink(InfluxDBConfig{URL: srv.URL + "/api/v2/write?org=o&bucket=b", TokenFile: tokenFile, Measurement: "rbln_device"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Write(context.Background(), testSnapshot()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if gotAuth != "Token s3cret" {
+		t.Errorf("Authorization = %q, want Token s3cret", gotAuth)
+	}
+	if !strings.HasPrefix(gotBody, "rbln_device,") || !strings.Contains(gotBody, ",name=rbln0,") ||
+		!strings.Contains(gotBody, " temperature_celsius=41.5,power_watts=80,dram_used_bytes=1073741824,") ||
+		!strings.HasSuffix(gotBody, " 1700000000000000005") {
+		t.Errorf("body = %q, want one line for rbln0", gotBody)
+	}
+}
+
+func TestInfluxDBSinkRedactsCredentials(t *testing.T) {
+	// A closed port makes the request fail with an error that repeats the URL.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s, err := NewInfluxDBSink(InfluxDBConfig{URL: "http://" + addr + "/write?db=rbln&u=admin&p=hunter2", Measurement: "rbln_device"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Write(context.Background(), testSnapshot())
+	if err == nil {
+		t.Fatal("Write() to a closed port succeeded")
+	}
+	if msg := err.Error(); strings.Contains(msg, "hunter2") || strings.Contains(msg, "admin") || !strings.Contains(msg, "db=rbln") {
+		t.Errorf("Write() error = %q, want the URL without u and p", msg)
+	}
+
+	_, err = NewInfluxDBSink(InfluxDBConfig{URL: "tcp://influxdb:8086/write?u=admin&p=hunter2"})
+	if err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("NewInfluxDBSink() error = %v, want a scheme error without credentials", err)
+	}
+}
@@ -0,0 +1,62 @@
+package sink
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+)
+
+// Sink receives every collection snapshot and forwards it to an external system.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, snapshot collector.Snapshot) error
+	Close() error
+}
+
+// Run feeds snapshots from the store to the sink until ctx is done. Each sink
+// has its own subscription, so a slow sink only skips its own snapshots. Sinks
+// follow the collection interval; timeout returns the current one, which
+// bounds every write.
+func Run(ctx context.Context, s Sink, snapshots *collector.SnapshotStore, timeout func() time.Duration) {
+	ch, unsubscribe := snapshots.Subscribe()
+	defer unsubscribe()
+	defer func() {
+		if err := s.Close(); err != nil {
+			slog.Warn("failed to close sink", "sink", s.Name(), "err", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snapshot := <-ch:
+			writeCtx, cancel := context.WithTimeout(ctx, timeout())
+			if err := s.Write(writeCtx, snapshot); err != nil {
+				slog.Warn("sink write failed", "sink", s.Name(), "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+type field struct {
+	name  string
+	value float64
+}
+
+// deviceFields lists the per-device values in the same units as the
+// Prometheus gauges.
+func deviceFields(device daemon.DeviceInfo) []field {
+	return []field{
+		{"temperature_celsius", device.Temperature},
+		{"power_watts", device.Power},
+		{"dram_used_bytes", float64(collector.BytesFromGiB(device.DRAMUsedGiB))},
+		{"dram_total_bytes", float64(collector.BytesFromGiB(device.DRAMTotalGiB))},
+		{"utilization_percent", device.Utilization},
+		{"health", float64(device.DeviceStatus)},
+	}
+}
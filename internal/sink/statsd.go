@@ -0,0 +1,85 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+)
+
+type StatsDConfig struct {
+	Address string
+	Prefix  string
+}
+
+func (c StatsDConfig) Enabled() bool {
+	return c.Address != ""
+}
+
+// StatsDSink sends snapshots as DogStatsD gauges over UDP.
+type StatsDSink struct {
+	cfg  StatsDConfig
+	conn net.Conn
+}
+
+func NewStatsDSink(cfg StatsDConfig) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial statsd %s: %w", cfg.Address, err)
+	}
+	return &StatsDSink{cfg: cfg, conn: conn}, nil
+}
+
+func (s *StatsDSink) Name() string {
+	return "statsd"
+}
+
+func (s *StatsDSink) Write(ctx context.Context, snapshot collector.Snapshot) error {
+	var lines []string
+	for _, device := range snapshot.Devices {
+		for _, labels := range snapshot.Labels(device) {
+			tags := statsdTags(labels)
+			for _, f := range deviceFields(device) {
+				lines = append(lines, s.metricName(f.name)+":"+strconv.FormatFloat(f.value, 'f', -1, 64)+"|g"+tags)
+			}
+		}
+	}
+	return writeDatagrams(s.conn, lines, "\n")
+}
+
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+func (s *StatsDSink) metricName(field string) string {
+	if s.cfg.Prefix == "" {
+		return field
+	}
+	return s.cfg.Prefix + "." + field
+}
+
+// statsdTags renders labels as a DogStatsD tag suffix ("|#k:v,k2:v2"). Characters
+// that delimit the datagram format are replaced in tag values.
+func statsdTags(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k, v := range labels {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	slices.Sort(keys)
+
+	replacer := strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+	tags := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, k+":"+replacer.Replace(labels[k]))
+	}
+	return "|#" + strings.Join(tags, ",")
+}
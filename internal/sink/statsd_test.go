@@ -0,0 +1,96 @@
+package sink
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsdTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{name: "no labels", labels: nil, want: ""},
+		{name: "only empty values", labels: map[string]string{"pod": ""}, want: ""},
+		{name: "sorted", labels: map[string]string{"name": "rbln0", "card": "RBLN-CA22", "pod": ""}, want: "|#card:RBLN-CA22,name:rbln0"},
+		{name: "delimiters replaced", labels: map[string]string{"pod": "a,b|c#d\ne"}, want: "|#pod:a_b_c_d_e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statsdTags(tt.labels); got != tt.want {
+				t.Errorf("statsdTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsDSinkWrite(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := NewStatsDSink(StatsDConfig{Address: conn.LocalAddr().String(), Prefix: "rbln"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Write(context.Background(), testSnapshot()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	buf := make([]byte, maxUDPPayload)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(buf[:n]), "\n")
+	if len(lines) != len(deviceFields(testSnapshot().Devices[0])) {
+		t.Fatalf("got %d lines %q, want one per field", len(lines), lines)
+	}
+	want := "rbln.temperature_celsius:41.5|g|#card:RBLN-CA22,"
+	if !strings.HasPrefix(lines[0], want) || !strings.Contains(lines[0], ",name:rbln0") {
+		t.Errorf("first line = %q, want prefix %q and the name tag", lines[0], want)
+	}
+	if !strings.HasPrefix(lines[2], "rbln.dram_used_bytes:1073741824|g|#") {
+		t.Errorf("third line = %q, want DRAM usage in bytes", lines[2])
+	}
+}
+
+func TestWriteDatagrams(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	line := strings.Repeat("x", 600)
+	if err := writeDatagrams(client, []string{line, line, line}, "\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two lines fit into one datagram, the third goes into a second one.
+	buf := make([]byte, 2*maxUDPPayload)
+	for _, want := range []int{2*600 + 1, 600} {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("datagram size = %d, want %d", n, want)
+		}
+	}
+}
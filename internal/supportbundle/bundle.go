@@ -0,0 +1,285 @@
+// Package supportbundle writes a tar.gz archive with everything needed to
+// diagnose the exporter and the devices on a node.
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/restapi"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/version"
+)
+
+const (
+	rpcTimeout = 30 * time.Second
+	// maxSysfsFileSize caps the size of a copied sysfs file.
+	maxSysfsFileSize = 64 << 10
+	sysfsModuleDir   = "/sys/module/rebellions"
+)
+
+// sysfsFiles are copied from the PCI device directory of every device bound
+// to the rebellions driver. Missing files are skipped.
+var sysfsFiles = []string{
+	"vendor", "device", "subsystem_vendor", "subsystem_device", "class", "revision",
+	"uevent", "enable", "irq", "numa_node", "local_cpulist", "power_state", "reset_method",
+	"current_link_speed", "current_link_width", "max_link_speed", "max_link_width",
+	"aer_dev_correctable", "aer_dev_fatal", "aer_dev_nonfatal", "pools",
+}
+
+// Input is the state of the exporter that goes into a bundle.
+type Input struct {
+	// Config is the effective configuration with secrets redacted.
+	Config        []byte
+	Sources       []collector.Source
+	PodResources  map[collector.DeviceName][]collector.PodResourceInfo
+	Gatherer      prometheus.Gatherer
+	Events        []events.Record
+	DroppedEvents int
+	Logs          []byte
+}
+
+type bundle struct {
+	tw      *tar.Writer
+	modTime time.Time
+	// problems are failures to collect parts of the bundle; they are written
+	// to errors.txt instead of failing the whole bundle.
+	problems []string
+}
+
+// Write writes the bundle to w. Parts that cannot be collected are listed in
+// errors.txt; only write errors are returned.
+func Write(ctx context.Context, w io.Writer, in Input) error {
+	gz := gzip.NewWriter(w)
+	b := &bundle{tw: tar.NewWriter(gz), modTime: time.Now()}
+
+	err := errors.Join(
+		b.add("config.yaml", in.Config),
+		b.add("build-info.txt", buildInfo()),
+		b.addDaemons(ctx, in.Sources),
+		b.addEvents(in.Events, in.DroppedEvents),
+		b.addPodResources(in.PodResources),
+		b.addSysfs(),
+		b.addMetrics(in.Gatherer),
+		b.add("exporter.log", in.Logs),
+	)
+	if err != nil {
+		return err
+	}
+	if len(b.problems) > 0 {
+		if err := b.add("errors.txt", []byte(strings.Join(b.problems, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (b *bundle) add(name string, data []byte) error {
+	err := b.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: b.modTime,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s to the support bundle: %w", name, err)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to the support bundle: %w", name, err)
+	}
+	return nil
+}
+
+func (b *bundle) addJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.problem(name, err)
+		return nil
+	}
+	return b.add(name, append(data, '\n'))
+}
+
+func (b *bundle) problem(part string, err error) {
+	b.problems = append(b.problems, fmt.Sprintf("%s: %v", part, err))
+}
+
+// buildInfo is the exporter version followed by the module information Go
+// embeds into the binary.
+func buildInfo() []byte {
+	var buf bytes.Buffer
+	info := version.Get()
+	fmt.Fprintf(&buf, "version: %s\nrevision: %s\nbuild date: %s\ngo version: %s\nplatform: %s\napi version: %s\n\n",
+		info.Version, info.Revision, info.BuildDate, info.GoVersion, info.Platform, info.APIVersion)
+	if build, ok := debug.ReadBuildInfo(); ok {
+		buf.WriteString(build.String())
+	}
+	return buf.Bytes()
+}
+
+type rpcCall struct {
+	Method          string            `json:"method"`
+	Device          string            `json:"device,omitempty"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	Error           string            `json:"error,omitempty"`
+	Responses       []json.RawMessage `json:"responses,omitempty"`
+}
+
+// addDaemons stores the raw response of every read-only RPC per daemon.
+func (b *bundle) addDaemons(ctx context.Context, sources []collector.Source) error {
+	ctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	for _, source := range sources {
+		calls := source.Client.CallAll(ctx)
+		out := make([]rpcCall, 0, len(calls))
+		for _, call := range calls {
+			c := rpcCall{
+				Method:          call.Method,
+				Device:          call.Device,
+				DurationSeconds: call.Duration.Seconds(),
+			}
+			if call.Err != nil {
+				c.Error = call.Err.Error()
+			}
+			for _, r := range call.Responses {
+				data, err := protojson.Marshal(r)
+				if err != nil {
+					b.problem("daemon "+call.Method, err)
+					continue
+				}
+				c.Responses = append(c.Responses, data)
+			}
+			out = append(out, c)
+		}
+		if err := b.addJSON(path.Join("daemon", cmp.Or(source.Name, "local")+".json"), out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type eventRecord struct {
+	Received time.Time `json:"received"`
+	restapi.Event
+}
+
+func (b *bundle) addEvents(records []events.Record, dropped int) error {
+	out := make([]eventRecord, 0, len(records))
+	for _, r := range records {
+		out = append(out, eventRecord{Received: r.Received, Event: restapi.NewEvent(r.Event)})
+	}
+	return b.addJSON("events.json", map[string]any{
+		"events":  out,
+		"dropped": dropped,
+	})
+}
+
+type podResource struct {
+	Device    string `json:"device"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+}
+
+// addPodResources writes one entry per device and owning container.
+func (b *bundle) addPodResources(resources map[collector.DeviceName][]collector.PodResourceInfo) error {
+	out := make([]podResource, 0, len(resources))
+	for device, owners := range resources {
+		for _, info := range owners {
+			out = append(out, podResource{
+				Device:    string(device),
+				Namespace: info.Namespace,
+				Pod:       info.Name,
+				Container: info.ContainerName,
+			})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b podResource) int {
+		return cmp.Or(cmp.Compare(len(a.Device), len(b.Device)), cmp.Compare(a.Device, b.Device))
+	})
+	return b.addJSON("pod-resources.json", out)
+}
+
+// addSysfs copies the sysfs attributes of every PCI device bound to the
+// rebellions driver and the driver module version.
+func (b *bundle) addSysfs() error {
+	devices, err := filepath.Glob(filepath.Join(collector.SysfsDriverDir, "[0-9a-f]*:*:*.*"))
+	if err != nil {
+		b.problem("sysfs", err)
+		return nil
+	}
+	if len(devices) == 0 {
+		b.problem("sysfs", fmt.Errorf("no PCI devices found in %s", collector.SysfsDriverDir))
+	}
+	for _, dir := range devices {
+		for _, file := range sysfsFiles {
+			if err := b.addSysfsFile(path.Join("sysfs", filepath.Base(dir), file), filepath.Join(dir, file)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, file := range []string{"version", "srcversion"} {
+		if err := b.addSysfsFile(path.Join("sysfs", "module", file), filepath.Join(sysfsModuleDir, file)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bundle) addSysfsFile(name, file string) error {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		b.problem(name, err)
+		return nil
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	data, err := io.ReadAll(io.LimitReader(f, maxSysfsFileSize))
+	if err != nil {
+		b.problem(name, err)
+		return nil
+	}
+	return b.add(name, data)
+}
+
+func (b *bundle) addMetrics(gatherer prometheus.Gatherer) error {
+	families, err := gatherer.Gather()
+	if err != nil {
+		b.problem("metrics", err)
+	}
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			b.problem("metrics", err)
+			break
+		}
+	}
+	return b.add("metrics.txt", buf.Bytes())
+}
@@ -0,0 +1,100 @@
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+)
+
+// readBundle returns the files of a support bundle by name.
+func readBundle(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(content)
+	}
+}
+
+type failingGatherer struct{}
+
+func (failingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	return nil, errors.New("collector exploded")
+}
+
+func TestWrite(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "up", Help: "Up"})
+	up.Set(1)
+	registry.MustRegister(up)
+
+	var buf bytes.Buffer
+	err := Write(context.Background(), &buf, Input{
+		Config: []byte("interval: 5\n"),
+		PodResources: map[collector.DeviceName][]collector.PodResourceInfo{
+			"rbln10": {{Namespace: "team-a", Name: "train", ContainerName: "main"}},
+			"rbln2":  {{Namespace: "team-b", Name: "serve", ContainerName: "main"}},
+		},
+		Gatherer: registry,
+		Logs:     []byte("level=INFO msg=started\n"),
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	files := readBundle(t, buf.Bytes())
+	for _, name := range []string{"config.yaml", "build-info.txt", "events.json", "pod-resources.json", "metrics.txt", "exporter.log"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("bundle has no %s", name)
+		}
+	}
+	if files["config.yaml"] != "interval: 5\n" {
+		t.Errorf("config.yaml = %q, want the given config", files["config.yaml"])
+	}
+	if !strings.Contains(files["metrics.txt"], "up 1") {
+		t.Errorf("metrics.txt = %q, want the gathered metrics", files["metrics.txt"])
+	}
+	if i, j := strings.Index(files["pod-resources.json"], "rbln2"), strings.Index(files["pod-resources.json"], "rbln10"); i < 0 || j < i {
+		t.Errorf("pod-resources.json = %s, want rbln2 before rbln10", files["pod-resources.json"])
+	}
+}
+
+func TestWriteListsFailedParts(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(context.Background(), &buf, Input{Gatherer: failingGatherer{}}); err != nil {
+		t.Fatalf("Write() error = %v, want failed parts in errors.txt", err)
+	}
+
+	files := readBundle(t, buf.Bytes())
+	if !strings.Contains(files["errors.txt"], "metrics: collector exploded") {
+		t.Errorf("errors.txt = %q, want the metrics failure", files["errors.txt"])
+	}
+	if _, ok := files["metrics.txt"]; !ok {
+		t.Error("bundle has no metrics.txt, want it written even if empty")
+	}
+}
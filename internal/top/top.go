@@ -0,0 +1,195 @@
+// Package top implements an interactive terminal view of the devices, fed by
+// the regular collection snapshots and daemon events.
+package top
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/events"
+)
+
+const (
+	keyUp    = "up"
+	keyDown  = "down"
+	keyLeft  = "left"
+	keyRight = "right"
+	keyEnter = "enter"
+	keyCtrlC = "ctrl-c"
+)
+
+const (
+	enterAltScreen = "\033[?1049h\033[?25l"
+	leaveAltScreen = "\033[?25h\033[?1049l"
+	cursorHome     = "\033[H"
+	clearLine      = "\033[K"
+	clearBelow     = "\033[J"
+)
+
+type Options struct {
+	Snapshots *collector.SnapshotStore
+	Broker    *events.Broker
+	// Errors receives collection errors, which are shown until the next
+	// successful collection.
+	Errors <-chan error
+}
+
+// CheckTerminal reports an error unless in and out are terminals.
+func CheckTerminal(in, out *os.File) error {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return errors.New("top requires an interactive terminal")
+	}
+	return nil
+}
+
+// Run draws the view on out until q or Ctrl-C is pressed or ctx is canceled.
+// in and out must be terminals.
+func Run(ctx context.Context, in, out *os.File, opts Options) error {
+	if err := CheckTerminal(in, out); err != nil {
+		return err
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer func() {
+		_ = term.Restore(int(in.Fd()), state)
+	}()
+	fmt.Fprint(out, enterAltScreen)
+	defer fmt.Fprint(out, leaveAltScreen)
+
+	keys := make(chan string)
+	// The reader blocks in Read until the next key press, so it is left
+	// running when top exits.
+	go readKeys(in, keys)
+
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	snapshots, unsubscribe := opts.Snapshots.Subscribe()
+	defer unsubscribe()
+	sub, cancel := opts.Broker.Subscribe()
+	defer cancel()
+
+	clock := time.NewTicker(time.Second)
+	defer clock.Stop()
+
+	v := newView()
+	if snapshot, ok := opts.Snapshots.Latest(); ok {
+		v.update(snapshot)
+	}
+	for {
+		v.dropped += sub.Dropped()
+		draw(out, v)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case key := <-keys:
+			if v.handleKey(key) {
+				return nil
+			}
+		case snapshot := <-snapshots:
+			v.update(snapshot)
+		case e := <-sub.C:
+			v.addEvent(e, time.Now())
+		case err := <-opts.Errors:
+			v.lastErr = err
+		case <-resize:
+		case <-clock.C:
+		}
+	}
+}
+
+func draw(out *os.File, v *view) {
+	width, height, err := term.GetSize(int(out.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	var b strings.Builder
+	b.WriteString(cursorHome)
+	lines := v.render(time.Now(), height)
+	for i, l := range lines {
+		if i == height {
+			break
+		}
+		if l.style != "" {
+			b.WriteString(l.style)
+		}
+		b.WriteString(truncate(l.text, width))
+		if l.style != "" {
+			b.WriteString(styleReset)
+		}
+		b.WriteString(clearLine)
+		if i < min(len(lines), height)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	b.WriteString(clearBelow)
+	_, _ = io.WriteString(out, b.String())
+}
+
+// truncate cuts s to width runes; all runes used by the view are one column
+// wide.
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:max(width, 0)])
+}
+
+// readKeys decodes key presses, including the arrow key escape sequences.
+func readKeys(in io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for data := buf[:n]; len(data) > 0; {
+			key, size := decodeKey(data)
+			data = data[size:]
+			if key != "" {
+				keys <- key
+			}
+		}
+	}
+}
+
+func decodeKey(data []byte) (string, int) {
+	if len(data) >= 3 && data[0] == 0x1b && (data[1] == '[' || data[1] == 'O') {
+		switch data[2] {
+		case 'A':
+			return keyUp, 3
+		case 'B':
+			return keyDown, 3
+		case 'C':
+			return keyRight, 3
+		case 'D':
+			return keyLeft, 3
+		}
+		return "", 3
+	}
+	switch data[0] {
+	case 0x03:
+		return keyCtrlC, 1
+	case '\r', '\n':
+		return keyEnter, 1
+	}
+	r, size := utf8.DecodeRune(data)
+	return string(r), size
+}
@@ -0,0 +1,382 @@
+package top
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/collector"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/daemon"
+	"github.com/rebellions-sw/rbln-metrics-exporter/internal/restapi"
+)
+
+const (
+	historyLength  = 60
+	sparklineWidth = 10
+	memoryBarWidth = 10
+	maxEvents      = 50
+)
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+type column int
+
+const (
+	columnDevice column = iota
+	columnUtilization
+	columnPower
+	columnTemperature
+	columnMemory
+	columnHealth
+	columnPod
+	numColumns
+)
+
+var columnNames = [numColumns]string{"DEVICE", "UTIL", "POWER", "TEMP", "MEMORY", "HEALTH", "POD"}
+
+// history keeps the most recent samples of a device, oldest first.
+type history struct {
+	utilization []float64
+	power       []float64
+	temperature []float64
+}
+
+func appendSample(samples []float64, v float64) []float64 {
+	samples = append(samples, v)
+	if len(samples) > historyLength {
+		samples = samples[len(samples)-historyLength:]
+	}
+	return samples
+}
+
+type event struct {
+	received time.Time
+	daemon.Event
+}
+
+// line is a rendered line. Styles are applied after the text is cut to the
+// terminal width, so that escape sequences are never truncated.
+type line struct {
+	text  string
+	style string
+}
+
+const (
+	styleBold    = "\033[1m"
+	styleReverse = "\033[7m"
+	styleRed     = "\033[31m"
+	styleReset   = "\033[0m"
+)
+
+// view is the state of the top screen. It is only used from the UI goroutine.
+type view struct {
+	hostname   string
+	updated    time.Time
+	devices    []restapi.Device
+	histories  map[string]*history
+	events     []event
+	dropped    int
+	lastErr    error
+	sortBy     column
+	descending bool
+	selected   string
+	details    bool
+}
+
+func newView() *view {
+	return &view{
+		histories: make(map[string]*history),
+		sortBy:    columnDevice,
+	}
+}
+
+func deviceKey(d restapi.Device) string {
+	if d.Source == "" {
+		return d.Name
+	}
+	return d.Source + "/" + d.Name
+}
+
+func (v *view) update(snapshot collector.Snapshot) {
+	list := restapi.NewDeviceList(snapshot)
+	v.hostname = list.Hostname
+	v.updated = list.Timestamp
+	v.devices = list.Devices
+	v.lastErr = nil
+
+	seen := make(map[string]bool, len(v.devices))
+	for _, d := range v.devices {
+		key := deviceKey(d)
+		seen[key] = true
+		h, ok := v.histories[key]
+		if !ok {
+			h = &history{}
+			v.histories[key] = h
+		}
+		h.utilization = appendSample(h.utilization, d.UtilizationPercent)
+		h.power = appendSample(h.power, d.PowerWatts)
+		h.temperature = appendSample(h.temperature, d.TemperatureCelsius)
+	}
+	for key := range v.histories {
+		if !seen[key] {
+			delete(v.histories, key)
+		}
+	}
+	if v.selected == "" && len(v.devices) > 0 {
+		v.selected = deviceKey(v.sorted()[0])
+	}
+}
+
+func (v *view) addEvent(e daemon.Event, now time.Time) {
+	v.events = append(v.events, event{received: now, Event: e})
+	if len(v.events) > maxEvents {
+		v.events = v.events[len(v.events)-maxEvents:]
+	}
+}
+
+// sorted returns the devices in display order.
+func (v *view) sorted() []restapi.Device {
+	devices := slices.Clone(v.devices)
+	slices.SortStableFunc(devices, func(a, b restapi.Device) int {
+		c := v.compare(a, b)
+		if v.descending {
+			c = -c
+		}
+		// Fall back to the natural device order (rbln2 before rbln10).
+		return cmp.Or(c, cmp.Compare(a.Source, b.Source), cmp.Compare(len(a.Name), len(b.Name)), cmp.Compare(a.Name, b.Name))
+	})
+	return devices
+}
+
+func (v *view) compare(a, b restapi.Device) int {
+	switch v.sortBy {
+	case columnUtilization:
+		return cmp.Compare(a.UtilizationPercent, b.UtilizationPercent)
+	case columnPower:
+		return cmp.Compare(a.PowerWatts, b.PowerWatts)
+	case columnTemperature:
+		return cmp.Compare(a.TemperatureCelsius, b.TemperatureCelsius)
+	case columnMemory:
+		return cmp.Compare(a.MemoryUsedBytes, b.MemoryUsedBytes)
+	case columnHealth:
+		return cmp.Compare(a.HealthStatus, b.HealthStatus)
+	case columnPod:
+		return cmp.Compare(podNames(a.Pods), podNames(b.Pods))
+	default:
+		return 0
+	}
+}
+
+// handleKey applies a key press and reports whether top should exit.
+func (v *view) handleKey(key string) bool {
+	switch key {
+	case "q", "Q", keyCtrlC:
+		return true
+	case keyUp, "k":
+		v.moveSelection(-1)
+	case keyDown, "j":
+		v.moveSelection(1)
+	case keyLeft, "<":
+		v.sortBy = (v.sortBy + numColumns - 1) % numColumns
+	case keyRight, ">":
+		v.sortBy = (v.sortBy + 1) % numColumns
+	case "r":
+		v.descending = !v.descending
+	case "n":
+		v.setSort(columnDevice)
+	case "u":
+		v.setSort(columnUtilization)
+	case "p":
+		v.setSort(columnPower)
+	case "t":
+		v.setSort(columnTemperature)
+	case "m":
+		v.setSort(columnMemory)
+	case keyEnter, "d":
+		v.details = !v.details
+	}
+	return false
+}
+
+// setSort sorts by the column, highest values first, or reverses the order
+// when the column is already selected.
+func (v *view) setSort(c column) {
+	if v.sortBy == c {
+		v.descending = !v.descending
+		return
+	}
+	v.sortBy = c
+	v.descending = c != columnDevice
+}
+
+func (v *view) moveSelection(delta int) {
+	devices := v.sorted()
+	if len(devices) == 0 {
+		return
+	}
+	i := slices.IndexFunc(devices, func(d restapi.Device) bool { return deviceKey(d) == v.selected })
+	i = min(max(i+delta, 0), len(devices)-1)
+	v.selected = deviceKey(devices[i])
+}
+
+func (v *view) render(now time.Time, height int) []line {
+	var lines []line
+
+	status := "waiting for the first collection"
+	if !v.updated.IsZero() {
+		status = fmt.Sprintf("%d devices, updated %s ago", len(v.devices), now.Sub(v.updated).Round(time.Second))
+	}
+	lines = append(lines,
+		line{text: fmt.Sprintf("rbln-metrics-exporter top - %s - %s - %s", v.hostname, now.Format(time.TimeOnly), status), style: styleBold},
+		line{text: "q quit  ↑/↓ select  ←/→ sort column  r reverse  n/u/p/t/m sort by device/util/power/temp/memory  enter details"},
+	)
+	if v.lastErr != nil {
+		lines = append(lines, line{text: "collection failed: " + v.lastErr.Error(), style: styleRed})
+	}
+	lines = append(lines, line{})
+
+	devices := v.sorted()
+	nameWidth := len(columnNames[columnDevice])
+	for _, d := range devices {
+		nameWidth = max(nameWidth, len(deviceKey(d)))
+	}
+
+	headers := make([]string, numColumns)
+	for c := range numColumns {
+		headers[c] = columnNames[c]
+		if c == v.sortBy {
+			if v.descending {
+				headers[c] += "▼"
+			} else {
+				headers[c] += "▲"
+			}
+		}
+	}
+	lines = append(lines, line{
+		text:  fmt.Sprintf("  %-*s  %-17s  %-18s  %-15s  %-25s  %-9s  %s", nameWidth, headers[0], headers[1], headers[2], headers[3], headers[4], headers[5], headers[6]),
+		style: styleBold,
+	})
+
+	// Power sparklines share a scale so that devices can be compared.
+	maxPower := 1.0
+	for _, h := range v.histories {
+		maxPower = max(maxPower, slices.Max(append([]float64{0}, h.power...)))
+	}
+
+	var selected *restapi.Device
+	for i, d := range devices {
+		key := deviceKey(d)
+		h := v.histories[key]
+		if h == nil {
+			h = &history{}
+		}
+		marker, style := "  ", ""
+		if key == v.selected {
+			marker, style = "> ", styleReverse
+			selected = &devices[i]
+		} else if !d.Healthy {
+			style = styleRed
+		}
+		lines = append(lines, line{
+			text: fmt.Sprintf("%s%-*s  %5.1f%% %-10s  %6.1fW %-10s  %3.0fC %-10s  %s %5.1f/%5.1fG  %-9s  %s",
+				marker, nameWidth, key,
+				d.UtilizationPercent, sparkline(h.utilization, 100),
+				d.PowerWatts, sparkline(h.power, maxPower),
+				d.TemperatureCelsius, sparkline(h.temperature, 100),
+				memoryBar(d.MemoryUsedBytes, d.MemoryTotalBytes), gib(d.MemoryUsedBytes), gib(d.MemoryTotalBytes),
+				healthName(d), podNames(d.Pods)),
+			style: style,
+		})
+	}
+
+	if v.details && selected != nil {
+		lines = append(lines, line{})
+		lines = append(lines, detailLines(*selected)...)
+	}
+
+	// Events use the remaining lines, newest first.
+	if free := height - len(lines) - 2; free > 0 {
+		title := "EVENTS"
+		if v.dropped > 0 {
+			title = fmt.Sprintf("EVENTS (%d dropped)", v.dropped)
+		}
+		lines = append(lines, line{}, line{text: title, style: styleBold})
+		if len(v.events) == 0 {
+			lines = append(lines, line{text: "no events since start"})
+		}
+		for i := len(v.events) - 1; i >= 0 && len(lines) < height; i-- {
+			e := v.events[i]
+			device := e.Device
+			if e.Source != "" {
+				device = e.Source + "/" + e.Device
+			}
+			lines = append(lines, line{
+				text: fmt.Sprintf("%s  %-*s  %-17s  %-17s  sub_value=%d  %s", e.received.Format(time.TimeOnly), nameWidth, device, e.Cause, e.Type, e.SubValue, e.UTCTime),
+			})
+		}
+	}
+	return lines
+}
+
+func detailLines(d restapi.Device) []line {
+	lines := []line{
+		{text: deviceKey(d), style: styleBold},
+		{text: fmt.Sprintf("  uuid %s  card %s  device id %s  hostname %s", d.UUID, d.Card, d.DeviceID, d.Hostname)},
+		{text: fmt.Sprintf("  driver %s  firmware %s  health status %d", d.DriverVersion, d.FirmwareVersion, d.HealthStatus)},
+	}
+	if len(d.Pods) == 0 {
+		return append(lines, line{text: "  pod -"})
+	}
+	for _, pod := range d.Pods {
+		lines = append(lines, line{text: fmt.Sprintf("  pod %s/%s (container %s)", pod.Namespace, pod.Name, pod.Container)})
+	}
+	return lines
+}
+
+// sparkline draws the last samples scaled to [0, maxValue].
+func sparkline(samples []float64, maxValue float64) string {
+	if len(samples) > sparklineWidth {
+		samples = samples[len(samples)-sparklineWidth:]
+	}
+	var b strings.Builder
+	for _, s := range samples {
+		i := int(s / maxValue * float64(len(sparkRunes)-1))
+		b.WriteRune(sparkRunes[min(max(i, 0), len(sparkRunes)-1)])
+	}
+	return b.String()
+}
+
+func memoryBar(used, total uint64) string {
+	filled := 0
+	if total > 0 {
+		filled = int(float64(used) / float64(total) * memoryBarWidth)
+	}
+	filled = min(max(filled, 0), memoryBarWidth)
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", memoryBarWidth-filled) + "]"
+}
+
+func gib(b uint64) float64 {
+	return float64(b) / collector.GiBToBytes
+}
+
+func healthName(d restapi.Device) string {
+	if d.Healthy {
+		return "OK"
+	}
+	return fmt.Sprintf("FAILED(%d)", d.HealthStatus)
+}
+
+// podNames lists the pods owning a device. Containers of the same pod are
+// listed once.
+func podNames(pods []restapi.Pod) string {
+	if len(pods) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Namespace+"/"+pod.Name)
+	}
+	return strings.Join(slices.Compact(names), ",")
+}
@@ -0,0 +1,96 @@
+// Package version reports the version of the exporter binary.
+//
+// Version, Revision and BuildDate are set at build time:
+//
+//	go build -ldflags "-X github.com/rebellions-sw/rbln-metrics-exporter/internal/version.Version=v0.2.0" ./cmd/rbln-metrics-exporter
+//
+// Values that are not set fall back to the module and VCS information Go
+// embeds into the binary.
+package version
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+
+	"github.com/rebellions-sw/rbln-metrics-exporter/pkg/rblnservicespb"
+)
+
+var (
+	Version   string
+	Revision  string
+	BuildDate string
+)
+
+const unknown = "unknown"
+
+// Info describes the exporter binary.
+type Info struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	// APIVersion identifies the rbln-daemon API the exporter was built
+	// against, see APIVersion.
+	APIVersion string `json:"api_version"`
+}
+
+var get = sync.OnceValue(func() Info {
+	info := Info{
+		Version:    Version,
+		Revision:   Revision,
+		BuildDate:  BuildDate,
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		APIVersion: APIVersion(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		var modified bool
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Revision == "" {
+					info.Revision = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && Revision == "" && info.Revision != "" {
+			info.Revision += "-dirty"
+		}
+	}
+	for _, s := range []*string{&info.Version, &info.Revision, &info.BuildDate} {
+		if *s == "" {
+			*s = unknown
+		}
+	}
+	return info
+})
+
+// Get returns the version of the running binary.
+func Get() Info {
+	return get()
+}
+
+// APIVersion identifies the rbln_services.proto the exporter was compiled
+// against by a hash of its descriptor, as the proto file carries no version.
+// It changes whenever a message or RPC is added or modified.
+func APIVersion() string {
+	file := rblnservicespb.File_rbln_services_proto
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(protodesc.ToFileDescriptorProto(file))
+	if err != nil {
+		return string(file.Package())
+	}
+	sum := sha256.Sum256(data)
+	return string(file.Package()) + "@" + hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,482 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v6.32.0
+// source: rbln_exporter.proto
+
+package rblnexporterpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SnapshotRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// device names (e.g. 'rbln0') or uuids to return. all devices when empty.
+	Devices       []string `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotRequest) Reset() {
+	*x = SnapshotRequest{}
+	mi := &file_rbln_exporter_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotRequest) ProtoMessage() {}
+
+func (x *SnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rbln_exporter_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotRequest.ProtoReflect.Descriptor instead.
+func (*SnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_rbln_exporter_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SnapshotRequest) GetDevices() []string {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+type Snapshot struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name of the node the exporter runs on
+	Hostname string `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	// time the snapshot was collected
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// devices of the snapshot
+	Devices       []*Device `protobuf:"bytes,3,rep,name=devices,proto3" json:"devices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Snapshot) Reset() {
+	*x = Snapshot{}
+	mi := &file_rbln_exporter_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Snapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Snapshot) ProtoMessage() {}
+
+func (x *Snapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_rbln_exporter_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Snapshot.ProtoReflect.Descriptor instead.
+func (*Snapshot) Descriptor() ([]byte, []int) {
+	return file_rbln_exporter_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Snapshot) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Snapshot) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Snapshot) GetDevices() []*Device {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+type Device struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// device name (e.g. 'rbln0')
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// device uuid
+	Uuid string `protobuf:"bytes,2,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// device id (e.g. '1220')
+	DeviceId string `protobuf:"bytes,3,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	// card name (e.g. 'RBLN-CA22')
+	Card string `protobuf:"bytes,4,opt,name=card,proto3" json:"card,omitempty"`
+	// driver version
+	DriverVersion string `protobuf:"bytes,5,opt,name=driver_version,json=driverVersion,proto3" json:"driver_version,omitempty"`
+	// firmware version
+	FirmwareVersion string `protobuf:"bytes,6,opt,name=firmware_version,json=firmwareVersion,proto3" json:"firmware_version,omitempty"`
+	// temperature in celsius unit
+	TemperatureCelsius float64 `protobuf:"fixed64,7,opt,name=temperature_celsius,json=temperatureCelsius,proto3" json:"temperature_celsius,omitempty"`
+	// power consumption in watt unit
+	PowerWatts float64 `protobuf:"fixed64,8,opt,name=power_watts,json=powerWatts,proto3" json:"power_watts,omitempty"`
+	// device memory in use (bytes)
+	MemoryUsedBytes uint64 `protobuf:"varint,9,opt,name=memory_used_bytes,json=memoryUsedBytes,proto3" json:"memory_used_bytes,omitempty"`
+	// device total memory (bytes)
+	MemoryTotalBytes uint64 `protobuf:"varint,10,opt,name=memory_total_bytes,json=memoryTotalBytes,proto3" json:"memory_total_bytes,omitempty"`
+	// utilization of the device (percent)
+	UtilizationPercent float64 `protobuf:"fixed64,11,opt,name=utilization_percent,json=utilizationPercent,proto3" json:"utilization_percent,omitempty"`
+	// device health status reported by the daemon (0 = healthy)
+	HealthStatus int32 `protobuf:"varint,12,opt,name=health_status,json=healthStatus,proto3" json:"health_status,omitempty"`
+	// true when health_status is 0
+	Healthy bool `protobuf:"varint,13,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	// first of pods, kept for clients that expect a single owner. unset when the device is not allocated.
+	Pod *Pod `protobuf:"bytes,14,opt,name=pod,proto3" json:"pod,omitempty"`
+	// name of the rbln-daemon endpoint the device was read from. empty with a single daemon.
+	Source string `protobuf:"bytes,15,opt,name=source,proto3" json:"source,omitempty"`
+	// host the device belongs to
+	Hostname string `protobuf:"bytes,16,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	// containers the device is allocated to. devices shared by several containers have more than one.
+	Pods          []*Pod `protobuf:"bytes,17,rep,name=pods,proto3" json:"pods,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_rbln_exporter_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Device) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Device) ProtoMessage() {}
+
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_rbln_exporter_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_rbln_exporter_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Device) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Device) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *Device) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *Device) GetCard() string {
+	if x != nil {
+		return x.Card
+	}
+	return ""
+}
+
+func (x *Device) GetDriverVersion() string {
+	if x != nil {
+		return x.DriverVersion
+	}
+	return ""
+}
+
+func (x *Device) GetFirmwareVersion() string {
+	if x != nil {
+		return x.FirmwareVersion
+	}
+	return ""
+}
+
+func (x *Device) GetTemperatureCelsius() float64 {
+	if x != nil {
+		return x.TemperatureCelsius
+	}
+	return 0
+}
+
+func (x *Device) GetPowerWatts() float64 {
+	if x != nil {
+		return x.PowerWatts
+	}
+	return 0
+}
+
+func (x *Device) GetMemoryUsedBytes() uint64 {
+	if x != nil {
+		return x.MemoryUsedBytes
+	}
+	return 0
+}
+
+func (x *Device) GetMemoryTotalBytes() uint64 {
+	if x != nil {
+		return x.MemoryTotalBytes
+	}
+	return 0
+}
+
+func (x *Device) GetUtilizationPercent() float64 {
+	if x != nil {
+		return x.UtilizationPercent
+	}
+	return 0
+}
+
+func (x *Device) GetHealthStatus() int32 {
+	if x != nil {
+		return x.HealthStatus
+	}
+	return 0
+}
+
+func (x *Device) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *Device) GetPod() *Pod {
+	if x != nil {
+		return x.Pod
+	}
+	return nil
+}
+
+func (x *Device) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Device) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Device) GetPods() []*Pod {
+	if x != nil {
+		return x.Pods
+	}
+	return nil
+}
+
+type Pod struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// pod namespace
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// pod name
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// container name
+	Container     string `protobuf:"bytes,3,opt,name=container,proto3" json:"container,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pod) Reset() {
+	*x = Pod{}
+	mi := &file_rbln_exporter_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pod) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pod) ProtoMessage() {}
+
+func (x *Pod) ProtoReflect() protoreflect.Message {
+	mi := &file_rbln_exporter_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pod.ProtoReflect.Descriptor instead.
+func (*Pod) Descriptor() ([]byte, []int) {
+	return file_rbln_exporter_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Pod) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *Pod) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Pod) GetContainer() string {
+	if x != nil {
+		return x.Container
+	}
+	return ""
+}
+
+var File_rbln_exporter_proto protoreflect.FileDescriptor
+
+const file_rbln_exporter_proto_rawDesc = "" +
+	"\n" +
+	"\x13rbln_exporter.proto\x12\frblnexporter\x1a\x1fgoogle/protobuf/timestamp.proto\"+\n" +
+	"\x0fSnapshotRequest\x12\x18\n" +
+	"\adevices\x18\x01 \x03(\tR\adevices\"\x90\x01\n" +
+	"\bSnapshot\x12\x1a\n" +
+	"\bhostname\x18\x01 \x01(\tR\bhostname\x128\n" +
+	"\ttimestamp\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12.\n" +
+	"\adevices\x18\x03 \x03(\v2\x14.rblnexporter.DeviceR\adevices\"\xcf\x04\n" +
+	"\x06Device\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04uuid\x18\x02 \x01(\tR\x04uuid\x12\x1b\n" +
+	"\tdevice_id\x18\x03 \x01(\tR\bdeviceId\x12\x12\n" +
+	"\x04card\x18\x04 \x01(\tR\x04card\x12%\n" +
+	"\x0edriver_version\x18\x05 \x01(\tR\rdriverVersion\x12)\n" +
+	"\x10firmware_version\x18\x06 \x01(\tR\x0ffirmwareVersion\x12/\n" +
+	"\x13temperature_celsius\x18\a \x01(\x01R\x12temperatureCelsius\x12\x1f\n" +
+	"\vpower_watts\x18\b \x01(\x01R\n" +
+	"powerWatts\x12*\n" +
+	"\x11memory_used_bytes\x18\t \x01(\x04R\x0fmemoryUsedBytes\x12,\n" +
+	"\x12memory_total_bytes\x18\n" +
+	" \x01(\x04R\x10memoryTotalBytes\x12/\n" +
+	"\x13utilization_percent\x18\v \x01(\x01R\x12utilizationPercent\x12#\n" +
+	"\rhealth_status\x18\f \x01(\x05R\fhealthStatus\x12\x18\n" +
+	"\ahealthy\x18\r \x01(\bR\ahealthy\x12#\n" +
+	"\x03pod\x18\x0e \x01(\v2\x11.rblnexporter.PodR\x03pod\x12\x16\n" +
+	"\x06source\x18\x0f \x01(\tR\x06source\x12\x1a\n" +
+	"\bhostname\x18\x10 \x01(\tR\bhostname\x12%\n" +
+	"\x04pods\x18\x11 \x03(\v2\x11.rblnexporter.PodR\x04pods\"U\n" +
+	"\x03Pod\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1c\n" +
+	"\tcontainer\x18\x03 \x01(\tR\tcontainer2\xa1\x01\n" +
+	"\fRBLNExporter\x12F\n" +
+	"\vGetSnapshot\x12\x1d.rblnexporter.SnapshotRequest\x1a\x16.rblnexporter.Snapshot\"\x00\x12I\n" +
+	"\fWatchDevices\x12\x1d.rblnexporter.SnapshotRequest\x1a\x16.rblnexporter.Snapshot\"\x000\x01BCZAgithub.com/rebellions-sw/rbln-metrics-exporter/pkg/rblnexporterpbb\x06proto3"
+
+var (
+	file_rbln_exporter_proto_rawDescOnce sync.Once
+	file_rbln_exporter_proto_rawDescData []byte
+)
+
+func file_rbln_exporter_proto_rawDescGZIP() []byte {
+	file_rbln_exporter_proto_rawDescOnce.Do(func() {
+		file_rbln_exporter_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_rbln_exporter_proto_rawDesc), len(file_rbln_exporter_proto_rawDesc)))
+	})
+	return file_rbln_exporter_proto_rawDescData
+}
+
+var file_rbln_exporter_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_rbln_exporter_proto_goTypes = []any{
+	(*SnapshotRequest)(nil),       // 0: rblnexporter.SnapshotRequest
+	(*Snapshot)(nil),              // 1: rblnexporter.Snapshot
+	(*Device)(nil),                // 2: rblnexporter.Device
+	(*Pod)(nil),                   // 3: rblnexporter.Pod
+	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+}
+var file_rbln_exporter_proto_depIdxs = []int32{
+	4, // 0: rblnexporter.Snapshot.timestamp:type_name -> google.protobuf.Timestamp
+	2, // 1: rblnexporter.Snapshot.devices:type_name -> rblnexporter.Device
+	3, // 2: rblnexporter.Device.pod:type_name -> rblnexporter.Pod
+	3, // 3: rblnexporter.Device.pods:type_name -> rblnexporter.Pod
+	0, // 4: rblnexporter.RBLNExporter.GetSnapshot:input_type -> rblnexporter.SnapshotRequest
+	0, // 5: rblnexporter.RBLNExporter.WatchDevices:input_type -> rblnexporter.SnapshotRequest
+	1, // 6: rblnexporter.RBLNExporter.GetSnapshot:output_type -> rblnexporter.Snapshot
+	1, // 7: rblnexporter.RBLNExporter.WatchDevices:output_type -> rblnexporter.Snapshot
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_rbln_exporter_proto_init() }
+func file_rbln_exporter_proto_init() {
+	if File_rbln_exporter_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_rbln_exporter_proto_rawDesc), len(file_rbln_exporter_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_rbln_exporter_proto_goTypes,
+		DependencyIndexes: file_rbln_exporter_proto_depIdxs,
+		MessageInfos:      file_rbln_exporter_proto_msgTypes,
+	}.Build()
+	File_rbln_exporter_proto = out.File
+	file_rbln_exporter_proto_goTypes = nil
+	file_rbln_exporter_proto_depIdxs = nil
+}
@@ -0,0 +1,175 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v6.32.0
+// source: rbln_exporter.proto
+
+package rblnexporterpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RBLNExporter_GetSnapshot_FullMethodName  = "/rblnexporter.RBLNExporter/GetSnapshot"
+	RBLNExporter_WatchDevices_FullMethodName = "/rblnexporter.RBLNExporter/WatchDevices"
+)
+
+// RBLNExporterClient is the client API for RBLNExporter service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Device telemetry collected by rbln-metrics-exporter. Node agents use this
+// service instead of polling rbln-daemon themselves.
+type RBLNExporterClient interface {
+	// get the latest collection snapshot
+	GetSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+	// get the latest snapshot followed by every new snapshot.
+	// a slow client skips intermediate snapshots and always receives the newest one.
+	WatchDevices(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Snapshot], error)
+}
+
+type rBLNExporterClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRBLNExporterClient(cc grpc.ClientConnInterface) RBLNExporterClient {
+	return &rBLNExporterClient{cc}
+}
+
+func (c *rBLNExporterClient) GetSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, RBLNExporter_GetSnapshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rBLNExporterClient) WatchDevices(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Snapshot], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RBLNExporter_ServiceDesc.Streams[0], RBLNExporter_WatchDevices_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SnapshotRequest, Snapshot]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RBLNExporter_WatchDevicesClient = grpc.ServerStreamingClient[Snapshot]
+
+// RBLNExporterServer is the server API for RBLNExporter service.
+// All implementations must embed UnimplementedRBLNExporterServer
+// for forward compatibility.
+//
+// Device telemetry collected by rbln-metrics-exporter. Node agents use this
+// service instead of polling rbln-daemon themselves.
+type RBLNExporterServer interface {
+	// get the latest collection snapshot
+	GetSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error)
+	// get the latest snapshot followed by every new snapshot.
+	// a slow client skips intermediate snapshots and always receives the newest one.
+	WatchDevices(*SnapshotRequest, grpc.ServerStreamingServer[Snapshot]) error
+	mustEmbedUnimplementedRBLNExporterServer()
+}
+
+// UnimplementedRBLNExporterServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRBLNExporterServer struct{}
+
+func (UnimplementedRBLNExporterServer) GetSnapshot(context.Context, *SnapshotRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSnapshot not implemented")
+}
+func (UnimplementedRBLNExporterServer) WatchDevices(*SnapshotRequest, grpc.ServerStreamingServer[Snapshot]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDevices not implemented")
+}
+func (UnimplementedRBLNExporterServer) mustEmbedUnimplementedRBLNExporterServer() {}
+func (UnimplementedRBLNExporterServer) testEmbeddedByValue()                      {}
+
+// UnsafeRBLNExporterServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RBLNExporterServer will
+// result in compilation errors.
+type UnsafeRBLNExporterServer interface {
+	mustEmbedUnimplementedRBLNExporterServer()
+}
+
+func RegisterRBLNExporterServer(s grpc.ServiceRegistrar, srv RBLNExporterServer) {
+	// If the following call pancis, it indicates UnimplementedRBLNExporterServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RBLNExporter_ServiceDesc, srv)
+}
+
+func _RBLNExporter_GetSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBLNExporterServer).GetSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBLNExporter_GetSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBLNExporterServer).GetSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RBLNExporter_WatchDevices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SnapshotRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RBLNExporterServer).WatchDevices(m, &grpc.GenericServerStream[SnapshotRequest, Snapshot]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RBLNExporter_WatchDevicesServer = grpc.ServerStreamingServer[Snapshot]
+
+// RBLNExporter_ServiceDesc is the grpc.ServiceDesc for RBLNExporter service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RBLNExporter_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rblnexporter.RBLNExporter",
+	HandlerType: (*RBLNExporterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSnapshot",
+			Handler:    _RBLNExporter_GetSnapshot_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDevices",
+			Handler:       _RBLNExporter_WatchDevices_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "rbln_exporter.proto",
+}